@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/minus5/svckit/config"
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+)
+
+func configCmd(cmd string, args []string) error {
+	fs := flag.NewFlagSet("svckit config "+cmd, flag.ExitOnError)
+	app := fs.String("app", env.AppName(), "application name")
+	decrypt := fs.Bool("decrypt", false, "show decrypted values")
+	encrypt := fs.Bool("encrypt", false, "encrypt value with key from "+config.EnvKey)
+	fs.Parse(args)
+	env.SetAppName(*app)
+	prefix := config.Prefix()
+
+	switch cmd {
+	case "get":
+		values, err := kvValues(prefix, *decrypt)
+		if err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			v, ok := values[fs.Arg(0)]
+			if !ok {
+				return fmt.Errorf("key %s not found", fs.Arg(0))
+			}
+			fmt.Println(v)
+			return nil
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%s = %s\n", k, values[k])
+		}
+	case "set":
+		if fs.NArg() != 2 {
+			usage()
+		}
+		key, value := fs.Arg(0), fs.Arg(1)
+		if *encrypt {
+			k, err := base64.StdEncoding.DecodeString(os.Getenv(config.EnvKey))
+			if err != nil || len(k) == 0 {
+				return errors.New(config.EnvKey + " not set")
+			}
+			if value, err = config.Encrypt(k, value); err != nil {
+				return err
+			}
+		}
+		return dcy.KVPut(prefix+"/"+strings.TrimPrefix(key, "/"), []byte(value))
+	case "diff":
+		if fs.NArg() != 1 {
+			usage()
+		}
+		values, err := kvValues(prefix, *decrypt)
+		if err != nil {
+			return err
+		}
+		file, err := config.File(fs.Arg(0)).Values()
+		if err != nil {
+			return err
+		}
+		for _, c := range config.Diff(values, file) {
+			fmt.Printf("%s %s: %q -> %q\n", c.Op(values, file), c.Key, c.Old, c.New)
+		}
+	default:
+		usage()
+	}
+	return nil
+}
+
+// kvValues returns raw values under prefix (with overlay subtrees), optionally decrypted.
+func kvValues(prefix string, decrypt bool) (map[string]string, error) {
+	values, err := config.KV(prefix).Values()
+	if err != nil {
+		return nil, err
+	}
+	if decrypt {
+		for k, v := range values {
+			if values[k], err = config.Decrypt(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,35 @@
+// Command svckit is operator tool for svckit services.
+//
+// Usage:
+//
+//	svckit config get [-app name] [-decrypt] [key]
+//	svckit config set [-app name] [-encrypt] key value
+//	svckit config diff [-app name] [-decrypt] file
+//
+// Config commands use the same KV layout as config package (env/<app>/config, with
+// base, dc and node overlay subtrees) and encryption with key from SVCKIT_CONFIG_KEY.
+// Consul location is from SVCKIT_DCY_CONSUL (default local agent).
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  svckit config get [-app name] [-decrypt] [key]
+  svckit config set [-app name] [-encrypt] key value
+  svckit config diff [-app name] [-decrypt] file`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 3 || os.Args[1] != "config" {
+		usage()
+	}
+	if err := configCmd(os.Args[2], os.Args[3:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
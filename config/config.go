@@ -0,0 +1,155 @@
+// Package config loads typed application configuration from Consul KV.
+//
+// Configuration is struct with config tags. Tag is key relative to config prefix,
+// nested structs are KV subtrees. Fields without tag use lowercase field name as key.
+// Default tag is value used when key is not found.
+//
+//	type Config struct {
+//		Addr    string        `config:"addr" default:":8080"`
+//		Timeout time.Duration `config:"timeout" default:"5s"`
+//		Topics  []string      `config:"topics"`
+//		Mongo   struct {
+//			URL string `config:"url"`
+//		} `config:"mongo"`
+//	}
+//
+//	var c Config
+//	if err := config.Load(&c); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// reads keys env/<app>/config/addr, env/<app>/config/mongo/url...
+// (dc and node specific values can override them, see Overlays).
+//
+// Default source is Standard layered configuration, where value of each key
+// can be overridden by environment variable (SVCKIT_CONFIG_MONGO_URL) or
+// command line flag (-mongo.url, see RegisterFlags).
+// Configuration file (yaml, toml or json) from SVCKIT_CONFIG_FILE or -config.file flag
+// is the lowest layer, see Standard.
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// Prefix returns default KV prefix of application configuration (env/<app>/config).
+func Prefix() string {
+	return fmt.Sprintf("env/%s/config", env.AppName())
+}
+
+// Source provides configuration values.
+// Keys are relative to the configuration root, subtrees separated by /.
+type Source interface {
+	Values() (map[string]string, error)
+}
+
+// Watchable source supports blocking queries.
+// Wait returns when values change after index (or on timeout) with new index.
+type Watchable interface {
+	Source
+	Wait(index uint64) (map[string]string, uint64, error)
+}
+
+// Map is static configuration source (defaults, tests).
+type Map map[string]string
+
+// Values returns map itself.
+func (m Map) Values() (map[string]string, error) {
+	return m, nil
+}
+
+// KV returns source reading Consul KV subtree under prefix.
+func KV(prefix string) Source {
+	return kvSource(prefix)
+}
+
+type kvSource string
+
+func (s kvSource) Values() (map[string]string, error) {
+	kvs, _, err := dcy.KVList(string(s))
+	if err != nil {
+		return nil, err
+	}
+	return toStrings(kvs), nil
+}
+
+// kvWait is maximal duration of Consul blocking query.
+const kvWait = 5 * time.Minute
+
+func (s kvSource) Wait(index uint64) (map[string]string, uint64, error) {
+	kvs, idx, err := dcy.KVListWait(string(s), index, kvWait)
+	if err != nil {
+		return nil, index, err
+	}
+	return toStrings(kvs), idx, nil
+}
+
+func toStrings(kvs map[string][]byte) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		m[k] = string(v)
+	}
+	return m
+}
+
+// Option configures Load.
+type Option func(*loader)
+
+type loader struct {
+	source  Source
+	secrets SecretProvider
+}
+
+// WithSource sets configuration source, default is Standard().
+func WithSource(s Source) Option {
+	return func(l *loader) {
+		l.source = s
+	}
+}
+
+func newLoader(opts []Option) *loader {
+	l := &loader{}
+	for _, o := range opts {
+		o(l)
+	}
+	if l.source == nil {
+		l.source = Standard()
+	}
+	return l
+}
+
+// Load reads configuration from source into v (pointer to struct) and validates it.
+func Load(v interface{}, opts ...Option) error {
+	l := newLoader(opts)
+	values, err := l.values(v)
+	if err != nil {
+		return err
+	}
+	if err := l.decode(v, values); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// values reads source, keyed source gets keys of configuration v.
+func (l *loader) values(v interface{}) (map[string]string, error) {
+	return valuesFor(l.source, keysOf(v))
+}
+
+// decode resolves secrets and decodes values into v.
+func (l *loader) decode(v interface{}, values map[string]string) error {
+	values, err := l.resolveSecrets(v, values)
+	if err != nil {
+		return err
+	}
+	return Decode(v, values)
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.config")
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConfig struct {
+	Addr    string        `config:"addr" default:":8080"`
+	Timeout time.Duration `config:"timeout" default:"5s"`
+	Workers int           `default:"4"`
+	Debug   bool          `config:"debug"`
+	Ratio   float64       `config:"ratio"`
+	Topics  []string      `config:"topics"`
+	Ports   []int         `config:"ports"`
+	IP      net.IP        `config:"ip"`
+	Limit   *int          `config:"limit"`
+	Ignored string        `config:"-"`
+	Mongo   struct {
+		URL      string `config:"url"`
+		PoolSize uint16 `config:"pool_size" default:"10"`
+	} `config:"mongo"`
+}
+
+func TestLoad(t *testing.T) {
+	var c testConfig
+	err := Load(&c, WithSource(Map{
+		"timeout":   "1m",
+		"workers":   "8",
+		"debug":     "true",
+		"ratio":     "0.25",
+		"topics":    "a, b",
+		"ports":     "80,443",
+		"ip":        "10.0.0.1",
+		"limit":     "5",
+		"-":         "x",
+		"mongo/url": "mongodb://mongo",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, ":8080", c.Addr)
+	assert.Equal(t, time.Minute, c.Timeout)
+	assert.Equal(t, 8, c.Workers)
+	assert.True(t, c.Debug)
+	assert.Equal(t, 0.25, c.Ratio)
+	assert.Equal(t, []string{"a", "b"}, c.Topics)
+	assert.Equal(t, []int{80, 443}, c.Ports)
+	assert.Equal(t, "10.0.0.1", c.IP.String())
+	assert.Equal(t, 5, *c.Limit)
+	assert.Empty(t, c.Ignored)
+	assert.Equal(t, "mongodb://mongo", c.Mongo.URL)
+	assert.Equal(t, uint16(10), c.Mongo.PoolSize)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var c testConfig
+	err := Decode(&c, map[string]string{"mongo/pool_size": "100000"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "config mongo/pool_size")
+	assert.Error(t, Decode(c, nil))
+}
+
+func TestPrefix(t *testing.T) {
+	assert.Equal(t, "env/config.test/config", Prefix())
+}
+
+type testSecrets map[string]string
+
+func (s testSecrets) Secret(path, key string) (string, error) {
+	v, ok := s[path+"#"+key]
+	if !ok {
+		return "", errors.New("secret not found")
+	}
+	return v, nil
+}
+
+func TestSecrets(t *testing.T) {
+	var c struct {
+		User     string `config:"user"`
+		Password string `config:"password" vault:"secret/data/mongo#password"`
+		Token    string `config:"token" vault:"secret/data/token"`
+	}
+	src := WithSource(Map{"user": "svc", "password": "from kv"})
+	assert.NoError(t, Load(&c, src))
+	assert.Equal(t, "from kv", c.Password)
+
+	secrets := testSecrets{"secret/data/mongo#password": "pass", "secret/data/token#value": "tok"}
+	assert.NoError(t, Load(&c, src, WithSecrets(secrets)))
+	assert.Equal(t, "svc", c.User)
+	assert.Equal(t, "pass", c.Password)
+	assert.Equal(t, "tok", c.Token)
+
+	delete(secrets, "secret/data/token#value")
+	assert.Error(t, Load(&c, src, WithSecrets(secrets)))
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType  = reflect.TypeOf(time.Duration(0))
+	timeType      = reflect.TypeOf(time.Time{})
+	unmarshalType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Decode sets fields of v (pointer to struct) from values.
+// Fields without value get default from the default tag, or stay unchanged.
+// Encrypted values (enc:<scheme>:...) are decrypted.
+// Values are converted to field type: strings, bool, numbers, time.Duration,
+// encoding.TextUnmarshaler and slices (comma separated).
+func Decode(v interface{}, values map[string]string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T is not pointer to struct", v)
+	}
+	return decodeStruct(rv.Elem(), "", values)
+}
+
+// field is struct field with its full key.
+type field struct {
+	reflect.StructField
+	key   string
+	value reflect.Value
+}
+
+// fields returns all exported fields of struct, descending into nested structs.
+func fields(rv reflect.Value, prefix string) []field {
+	var fs []field
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		name := sf.Tag.Get("config")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		f := field{StructField: sf, key: prefix + name, value: rv.Field(i)}
+		if nested(f.value) {
+			fs = append(fs, fields(f.value, f.key+"/")...)
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return fs
+}
+
+// nested returns true for struct fields which are KV subtrees.
+func nested(v reflect.Value) bool {
+	return v.Kind() == reflect.Struct && v.Type() != timeType &&
+		!reflect.PtrTo(v.Type()).Implements(unmarshalType)
+}
+
+func decodeStruct(rv reflect.Value, prefix string, values map[string]string) error {
+	for _, f := range fields(rv, prefix) {
+		s, ok := values[f.key]
+		if !ok {
+			if s, ok = f.Tag.Lookup("default"); !ok {
+				continue
+			}
+		}
+		s, err := Decrypt(s)
+		if err != nil {
+			return fmt.Errorf("config %s: %w", f.key, err)
+		}
+		if err := set(f.value, s); err != nil {
+			return fmt.Errorf("config %s: %w", f.key, err)
+		}
+	}
+	return nil
+}
+
+// set converts s to type of v and sets it.
+func set(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(unmarshalType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if s == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := set(sl.Index(i), p); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		if err := set(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
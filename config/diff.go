@@ -0,0 +1,39 @@
+package config
+
+import "sort"
+
+// Change is difference of one key between two sets of values.
+// Old is empty for added key, New for removed.
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// Op returns + for added, - for removed and ~ for changed key.
+func (c Change) Op(from, to map[string]string) string {
+	if _, ok := from[c.Key]; !ok {
+		return "+"
+	}
+	if _, ok := to[c.Key]; !ok {
+		return "-"
+	}
+	return "~"
+}
+
+// Diff returns changes from values from to values to, sorted by key.
+func Diff(from, to map[string]string) []Change {
+	var changes []Change
+	for k, v := range from {
+		if nv, ok := to[k]; !ok || nv != v {
+			changes = append(changes, Change{Key: k, Old: v, New: nv})
+		}
+	}
+	for k, v := range to {
+		if _, ok := from[k]; !ok {
+			changes = append(changes, Change{Key: k, New: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
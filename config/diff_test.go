@@ -0,0 +1,17 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]string{"a": "1", "b": "2", "c": "3"}
+	to := map[string]string{"a": "1", "b": "20", "d": "4"}
+	changes := Diff(from, to)
+	assert.Equal(t, []Change{{"b", "2", "20"}, {"c", "3", ""}, {"d", "", "4"}}, changes)
+	assert.Equal(t, "~", changes[0].Op(from, to))
+	assert.Equal(t, "-", changes[1].Op(from, to))
+	assert.Equal(t, "+", changes[2].Op(from, to))
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Masked replaces secret values in Dump.
+const Masked = "***"
+
+// secretKeys are parts of key names which are always masked.
+var secretKeys = []string{"password", "passwd", "secret", "token", "credential", "private_key"}
+
+// Dump returns effective configuration v (pointer to struct) as map of keys to values.
+// Secret fields are masked: tagged with secret:"true" or vault tag,
+// and fields with password, secret, token... in the key.
+func Dump(v interface{}) map[string]interface{} {
+	d := map[string]interface{}{}
+	for _, f := range fieldsOf(v) {
+		if secret(f) {
+			if !f.value.IsZero() {
+				d[f.key] = Masked
+			}
+			continue
+		}
+		d[f.key] = dumpValue(f.value.Interface())
+	}
+	return d
+}
+
+func secret(f field) bool {
+	if f.Tag.Get("secret") == "true" {
+		return true
+	}
+	if _, ok := f.Tag.Lookup("vault"); ok {
+		return true
+	}
+	k := strings.ToLower(f.key)
+	for _, s := range secretKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func dumpValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Duration:
+		return t.String()
+	case fmt.Stringer:
+		return t.String()
+	}
+	return v
+}
+
+// DumpHandler returns admin handler with current effective configuration as json (see Dump).
+// Requests must have "Authorization: Bearer <token>" header, if token is empty all requests are forbidden.
+//
+//	cfg, _ := config.Watch[Config]()
+//	httpi.Handle("/debug/config", config.DumpHandler(os.Getenv(log.EnvAdminToken), func() interface{} {
+//		c := cfg.Get()
+//		return &c
+//	}))
+func DumpHandler(token string, current func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(Dump(current()))
+	}
+}
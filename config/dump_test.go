@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	c := struct {
+		Addr    string        `config:"addr"`
+		Timeout time.Duration `config:"timeout"`
+		APIKey  string        `config:"api_key" secret:"true"`
+		Empty   string        `config:"empty" secret:"true"`
+		DBUser  string        `config:"db_user" vault:"database/creds/app#username"`
+		Mongo   struct {
+			Password string `config:"password"`
+		} `config:"mongo"`
+	}{Addr: ":8080", Timeout: time.Second, APIKey: "key", DBUser: "u1"}
+	c.Mongo.Password = "pass"
+
+	assert.Equal(t, map[string]interface{}{
+		"addr":           ":8080",
+		"timeout":        "1s",
+		"api_key":        Masked,
+		"db_user":        Masked,
+		"mongo/password": Masked,
+	}, Dump(&c))
+
+	h := DumpHandler("admin", func() interface{} { return &c })
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/debug/config", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/debug/config", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	h(w, req)
+	var d map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &d))
+	assert.Equal(t, ":8080", d["addr"])
+	assert.NotContains(t, w.Body.String(), `"pass"`)
+}
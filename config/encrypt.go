@@ -0,0 +1,141 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvKey is environment variable with base64 encoded 32 byte key of the v1 encryption scheme.
+const EnvKey = "SVCKIT_CONFIG_KEY"
+
+// EncryptedPrefix marks encrypted value: enc:<scheme>:<base64 ciphertext>.
+const EncryptedPrefix = "enc:"
+
+// SchemeV1 is AES-256-GCM with shared key, nonce is prepended to ciphertext.
+const SchemeV1 = "v1"
+
+// Decrypter decrypts values of one scheme (e.g. KMS client).
+type Decrypter interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// DecrypterFunc is function implementing Decrypter.
+type DecrypterFunc func(ciphertext []byte) ([]byte, error)
+
+// Decrypt calls f.
+func (f DecrypterFunc) Decrypt(ciphertext []byte) ([]byte, error) {
+	return f(ciphertext)
+}
+
+var (
+	decryptersMu sync.RWMutex
+	decrypters   = map[string]Decrypter{}
+)
+
+func init() {
+	if k := os.Getenv(EnvKey); k != "" {
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			logger().Error(fmt.Errorf("%s: %w", EnvKey, err))
+			return
+		}
+		if err := SetKey(key); err != nil {
+			logger().Error(err)
+		}
+	}
+}
+
+// RegisterDecrypter sets decrypter of the scheme.
+// Values encrypted by KMS are stored as enc:kms:<base64> with decrypter registered for kms scheme.
+func RegisterDecrypter(scheme string, d Decrypter) {
+	decryptersMu.Lock()
+	defer decryptersMu.Unlock()
+	decrypters[scheme] = d
+}
+
+// SetKey sets 32 byte shared key of the v1 scheme (default is from EnvKey).
+func SetKey(key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	RegisterDecrypter(SchemeV1, DecrypterFunc(func(ciphertext []byte) ([]byte, error) {
+		n := aead.NonceSize()
+		if len(ciphertext) < n {
+			return nil, errors.New("ciphertext too short")
+		}
+		return aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+	}))
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("config key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateKey returns new random key for the v1 scheme.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+// Encrypt encrypts value with key using v1 scheme, result is enc:v1:<base64>.
+func Encrypt(key []byte, value string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aead.Seal(nonce, nonce, []byte(value), nil)
+	return EncryptedPrefix + SchemeV1 + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Encrypted returns true if value is encrypted.
+func Encrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// Decrypt decrypts encrypted value with decrypter of its scheme.
+// Not encrypted value is returned unchanged.
+func Decrypt(value string) (string, error) {
+	if !Encrypted(value) {
+		return value, nil
+	}
+	scheme, data, ok := strings.Cut(strings.TrimPrefix(value, EncryptedPrefix), ":")
+	if !ok {
+		return "", errors.New("invalid encrypted value")
+	}
+	decryptersMu.RLock()
+	d, ok := decrypters[scheme]
+	decryptersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no decrypter for scheme %s", scheme)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return "", err
+	}
+	plain, err := d.Decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncrypted(t *testing.T) {
+	key, err := GenerateKey()
+	assert.NoError(t, err)
+	enc, err := Encrypt(key, "s3cret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enc, "enc:v1:"))
+
+	var c struct {
+		Password string `config:"password"`
+		Token    string `config:"token"`
+	}
+	assert.NoError(t, SetKey(key))
+	assert.NoError(t, Decode(&c, map[string]string{"password": enc, "token": "plain"}))
+	assert.Equal(t, "s3cret", c.Password)
+	assert.Equal(t, "plain", c.Token)
+
+	other, _ := GenerateKey()
+	assert.NoError(t, SetKey(other))
+	assert.Error(t, Decode(&c, map[string]string{"password": enc}))
+
+	RegisterDecrypter("kms", DecrypterFunc(func(b []byte) ([]byte, error) {
+		return []byte(strings.ToUpper(string(b))), nil
+	}))
+	kms := "enc:kms:" + base64.StdEncoding.EncodeToString([]byte("abc"))
+	assert.NoError(t, Decode(&c, map[string]string{"password": kms}))
+	assert.Equal(t, "ABC", c.Password)
+	assert.Error(t, Decode(&c, map[string]string{"password": "enc:unknown:YQ=="}))
+}
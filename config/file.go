@@ -0,0 +1,268 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/minus5/svckit/internal/yaml"
+)
+
+// EnvFile is environment variable with path of configuration file.
+const EnvFile = "SVCKIT_CONFIG_FILE"
+
+// FileFlag is name of the command line flag with configuration file path, see RegisterFileFlag.
+const FileFlag = "config.file"
+
+// LayerFile is name of the configuration file layer in Standard configuration.
+const LayerFile = "file"
+
+// RegisterFileFlag defines -config.file flag on fs.
+func RegisterFileFlag(fs *flag.FlagSet) {
+	if fs.Lookup(FileFlag) == nil {
+		fs.String(FileFlag, "", "configuration file (yaml, toml or json)")
+	}
+}
+
+// FilePath returns configuration file path from -config.file flag or EnvFile.
+func FilePath() string {
+	if f := flag.CommandLine.Lookup(FileFlag); f != nil && f.Value.String() != "" {
+		return f.Value.String()
+	}
+	return os.Getenv(EnvFile)
+}
+
+// File returns source reading configuration file.
+// Format is detected from extension (.yaml, .yml, .toml, .json) or content.
+// Only flat subset of each format is supported:
+//   - nested maps are subtrees (mongo: {url: x} is key mongo/url)
+//   - scalars are strings, numbers and booleans
+//   - lists of scalars are comma separated values, items must not contain comma
+//
+// Anything else (lists of maps, inline maps, yaml anchors, block scalars and
+// multiple documents, toml arrays of tables and multi line strings) is an error.
+// Empty path is source without values.
+func File(path string) Source {
+	return fileSource(path)
+}
+
+type fileSource string
+
+func (s fileSource) Values() (map[string]string, error) {
+	path := string(s)
+	if path == "" {
+		return map[string]string{}, nil
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseFile(path, buf)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// parseFile parses content in format detected from path extension or content.
+func parseFile(path string, buf []byte) (map[string]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSON(buf)
+	case ".yaml", ".yml":
+		return parseYAML(buf)
+	case ".toml":
+		return parseTOML(buf)
+	}
+	trimmed := bytes.TrimSpace(buf)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseJSON(buf)
+	}
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// toml key = value, yaml key: value
+		eq, colon := strings.Index(line, "="), strings.Index(line, ":")
+		if strings.HasPrefix(line, "[") || (eq > 0 && (colon < 0 || eq < colon)) {
+			return parseTOML(buf)
+		}
+		break
+	}
+	return parseYAML(buf)
+}
+
+func parseJSON(buf []byte) (map[string]string, error) {
+	var v interface{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("json root is not object")
+	}
+	values := map[string]string{}
+	return values, flatten(values, "", m)
+}
+
+// flatten adds nested map values as keys separated by /.
+func flatten(values map[string]string, prefix string, m map[string]interface{}) error {
+	for k, v := range m {
+		key := prefix + k
+		switch t := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(values, key+"/", t); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, 0, len(t))
+			for _, i := range t {
+				s, err := scalar(i)
+				if err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				items = append(items, s)
+			}
+			s, err := list(items)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			values[key] = s
+		case nil:
+		default:
+			s, err := scalar(t)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			values[key] = s
+		}
+	}
+	return nil
+}
+
+func scalar(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// list joins list items into comma separated value.
+func list(items []string) (string, error) {
+	for _, i := range items {
+		if strings.Contains(i, ",") {
+			return "", fmt.Errorf("list item %q contains comma", i)
+		}
+	}
+	return strings.Join(items, ","), nil
+}
+
+// lines returns lines without comments, with line numbers.
+func lines(buf []byte) ([]string, []int) {
+	var ls []string
+	var ns []int
+	for i, l := range strings.Split(string(buf), "\n") {
+		l = yaml.StripComment(strings.TrimRight(l, "\r"))
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		ls = append(ls, l)
+		ns = append(ns, i+1)
+	}
+	return ls, ns
+}
+
+// value converts toml value to config value.
+func value(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		items, err := yaml.InlineList(s[1 : len(s)-1])
+		if err != nil {
+			return "", err
+		}
+		ss := make([]string, 0, len(items))
+		for _, i := range items {
+			v, err := scalar(i)
+			if err != nil {
+				return "", err
+			}
+			ss = append(ss, v)
+		}
+		return list(ss)
+	}
+	if strings.HasPrefix(s, "{") {
+		return "", fmt.Errorf("inline map not supported")
+	}
+	if strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''") {
+		return "", fmt.Errorf("multi line string not supported")
+	}
+	return yaml.Unquote(s), nil
+}
+
+func parseYAML(buf []byte) (map[string]string, error) {
+	v, err := yaml.Parse(buf)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if v == nil {
+		return values, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml root is not map")
+	}
+	return values, flatten(values, "", m)
+}
+
+func parseTOML(buf []byte) (map[string]string, error) {
+	values := map[string]string{}
+	prefix := ""
+	ls, ns := lines(buf)
+	for i := 0; i < len(ls); i++ {
+		l := strings.TrimSpace(ls[i])
+		if strings.HasPrefix(l, "[[") {
+			return nil, fmt.Errorf("line %d: array of tables not supported", ns[i])
+		}
+		if strings.HasPrefix(l, "[") && strings.HasSuffix(l, "]") {
+			prefix = tomlKey(l[1:len(l)-1]) + "/"
+			continue
+		}
+		k, v, ok := strings.Cut(l, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", ns[i])
+		}
+		v = strings.TrimSpace(v)
+		// multi line array
+		for strings.HasPrefix(v, "[") && strings.Count(v, "[") > strings.Count(v, "]") && i+1 < len(ls) {
+			i++
+			v += " " + strings.TrimSpace(ls[i])
+		}
+		s, err := value(v)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", ns[i], err)
+		}
+		values[prefix+tomlKey(k)] = s
+	}
+	return values, nil
+}
+
+// tomlKey converts dotted toml key to config key.
+func tomlKey(k string) string {
+	parts := strings.Split(strings.TrimSpace(k), ".")
+	for i, p := range parts {
+		parts[i] = yaml.Unquote(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var fileExpected = map[string]string{
+	"addr":            ":8080",
+	"debug":           "true",
+	"workers":         "4",
+	"ratio":           "0.5",
+	"topics":          "a,b c",
+	"mongo/url":       "mongodb://mongo#1",
+	"mongo/pool/size": "10",
+}
+
+func TestParseFile(t *testing.T) {
+	files := map[string]string{
+		"app.yaml": `# app config
+addr: ":8080"
+debug: true
+workers: 4 # comment
+ratio: 0.5
+topics:
+  - a
+  - "b c"
+mongo:
+  url: 'mongodb://mongo#1'
+  pool:
+    size: 10
+`,
+		"app.toml": `addr = ":8080"
+debug = true
+workers = 4
+ratio = 0.5
+topics = [
+  "a",
+  "b c",
+]
+
+[mongo]
+url = "mongodb://mongo#1" # comment
+pool.size = 10
+`,
+		"app.json": `{"addr": ":8080", "debug": true, "workers": 4, "ratio": 0.5, "topics": ["a", "b c"],
+"mongo": {"url": "mongodb://mongo#1", "pool": {"size": 10}}}`,
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		values, err := parseFile(name, []byte(content))
+		assert.NoError(t, err, name)
+		assert.Equal(t, fileExpected, values, name)
+
+		// autodetect without extension
+		path := filepath.Join(dir, "config-"+filepath.Ext(name)[1:])
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		values, err = File(path).Values()
+		assert.NoError(t, err, name)
+		assert.Equal(t, fileExpected, values, name)
+	}
+
+	_, err := parseYAML([]byte("items:\n  - name: a\n"))
+	assert.Error(t, err)
+	_, err = parseTOML([]byte("[[items]]\n"))
+	assert.Error(t, err)
+	for name, content := range map[string]string{
+		"comma.yaml":     "hosts:\n  - a,b\n",
+		"comma.toml":     `hosts = ["a,b", "c"]`,
+		"comma.json":     `{"hosts": ["a,b"]}`,
+		"anchor.yaml":    "base: &base x\nother: *base\n",
+		"documents.yaml": "a: 1\n---\nb: 2\n",
+		"tab.yaml":       "mongo:\n\turl: x\n",
+		"nested.yaml":    "items:\n  - [a, b]\n",
+		"string.toml":    `text = """a"""`,
+	} {
+		_, err := parseFile(name, []byte(content))
+		assert.Error(t, err, name)
+	}
+	_, err = File(filepath.Join(dir, "missing.yaml")).Values()
+	assert.Error(t, err)
+}
+
+func TestStandardFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("workers: 3\n"), 0644))
+	os.Setenv(EnvFile, path)
+	defer os.Unsetenv(EnvFile)
+
+	var c struct {
+		Workers int `config:"workers"`
+	}
+	// consul is not available in tests, kv layer is optional with config file
+	assert.NoError(t, Load(&c))
+	assert.Equal(t, 3, c.Workers)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchString calls handler with value of the key and on each its change.
+// Key is relative to configuration root (see Load), it is read from
+// default source or source set with WithSource option.
+// Handler is not called while key is missing or invalid.
+// Returns function which stops watching.
+//
+//	stop := config.WatchDuration("poll_interval", func(d time.Duration) {
+//		poller.SetInterval(d)
+//	})
+func WatchString(key string, handler func(string), opts ...Option) func() {
+	return watchKey(key, func(s string) (string, error) { return s, nil }, handler, opts)
+}
+
+// WatchInt calls handler with int value of the key and on each its change.
+func WatchInt(key string, handler func(int), opts ...Option) func() {
+	return watchKey(key, func(s string) (int, error) { return strconv.Atoi(strings.TrimSpace(s)) }, handler, opts)
+}
+
+// WatchDuration calls handler with duration value of the key and on each its change.
+func WatchDuration(key string, handler func(time.Duration), opts ...Option) func() {
+	return watchKey(key, func(s string) (time.Duration, error) { return time.ParseDuration(strings.TrimSpace(s)) }, handler, opts)
+}
+
+// WatchBool calls handler with bool value of the key and on each its change.
+func WatchBool(key string, handler func(bool), opts ...Option) func() {
+	return watchKey(key, func(s string) (bool, error) { return strconv.ParseBool(strings.TrimSpace(s)) }, handler, opts)
+}
+
+func watchKey[T any](key string, parse func(string) (T, error), handler func(T), opts []Option) func() {
+	l := newLoader(opts)
+	stop := make(chan struct{})
+	var last *string
+	apply := func(values map[string]string) {
+		s, ok := values[key]
+		if !ok || (last != nil && *last == s) {
+			return
+		}
+		last = &s
+		s, err := Decrypt(s)
+		if err != nil {
+			logger().S("key", key).Error(err)
+			return
+		}
+		v, err := parse(s)
+		if err != nil {
+			logger().S("key", key).Error(err)
+			return
+		}
+		handler(v)
+	}
+	values, err := valuesFor(l.source, []string{key})
+	if err != nil {
+		logger().S("key", key).Error(err)
+	}
+	apply(values)
+	if w, ok := l.source.(Watchable); ok {
+		go WatchSource(w, values, stop, apply)
+	}
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }
+}
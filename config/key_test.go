@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchKey(t *testing.T) {
+	src := &testSource{updates: make(chan map[string]string), current: Map{"interval": "1s", "workers": "2"}}
+	intervals := make(chan time.Duration, 10)
+	stop := WatchDuration("interval", func(d time.Duration) { intervals <- d }, WithSource(src))
+	defer stop()
+	assert.Equal(t, time.Second, <-intervals)
+
+	src.updates <- Map{"interval": "1s", "workers": "3"}
+	src.updates <- Map{"interval": "bad"}
+	src.updates <- Map{"interval": "5s"}
+	assert.Equal(t, 5*time.Second, <-intervals)
+	assert.Len(t, intervals, 0)
+
+	var workers int
+	WatchInt("workers", func(n int) { workers = n }, WithSource(Map{"workers": "4"}))()
+	assert.Equal(t, 4, workers)
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvPrefix is prefix of environment variables in Standard configuration.
+// Key mongo/pool_size is set by SVCKIT_CONFIG_MONGO_POOL_SIZE.
+const EnvPrefix = "SVCKIT_CONFIG_"
+
+// Layer names in Standard configuration, defaults from struct tags are reported as LayerDefault.
+const (
+	LayerDefault = "default"
+	LayerKV      = "kv"
+	LayerEnv     = "env"
+	LayerFlags   = "flags"
+)
+
+// pollInterval is period of reading layers when none of them is Watchable.
+var pollInterval = time.Minute
+
+// KeyedSource provides values only for known keys (environment variables, flags).
+type KeyedSource interface {
+	Source
+	ValuesFor(keys []string) (map[string]string, error)
+}
+
+// Layer is named source in layered configuration.
+// Errors of optional layer are logged and layer is skipped.
+type Layer struct {
+	Name     string
+	Source   Source
+	Optional bool
+}
+
+// Layered merges values of layers, later layer overrides earlier.
+// It remembers origin of each value.
+type Layered struct {
+	layers  []Layer
+	mu      sync.Mutex
+	keys    []string
+	origins map[string]string
+	// waitIndex is index of the Watchable layer (e.g. Consul KV index),
+	// changes counts values returned by Wait and is index returned to the caller
+	waitIndex uint64
+	changes   uint64
+}
+
+// Layers returns layered source, later layers have precedence.
+func Layers(layers ...Layer) *Layered {
+	return &Layered{layers: layers, origins: map[string]string{}}
+}
+
+// Standard returns layered source with precedence:
+// defaults < configuration file < Consul KV (with dc and node overlays) <
+// environment variables < command line flags.
+// When configuration file is set (see FilePath), Consul KV is optional.
+func Standard() *Layered {
+	path := FilePath()
+	return Layers(
+		Layer{Name: LayerFile, Source: File(path)},
+		Layer{Name: LayerKV, Source: Overlays(Prefix()), Optional: path != ""},
+		Layer{Name: LayerEnv, Source: Env(EnvPrefix)},
+		Layer{Name: LayerFlags, Source: Flags(flag.CommandLine)},
+	)
+}
+
+// Values merges values of all layers.
+func (l *Layered) Values() (map[string]string, error) {
+	l.mu.Lock()
+	keys := l.keys
+	l.mu.Unlock()
+	return l.merge(keys, nil, -1)
+}
+
+// ValuesFor merges values of all layers, keyed layers provide values for keys.
+func (l *Layered) ValuesFor(keys []string) (map[string]string, error) {
+	l.mu.Lock()
+	l.keys = keys
+	l.mu.Unlock()
+	return l.merge(keys, nil, -1)
+}
+
+// Wait blocks on the first Watchable layer and merges its values with other layers.
+// Without Watchable layer, or when optional one fails, layers are read again after poll interval.
+// Returned index counts changes, index of the Watchable layer is kept separately,
+// so switching between waiting and polling doesn't mix them. Index 0 returns current values.
+func (l *Layered) Wait(index uint64) (map[string]string, uint64, error) {
+	for i, ly := range l.layers {
+		w, ok := ly.Source.(Watchable)
+		if !ok {
+			continue
+		}
+		l.mu.Lock()
+		waitIndex := l.waitIndex
+		l.mu.Unlock()
+		if index == 0 {
+			waitIndex = 0
+		}
+		values, idx, err := w.Wait(waitIndex)
+		if err != nil {
+			if ly.Optional {
+				logger().S("layer", ly.Name).Error(err)
+				break
+			}
+			return nil, index, err
+		}
+		l.mu.Lock()
+		keys := l.keys
+		l.waitIndex = idx
+		l.mu.Unlock()
+		merged, err := l.merge(keys, values, i)
+		return merged, l.changed(), err
+	}
+	if index > 0 {
+		time.Sleep(pollInterval)
+	}
+	values, err := l.Values()
+	return values, l.changed(), err
+}
+
+// changed increments and returns changes counter.
+func (l *Layered) changed() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes++
+	return l.changes
+}
+
+// merge reads and merges all layers, values of the layer at index waited are already read.
+func (l *Layered) merge(keys []string, waited map[string]string, index int) (map[string]string, error) {
+	merged := map[string]string{}
+	origins := map[string]string{}
+	for i, ly := range l.layers {
+		values := waited
+		if i != index {
+			var err error
+			if values, err = valuesFor(ly.Source, keys); err != nil {
+				if !ly.Optional {
+					return nil, err
+				}
+				logger().S("layer", ly.Name).Error(err)
+			}
+		}
+		for k, v := range values {
+			merged[k] = v
+			origins[k] = ly.Name
+		}
+	}
+	l.mu.Lock()
+	l.origins = origins
+	l.mu.Unlock()
+	return merged, nil
+}
+
+func valuesFor(s Source, keys []string) (map[string]string, error) {
+	if ks, ok := s.(KeyedSource); ok {
+		return ks.ValuesFor(keys)
+	}
+	return s.Values()
+}
+
+// Origin is source of effective configuration value.
+type Origin struct {
+	Key    string `json:"key"`
+	Source string `json:"source"`
+}
+
+// Report returns origin of each field of the configuration v (pointer to struct)
+// from the last read of layers. Fields without value have empty source.
+func (l *Layered) Report(v interface{}) []Origin {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var report []Origin
+	for _, f := range fieldsOf(v) {
+		o := Origin{Key: f.key, Source: l.origins[f.key]}
+		if _, ok := f.Tag.Lookup("default"); ok && o.Source == "" {
+			o.Source = LayerDefault
+		}
+		report = append(report, o)
+	}
+	return report
+}
+
+// fieldsOf returns fields of v if it is pointer to struct.
+func fieldsOf(v interface{}) []field {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return fields(rv.Elem(), "")
+}
+
+// keysOf returns keys of all fields of v.
+func keysOf(v interface{}) []string {
+	var keys []string
+	for _, f := range fieldsOf(v) {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// Env returns source reading environment variables.
+// Variable name is prefix and upper case key with / . and - replaced by _.
+func Env(prefix string) KeyedSource {
+	return envSource(prefix)
+}
+
+type envSource string
+
+// EnvName returns name of environment variable for key.
+func (s envSource) EnvName(key string) string {
+	return string(s) + strings.ToUpper(strings.NewReplacer("/", "_", ".", "_", "-", "_").Replace(key))
+}
+
+func (s envSource) Values() (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (s envSource) ValuesFor(keys []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, k := range keys {
+		if v, ok := os.LookupEnv(s.EnvName(k)); ok {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// Flags returns source reading command line flags set on fs.
+// Flag name is key with / replaced by . (e.g. -mongo.pool_size), see RegisterFlags.
+// Only explicitly set flags are used.
+func Flags(fs *flag.FlagSet) KeyedSource {
+	return flagsSource{fs}
+}
+
+type flagsSource struct {
+	fs *flag.FlagSet
+}
+
+func flagName(key string) string {
+	return strings.Replace(key, "/", ".", -1)
+}
+
+func (s flagsSource) Values() (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (s flagsSource) ValuesFor(keys []string) (map[string]string, error) {
+	values := map[string]string{}
+	if !s.fs.Parsed() {
+		return values, nil
+	}
+	set := map[string]string{}
+	s.fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	for _, k := range keys {
+		if v, ok := set[flagName(k)]; ok {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// RegisterFlags defines string flag on fs for each field of configuration v,
+// call it before flag.Parse. Flag usage is from usage tag.
+// Flags which are already defined are skipped.
+func RegisterFlags(fs *flag.FlagSet, v interface{}) {
+	ff := fieldsOf(v)
+	sort.Slice(ff, func(i, j int) bool { return ff[i].key < ff[j].key })
+	for _, f := range ff {
+		name := flagName(f.key)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		fs.String(name, f.Tag.Get("default"), f.Tag.Get("usage"))
+	}
+}
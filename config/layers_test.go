@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayers(t *testing.T) {
+	type cfg struct {
+		Addr    string `config:"addr" default:":8080"`
+		Workers int    `config:"workers" default:"1"`
+		Mongo   struct {
+			PoolSize int `config:"pool_size"`
+			URL      string
+		} `config:"mongo"`
+		Debug bool `config:"debug"`
+	}
+	var c cfg
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs, &c)
+	assert.NotNil(t, fs.Lookup("mongo.pool_size"))
+	assert.NoError(t, fs.Parse([]string{"-debug=true"}))
+
+	os.Setenv("TEST_CONFIG_MONGO_POOL_SIZE", "20")
+	os.Setenv("TEST_CONFIG_DEBUG", "false")
+	defer os.Unsetenv("TEST_CONFIG_MONGO_POOL_SIZE")
+	defer os.Unsetenv("TEST_CONFIG_DEBUG")
+
+	l := Layers(
+		Layer{Name: LayerKV, Source: Map{"workers": "4", "mongo/pool_size": "10", "mongo/url": "mongodb://kv"}},
+		Layer{Name: LayerEnv, Source: Env("TEST_CONFIG_")},
+		Layer{Name: LayerFlags, Source: Flags(fs)},
+	)
+	assert.NoError(t, Load(&c, WithSource(l)))
+	assert.Equal(t, ":8080", c.Addr)
+	assert.Equal(t, 4, c.Workers)
+	assert.Equal(t, 20, c.Mongo.PoolSize)
+	assert.Equal(t, "mongodb://kv", c.Mongo.URL)
+	assert.True(t, c.Debug)
+
+	assert.Equal(t, []Origin{
+		{"addr", LayerDefault},
+		{"workers", LayerKV},
+		{"mongo/pool_size", LayerEnv},
+		{"mongo/url", LayerKV},
+		{"debug", LayerFlags},
+	}, l.Report(&c))
+}
+
+type indexedKV struct {
+	Map
+	indexes []uint64
+	fail    bool
+}
+
+func (k *indexedKV) Wait(index uint64) (map[string]string, uint64, error) {
+	k.indexes = append(k.indexes, index)
+	if k.fail {
+		return nil, index, errors.New("kv unavailable")
+	}
+	return k.Map, 100 + uint64(len(k.indexes)), nil
+}
+
+func TestLayersWaitIndex(t *testing.T) {
+	defer func(d time.Duration) { pollInterval = d }(pollInterval)
+	pollInterval = time.Millisecond
+
+	kv := &indexedKV{Map: Map{"workers": "4"}}
+	l := Layers(Layer{Name: LayerKV, Source: kv, Optional: true})
+
+	_, i1, err := l.Wait(0)
+	assert.NoError(t, err)
+	_, i2, err := l.Wait(i1)
+	assert.NoError(t, err)
+	kv.fail = true
+	values, i3, err := l.Wait(i2)
+	assert.NoError(t, err)
+	assert.Equal(t, "4", values["workers"])
+	kv.fail = false
+	_, i4, err := l.Wait(i3)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []uint64{0, 101, 102, 102}, kv.indexes)
+	assert.Equal(t, []uint64{1, 2, 3, 4}, []uint64{i1, i2, i3, i4})
+}
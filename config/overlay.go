@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/minus5/svckit/env"
+)
+
+// Overlay subtrees of configuration prefix.
+// Underscore reserves them, so config keys like node/... or dc/... stay plain keys.
+const (
+	overlayBase = "_base/"
+	overlayDc   = "_dc/"
+	overlayNode = "_node/"
+)
+
+// Overlays returns Consul KV source which merges subtrees under prefix:
+//
+//	<prefix>/<key>               base, same as <prefix>/_base/<key>
+//	<prefix>/_base/<key>
+//	<prefix>/_dc/<dc>/<key>      overrides base in datacenter
+//	<prefix>/_node/<node>/<key>  overrides dc and base on node
+//
+// Whole prefix is read (and watched) with one query.
+func Overlays(prefix string) Source {
+	return Overlay(KV(prefix), env.Dc(), env.NodeName())
+}
+
+// Overlay merges base, dc and node subtrees of values from src.
+// Result is Watchable if src is Watchable.
+func Overlay(src Source, dc, node string) Source {
+	o := overlay{src: src, dc: dc, node: node}
+	if w, ok := src.(Watchable); ok {
+		return watchableOverlay{o, w}
+	}
+	return o
+}
+
+type overlay struct {
+	src      Source
+	dc, node string
+}
+
+func (o overlay) Values() (map[string]string, error) {
+	values, err := o.src.Values()
+	if err != nil {
+		return nil, err
+	}
+	return o.merge(values), nil
+}
+
+// merge flattens overlay subtrees, more specific overrides less specific.
+func (o overlay) merge(values map[string]string) map[string]string {
+	merged := map[string]string{}
+	dc := overlayDc + o.dc + "/"
+	node := overlayNode + o.node + "/"
+	for k, v := range values {
+		if !strings.HasPrefix(k, overlayBase) && !strings.HasPrefix(k, overlayDc) && !strings.HasPrefix(k, overlayNode) {
+			merged[k] = v
+		}
+	}
+	for _, p := range []string{overlayBase, dc, node} {
+		for k, v := range values {
+			if strings.HasPrefix(k, p) {
+				merged[strings.TrimPrefix(k, p)] = v
+			}
+		}
+	}
+	return merged
+}
+
+type watchableOverlay struct {
+	overlay
+	w Watchable
+}
+
+func (o watchableOverlay) Wait(index uint64) (map[string]string, uint64, error) {
+	values, idx, err := o.w.Wait(index)
+	if err != nil {
+		return nil, idx, err
+	}
+	return o.merge(values), idx, nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlay(t *testing.T) {
+	src := Map{
+		"workers":                "1",
+		"node/timeout":           "2s",
+		"_base/timeout":          "1s",
+		"_base/workers":          "2",
+		"_dc/s1/workers":         "3",
+		"_dc/s1/mode":            "safe",
+		"_dc/s2/workers":         "4",
+		"_node/node01/workers":   "5",
+		"_node/node02/mode":      "fast",
+		"_node/node01/mongo/url": "mongodb://local",
+	}
+	values, err := Overlay(src, "s1", "node01").Values()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"workers":      "5",
+		"timeout":      "1s",
+		"mode":         "safe",
+		"mongo/url":    "mongodb://local",
+		"node/timeout": "2s",
+	}, values)
+
+	values, _ = Overlay(src, "s2", "node02").Values()
+	assert.Equal(t, "4", values["workers"])
+	assert.Equal(t, "fast", values["mode"])
+
+	_, ok := Overlay(src, "s1", "node01").(Watchable)
+	assert.False(t, ok)
+	_, ok = Overlay(&testSource{}, "s1", "node01").(Watchable)
+	assert.True(t, ok)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SecretProvider resolves fields tagged with vault tag (path#key),
+// implemented by vault.Client.
+//
+//	type Config struct {
+//		MongoPassword string `config:"mongo/password" vault:"secret/data/mongo#password"`
+//	}
+type SecretProvider interface {
+	Secret(path, key string) (string, error)
+}
+
+// SecretWatcher is implemented by secret providers which know when secret has changed
+// (e.g. vault.Client reading not renewable secret again).
+// Watch reloads configuration on each change.
+type SecretWatcher interface {
+	SubscribeSecrets(f func()) (unsubscribe func())
+}
+
+// WithSecrets sets provider of secret fields.
+// Without provider secret fields are loaded from source as other fields.
+func WithSecrets(p SecretProvider) Option {
+	return func(l *loader) {
+		l.secrets = p
+	}
+}
+
+// resolveSecrets returns values with secret fields of v fetched from secrets provider.
+func (l *loader) resolveSecrets(v interface{}, values map[string]string) (map[string]string, error) {
+	if l.secrets == nil {
+		return values, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return values, nil
+	}
+	var resolved map[string]string
+	for _, f := range fields(rv.Elem(), "") {
+		tag, ok := f.Tag.Lookup("vault")
+		if !ok {
+			continue
+		}
+		path, key, _ := strings.Cut(tag, "#")
+		if key == "" {
+			key = "value"
+		}
+		s, err := l.secrets.Secret(path, key)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", f.key, err)
+		}
+		if resolved == nil {
+			resolved = make(map[string]string, len(values))
+			for k, v := range values {
+				resolved[k] = v
+			}
+		}
+		resolved[f.key] = s
+	}
+	if resolved == nil {
+		return values, nil
+	}
+	return resolved, nil
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+)
+
+// Lookups used by templates, replaced in tests.
+var (
+	lookupService = dcy.Services
+	watchService  = dcy.Watch
+	lookupKV      = func(key string, index uint64) (string, uint64, error) {
+		v, idx, err := dcy.KVWait(key, index, kvWait)
+		return string(v), idx, err
+	}
+)
+
+// Render renders Go template text with consul-template style functions:
+//
+//	{{service "mongo"}}           addresses of service instances (host:port), comma separated
+//	{{range service "mongo"}}...  each instance with .Address and .Port
+//	{{kv "path/to/key"}}          value of Consul KV key
+//	{{env "NAME"}}                environment variable
+//
+// Example:
+//
+//	hosts = [{{range $i, $s := service "kafka"}}{{if $i}}, {{end}}"{{$s}}"{{end}}]
+func Render(text string) (string, error) {
+	r := newRenderer(text)
+	return r.render()
+}
+
+// RenderFile renders template from file src into dest and renders it again
+// whenever addresses of used services or values of used KV keys change.
+// Dest is written only when content changes, after that onChange is called (can be nil),
+// e.g. to reload embedded third party library.
+// Returns function which stops watching.
+func RenderFile(src, dest string, onChange func()) (func(), error) {
+	buf, err := os.ReadFile(src)
+	if err != nil {
+		return nil, err
+	}
+	r := newRenderer(string(buf))
+	r.dest = dest
+	r.onChange = onChange
+	if err := r.update(); err != nil {
+		return nil, err
+	}
+	return r.close, nil
+}
+
+// renderer renders template and watches its dependencies.
+type renderer struct {
+	text     string
+	dest     string
+	onChange func()
+	lookupKV func(key string, index uint64) (string, uint64, error)
+
+	updateMu sync.Mutex
+	mu       sync.Mutex
+	last     string
+	services map[string]func()
+	keys     map[string]string
+	watching map[string]bool
+	stop     chan struct{}
+	closed   bool
+}
+
+func newRenderer(text string) *renderer {
+	return &renderer{
+		text:     text,
+		lookupKV: lookupKV,
+		services: map[string]func(){},
+		keys:     map[string]string{},
+		watching: map[string]bool{},
+		stop:     make(chan struct{}),
+	}
+}
+
+// serviceAddrs is result of the service template function.
+type serviceAddrs dcy.Addresses
+
+// String joins addresses with comma.
+func (a serviceAddrs) String() string {
+	return strings.Join(dcy.Addresses(a).String(), ",")
+}
+
+func (r *renderer) render() (string, error) {
+	var used []string
+	t, err := template.New("config").Funcs(template.FuncMap{
+		"service": func(name string) (serviceAddrs, error) {
+			used = append(used, name)
+			addrs, err := lookupService(name)
+			// copy, don't reorder cached addresses
+			addrs = append(dcy.Addresses(nil), addrs...)
+			sort.Slice(addrs, func(i, j int) bool { return addrs[i].String() < addrs[j].String() })
+			return serviceAddrs(addrs), err
+		},
+		"kv": func(key string) string {
+			return r.kv(key)
+		},
+		"env": os.Getenv,
+	}).Parse(r.text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+	r.watchServices(used)
+	return buf.String(), nil
+}
+
+// kv returns value of the key, first use of the key starts watching it.
+func (r *renderer) kv(key string) string {
+	r.mu.Lock()
+	v, ok := r.keys[key]
+	r.mu.Unlock()
+	if ok {
+		return v
+	}
+	v, index, err := r.lookupKV(key, 0)
+	if err != nil {
+		logger().S("key", key).Error(err)
+	}
+	r.mu.Lock()
+	r.keys[key] = v
+	watch := r.dest != "" && !r.watching[key]
+	r.watching[key] = true
+	r.mu.Unlock()
+	if watch {
+		go r.watchKey(key, index)
+	}
+	return v
+}
+
+func (r *renderer) watchKey(key string, index uint64) {
+	for {
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+		v, idx, err := r.lookupKV(key, index)
+		if err != nil {
+			select {
+			case <-time.After(retryInterval):
+			case <-r.stop:
+				return
+			}
+			continue
+		}
+		index = idx
+		r.mu.Lock()
+		changed := r.keys[key] != v
+		r.keys[key] = v
+		r.mu.Unlock()
+		if changed {
+			r.update()
+		}
+	}
+}
+
+// watchServices subscribes to changes of used services.
+func (r *renderer) watchServices(names []string) {
+	if r.dest == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	for _, name := range names {
+		if _, ok := r.services[name]; ok {
+			continue
+		}
+		r.services[name] = watchService(name, func(dcy.Addresses) { go r.update() })
+	}
+}
+
+// update renders template and writes dest if content changed.
+func (r *renderer) update() error {
+	r.updateMu.Lock()
+	defer r.updateMu.Unlock()
+	content, err := r.render()
+	if err != nil {
+		logger().S("dest", r.dest).Error(err)
+		return err
+	}
+	r.mu.Lock()
+	if content == r.last || r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.last = content
+	r.mu.Unlock()
+	if err := writeFile(r.dest, content); err != nil {
+		logger().S("dest", r.dest).Error(err)
+		return err
+	}
+	logger().S("dest", r.dest).Info("template rendered")
+	if r.onChange != nil {
+		r.onChange()
+	}
+	return nil
+}
+
+// writeFile writes content to temporary file and renames it to path.
+func writeFile(path, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (r *renderer) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	close(r.stop)
+	for _, stop := range r.services {
+		stop()
+	}
+}
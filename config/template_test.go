@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	defer func(s func(string) (dcy.Addresses, error), k func(string, uint64) (string, uint64, error)) {
+		lookupService, lookupKV = s, k
+	}(lookupService, lookupKV)
+
+	var mu sync.Mutex
+	addrs := dcy.Addresses{{Address: "10.0.0.2", Port: 9092}, {Address: "10.0.0.1", Port: 9092}}
+	lookupService = func(name string) (dcy.Addresses, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return addrs, nil
+	}
+	kvUpdates := make(chan string)
+	lookupKV = func(key string, index uint64) (string, uint64, error) {
+		if index == 0 {
+			return "info", 1, nil
+		}
+		return <-kvUpdates, index + 1, nil
+	}
+	var handlers []func(dcy.Addresses)
+	stopped := 0
+	defer func(w func(string, func(dcy.Addresses)) func()) { watchService = w }(watchService)
+	watchService = func(name string, h func(dcy.Addresses)) func() {
+		handlers = append(handlers, h)
+		return func() { stopped++ }
+	}
+
+	text := `brokers={{service "kafka"}}
+first={{range $i, $s := service "kafka"}}{{if not $i}}{{$s.Address}}:{{$s.Port}}{{end}}{{end}}
+level={{kv "kafka/log_level"}}
+`
+	out, err := Render(text)
+	assert.NoError(t, err)
+	assert.Equal(t, "brokers=10.0.0.1:9092,10.0.0.2:9092\nfirst=10.0.0.1:9092\nlevel=info\n", out)
+	// looked up addresses are not reordered
+	assert.Equal(t, "10.0.0.2", addrs[0].Address)
+
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "kafka.tmpl"), filepath.Join(dir, "kafka.conf")
+	assert.NoError(t, os.WriteFile(src, []byte(text), 0644))
+	changes := make(chan bool, 10)
+	stop, err := RenderFile(src, dest, func() { changes <- true })
+	assert.NoError(t, err)
+	<-changes
+	assert.Len(t, handlers, 1)
+
+	mu.Lock()
+	addrs = addrs[:1]
+	mu.Unlock()
+	handlers[0](nil)
+	wait(t, changes)
+	buf, _ := os.ReadFile(dest)
+	assert.Contains(t, string(buf), "brokers=10.0.0.2:9092\n")
+
+	kvUpdates <- "debug"
+	wait(t, changes)
+	buf, _ = os.ReadFile(dest)
+	assert.Contains(t, string(buf), "level=debug")
+
+	stop()
+	assert.Equal(t, 1, stopped)
+}
+
+func wait(t *testing.T, ch chan bool) {
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("not rendered")
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks fields of v (pointer to struct) against constraints in validate tags,
+// and calls Validate of v if it implements Validator.
+// Constraints are comma separated:
+//
+//	required     value is not zero
+//	min=n max=n  minimal and maximal number, duration (min=1s) or length of string and slice
+//	oneof=a|b    value is one of listed
+//	url          value is absolute url
+//	duration     string value is valid duration
+//
+// Example:
+//
+//	Workers int    `config:"workers" validate:"required,min=1,max=64"`
+//	Mode    string `config:"mode" validate:"oneof=fast|safe"`
+//
+// Load and Watch validate configuration, invalid reload is not applied.
+func Validate(v interface{}) error {
+	var errs []string
+	for _, f := range fieldsOf(v) {
+		tag := f.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		for _, c := range strings.Split(tag, ",") {
+			if err := check(f.value, strings.TrimSpace(c)); err != nil {
+				errs = append(errs, fmt.Sprintf("config %s: %s", f.key, err))
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	if vl, ok := v.(Validator); ok {
+		return vl.Validate()
+	}
+	return nil
+}
+
+// check checks value against one constraint.
+func check(v reflect.Value, c string) error {
+	name, arg, _ := strings.Cut(c, "=")
+	switch name {
+	case "":
+		return nil
+	case "required":
+		if v.IsZero() {
+			return errors.New("required")
+		}
+	case "min", "max":
+		n, limit, err := compare(v, arg)
+		if err != nil {
+			return err
+		}
+		if name == "min" && n < limit {
+			return fmt.Errorf("must be at least %s", arg)
+		}
+		if name == "max" && n > limit {
+			return fmt.Errorf("must be at most %s", arg)
+		}
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, o := range strings.Split(arg, "|") {
+			if s == o {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", arg)
+	case "url":
+		if v.Kind() != reflect.String || v.String() == "" {
+			return nil
+		}
+		u, err := url.Parse(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("invalid url")
+		}
+	case "duration":
+		if v.Kind() != reflect.String || v.String() == "" {
+			return nil
+		}
+		if _, err := time.ParseDuration(v.String()); err != nil {
+			return errors.New("invalid duration")
+		}
+	default:
+		return fmt.Errorf("unknown constraint %s", name)
+	}
+	return nil
+}
+
+// compare returns value and limit as float64: numbers, durations or length of string and slice.
+func compare(v reflect.Value, arg string) (float64, float64, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+		return float64(v.Int()), float64(d), err
+	}
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid limit %s", arg)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), limit, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), limit, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), limit, nil
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(v.Len()), limit, nil
+	}
+	return 0, 0, fmt.Errorf("min/max not supported for %s", v.Type())
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validatedConfig struct {
+	Workers int           `config:"workers" validate:"required,min=1,max=64"`
+	Mode    string        `config:"mode" default:"fast" validate:"oneof=fast|safe"`
+	URL     string        `config:"url" validate:"url"`
+	Period  string        `config:"period" validate:"duration"`
+	Timeout time.Duration `config:"timeout" default:"1s" validate:"min=100ms,max=1m"`
+	Topics  []string      `config:"topics" validate:"max=2"`
+}
+
+func TestValidate(t *testing.T) {
+	var c validatedConfig
+	assert.NoError(t, Load(&c, WithSource(Map{"workers": "4", "url": "http://backend:8080/"})))
+
+	c = validatedConfig{}
+	err := Load(&c, WithSource(Map{}))
+	assert.EqualError(t, err, "config workers: required")
+
+	c = validatedConfig{}
+	err = Load(&c, WithSource(Map{
+		"workers": "100",
+		"mode":    "slow",
+		"url":     "backend",
+		"period":  "1 minute",
+		"timeout": "1ms",
+		"topics":  "a,b,c",
+	}))
+	assert.EqualError(t, err, "config workers: must be at most 64; config mode: must be one of fast|safe; "+
+		"config url: invalid url; config period: invalid duration; config timeout: must be at least 100ms; "+
+		"config topics: must be at most 2")
+}
+
+func TestWatchRejectsInvalid(t *testing.T) {
+	src := &testSource{updates: make(chan map[string]string), current: Map{"workers": "2"}}
+	v, err := Watch[validatedConfig](WithSource(src))
+	assert.NoError(t, err)
+	defer v.Close()
+	src.updates <- Map{"workers": "0"}
+	src.updates <- Map{"workers": "3"}
+	assert.Equal(t, 3, (<-v.Changes()).Workers)
+}
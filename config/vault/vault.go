@@ -0,0 +1,374 @@
+// Package vault is HashiCorp Vault secrets provider for config package.
+//
+// Client logs in to Vault, caches read secrets and renews token and secret
+// leases in background. Secrets which can't be renewed are read again,
+// config.Watch reloads configuration when secret read again has changed.
+//
+//	vc, err := vault.New(vault.AppRole(roleID, secretID))
+//	...
+//	err = config.Load(&c, config.WithSecrets(vc))
+//
+// Vault address is from VAULT_ADDR, or discovered in Consul as vault service.
+// Without auth option, auth is chosen from environment: VAULT_TOKEN,
+// VAULT_ROLE_ID and VAULT_SECRET_ID (AppRole) or VAULT_K8S_ROLE (Kubernetes).
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/log"
+)
+
+const (
+	// ServiceName is name of the Vault service in Consul.
+	ServiceName = "vault"
+	// K8sTokenPath is location of Kubernetes service account token.
+	K8sTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// authFunc logs in to Vault and returns auth response.
+type authFunc func(c *Client) (*authInfo, error)
+
+// Token auth uses token directly.
+func Token(token string) Option {
+	return func(c *Client) {
+		c.auth = func(c *Client) (*authInfo, error) {
+			return &authInfo{ClientToken: token}, nil
+		}
+	}
+}
+
+// AppRole logs in using AppRole auth method.
+func AppRole(roleID, secretID string) Option {
+	return func(c *Client) {
+		c.auth = func(c *Client) (*authInfo, error) {
+			return c.login("approle", map[string]string{"role_id": roleID, "secret_id": secretID})
+		}
+	}
+}
+
+// Kubernetes logs in using Kubernetes auth method with service account token.
+func Kubernetes(role string) Option {
+	return func(c *Client) {
+		c.auth = func(c *Client) (*authInfo, error) {
+			jwt, err := os.ReadFile(K8sTokenPath)
+			if err != nil {
+				return nil, err
+			}
+			return c.login("kubernetes", map[string]string{"role": role, "jwt": strings.TrimSpace(string(jwt))})
+		}
+	}
+}
+
+// Addr sets Vault address (e.g. https://vault:8200).
+func Addr(addr string) Option {
+	return func(c *Client) {
+		c.addr = strings.TrimSuffix(addr, "/")
+	}
+}
+
+// HTTPClient sets http client used for Vault requests.
+func HTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// Option configures Client.
+type Option func(*Client)
+
+// Client is Vault client implementing config.SecretProvider.
+type Client struct {
+	addr string
+	auth authFunc
+	http *http.Client
+
+	mu      sync.Mutex
+	token   string
+	secrets map[string]*secret
+	timers  map[string]*time.Timer // by lease, token or secret path
+	closed  bool
+	subsID  int
+	subs    map[int]func()
+}
+
+// tokenLease is timers key of the token lease.
+const tokenLease = "auth/token"
+
+type secret struct {
+	data    map[string]string
+	leaseID string
+}
+
+type authInfo struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+type response struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Auth          *authInfo              `json:"auth"`
+	Errors        []string               `json:"errors"`
+}
+
+// New creates client and logs in to Vault.
+func New(opts ...Option) (*Client, error) {
+	c := &Client{
+		http:    &http.Client{Timeout: 10 * time.Second},
+		secrets: map[string]*secret{},
+		timers:  map[string]*time.Timer{},
+		subs:    map[int]func(){},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.addr == "" {
+		addr, err := discover()
+		if err != nil {
+			return nil, err
+		}
+		c.addr = addr
+	}
+	if c.auth == nil {
+		envAuth()(c)
+	}
+	if err := c.authenticate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func discover() (string, error) {
+	if a := os.Getenv("VAULT_ADDR"); a != "" {
+		return strings.TrimSuffix(a, "/"), nil
+	}
+	a, err := dcy.Service(ServiceName)
+	if err != nil {
+		return "", err
+	}
+	return "https://" + a.String(), nil
+}
+
+func envAuth() Option {
+	if id := os.Getenv("VAULT_ROLE_ID"); id != "" {
+		return AppRole(id, os.Getenv("VAULT_SECRET_ID"))
+	}
+	if role := os.Getenv("VAULT_K8S_ROLE"); role != "" {
+		return Kubernetes(role)
+	}
+	return Token(os.Getenv("VAULT_TOKEN"))
+}
+
+// authenticate logs in and schedules token renewal.
+func (c *Client) authenticate() error {
+	a, err := c.auth(c)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.token = a.ClientToken
+	c.mu.Unlock()
+	if a.LeaseDuration > 0 {
+		c.schedule(tokenLease, a.LeaseDuration, c.renewToken)
+	}
+	return nil
+}
+
+func (c *Client) renewToken() {
+	rsp, err := c.do("POST", "auth/token/renew-self", nil)
+	if err == nil && rsp.Auth != nil && rsp.Auth.Renewable {
+		c.schedule(tokenLease, rsp.Auth.LeaseDuration, c.renewToken)
+		return
+	}
+	if err := c.authenticate(); err != nil {
+		logger().Error(err)
+		c.schedule(tokenLease, 10, c.renewToken)
+	}
+}
+
+func (c *Client) login(method string, body interface{}) (*authInfo, error) {
+	rsp, err := c.do("POST", "auth/"+method+"/login", body)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Auth == nil {
+		return nil, fmt.Errorf("vault %s login without auth", method)
+	}
+	return rsp.Auth, nil
+}
+
+// Secret returns key of the secret at path, reading secret from Vault on first use.
+func (c *Client) Secret(path, key string) (string, error) {
+	c.mu.Lock()
+	s, ok := c.secrets[path]
+	c.mu.Unlock()
+	if !ok {
+		var err error
+		if s, err = c.read(path); err != nil {
+			return "", err
+		}
+	}
+	v, ok := s.data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %s", path, key)
+	}
+	return v, nil
+}
+
+// read reads secret, caches it and schedules lease renewal.
+func (c *Client) read(path string) (*secret, error) {
+	rsp, err := c.do("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	data := rsp.Data
+	// kv v2 secret engine has data and metadata
+	if d, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = d
+		}
+	}
+	s := &secret{data: map[string]string{}, leaseID: rsp.LeaseID}
+	for k, v := range data {
+		s.data[k] = fmt.Sprint(v)
+	}
+	c.mu.Lock()
+	old, cached := c.secrets[path]
+	c.secrets[path] = s
+	c.mu.Unlock()
+	if rsp.LeaseDuration > 0 {
+		c.schedule(path, rsp.LeaseDuration, func() { c.renewSecret(path, s, rsp.Renewable) })
+	}
+	if cached && !reflect.DeepEqual(old.data, s.data) {
+		logger().S("path", path).Info("secret changed")
+		c.notify()
+	}
+	return s, nil
+}
+
+// SubscribeSecrets registers function called when cached secret has changed.
+// Implements config.SecretWatcher. Returns function which removes subscription.
+func (c *Client) SubscribeSecrets(f func()) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subsID++
+	id := c.subsID
+	c.subs[id] = f
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subs, id)
+	}
+}
+
+func (c *Client) notify() {
+	c.mu.Lock()
+	fs := make([]func(), 0, len(c.subs))
+	for _, f := range c.subs {
+		fs = append(fs, f)
+	}
+	c.mu.Unlock()
+	for _, f := range fs {
+		f()
+	}
+}
+
+// renewSecret renews secret lease, or reads secret again if it is not renewable.
+func (c *Client) renewSecret(path string, s *secret, renewable bool) {
+	if renewable && s.leaseID != "" {
+		rsp, err := c.do("PUT", "sys/leases/renew", map[string]string{"lease_id": s.leaseID})
+		if err == nil && rsp.LeaseDuration > 0 {
+			c.schedule(path, rsp.LeaseDuration, func() { c.renewSecret(path, s, rsp.Renewable) })
+			return
+		}
+	}
+	if _, err := c.read(path); err != nil {
+		logger().S("path", path).Error(err)
+		c.schedule(path, 10, func() { c.renewSecret(path, s, false) })
+	}
+}
+
+// schedule calls f after 2/3 of lease duration (in seconds).
+// Lease has one timer, previously scheduled call is replaced.
+func (c *Client) schedule(lease string, leaseSeconds int, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if t, ok := c.timers[lease]; ok {
+		t.Stop()
+	}
+	d := time.Duration(leaseSeconds) * time.Second * 2 / 3
+	c.timers[lease] = time.AfterFunc(d, func() {
+		c.mu.Lock()
+		closed := c.closed
+		c.mu.Unlock()
+		if !closed {
+			f()
+		}
+	})
+}
+
+// Close stops renewals.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, t := range c.timers {
+		t.Stop()
+	}
+	c.timers = map[string]*time.Timer{}
+}
+
+func (c *Client) do(method, path string, body interface{}) (*response, error) {
+	var rb io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rb = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, c.addr+"/v1/"+strings.TrimPrefix(path, "/"), rb)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	var r response
+	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if rsp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("vault %s %s: %s %s", method, path, rsp.Status, strings.Join(r.Errors, "; "))
+	}
+	return &r, nil
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.config.vault")
+}
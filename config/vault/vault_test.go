@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	var renewals, reads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rsp interface{}
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["role_id"] != "role" || body["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				rsp = map[string]interface{}{"errors": []string{"permission denied"}}
+				break
+			}
+			rsp = map[string]interface{}{"auth": map[string]interface{}{"client_token": "tok", "lease_duration": 3600}}
+		case "/v1/secret/data/mongo":
+			atomic.AddInt32(&reads, 1)
+			assert.Equal(t, "tok", r.Header.Get("X-Vault-Token"))
+			rsp = map[string]interface{}{"data": map[string]interface{}{
+				"data":     map[string]interface{}{"password": "pass"},
+				"metadata": map[string]interface{}{"version": 1},
+			}}
+		case "/v1/database/creds/app":
+			rsp = map[string]interface{}{"lease_id": "database/creds/app/1", "lease_duration": 1, "renewable": true,
+				"data": map[string]interface{}{"username": "u1"}}
+		case "/v1/sys/leases/renew":
+			atomic.AddInt32(&renewals, 1)
+			rsp = map[string]interface{}{"lease_id": "database/creds/app/1", "lease_duration": 1, "renewable": true}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			rsp = map[string]interface{}{"errors": []string{}}
+		}
+		json.NewEncoder(w).Encode(rsp)
+	}))
+	defer srv.Close()
+
+	_, err := New(Addr(srv.URL), AppRole("role", "wrong"))
+	assert.Error(t, err)
+
+	vc, err := New(Addr(srv.URL), AppRole("role", "secret"))
+	assert.NoError(t, err)
+	defer vc.Close()
+
+	var c struct {
+		Password string `config:"password" vault:"secret/data/mongo#password"`
+		User     string `config:"user" vault:"database/creds/app#username"`
+	}
+	assert.NoError(t, config.Load(&c, config.WithSource(config.Map{}), config.WithSecrets(vc)))
+	assert.Equal(t, "pass", c.Password)
+	assert.Equal(t, "u1", c.User)
+
+	// cached
+	_, err = vc.Secret("secret/data/mongo", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reads))
+	_, err = vc.Secret("secret/data/mongo", "missing")
+	assert.Error(t, err)
+
+	time.Sleep(900 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&renewals) >= 1)
+}
+
+func TestSecretChanged(t *testing.T) {
+	var version int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&version, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"lease_duration": 1,
+			"data": map[string]interface{}{"password": fmt.Sprintf("pass%d", n)}})
+	}))
+	defer srv.Close()
+
+	vc, err := New(Addr(srv.URL), Token("tok"))
+	assert.NoError(t, err)
+	defer vc.Close()
+	changed := make(chan struct{}, 10)
+	vc.SubscribeSecrets(func() { changed <- struct{}{} })
+
+	p, err := vc.Secret("secret/mongo", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "pass1", p)
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not notified")
+	}
+	p, _ = vc.Secret("secret/mongo", "password")
+	assert.NotEqual(t, "pass1", p)
+	vc.mu.Lock()
+	assert.Len(t, vc.timers, 1)
+	vc.mu.Unlock()
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Validator is implemented by configuration which validates itself.
+// Invalid configuration is not applied.
+type Validator interface {
+	Validate() error
+}
+
+// retryInterval is pause after failed watch query.
+var retryInterval = 10 * time.Second
+
+// Value holds current configuration, it is atomically swapped on each change.
+type Value[T any] struct {
+	p      atomic.Pointer[T]
+	mu     sync.Mutex
+	subsID int
+	subs   map[int]func(T)
+	ch     chan T
+	stop   chan struct{}
+	once   sync.Once
+
+	// applyMu serializes source and secret changes, values are last source values
+	applyMu sync.Mutex
+	values  map[string]string
+}
+
+// Watch loads configuration and keeps it up to date by watching source
+// (Consul KV blocking queries by default).
+// Each change is decoded into new T and validated (see Validate),
+// invalid change is logged and not applied.
+// Sources which are not Watchable are loaded only once.
+// With SecretWatcher secrets provider configuration is also reloaded when secret changes.
+//
+//	cfg, err := config.Watch[Config]()
+//	...
+//	cfg.Subscribe(func(c Config) { pool.Resize(c.Workers) })
+//	timeout := cfg.Get().Timeout
+func Watch[T any](opts ...Option) (*Value[T], error) {
+	l := newLoader(opts)
+	v := &Value[T]{
+		subs: map[int]func(T){},
+		ch:   make(chan T, 1),
+		stop: make(chan struct{}),
+	}
+	values, err := l.values(new(T))
+	if err != nil {
+		return nil, err
+	}
+	c, err := decode[T](l, values)
+	if err != nil {
+		return nil, err
+	}
+	v.p.Store(c)
+	v.values = values
+	if w, ok := l.source.(Watchable); ok {
+		go v.watch(l, w, values)
+	}
+	if sw, ok := l.secrets.(SecretWatcher); ok {
+		unsubscribe := sw.SubscribeSecrets(func() { v.reload(l) })
+		go func() {
+			<-v.stop
+			unsubscribe()
+		}()
+	}
+	return v, nil
+}
+
+// decode decodes and validates values into new T.
+func decode[T any](l *loader, values map[string]string) (*T, error) {
+	c := new(T)
+	if err := l.decode(c, values); err != nil {
+		return nil, err
+	}
+	if err := Validate(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (v *Value[T]) watch(l *loader, w Watchable, last map[string]string) {
+	WatchSource(w, last, v.stop, func(values map[string]string) {
+		v.applyMu.Lock()
+		defer v.applyMu.Unlock()
+		v.values = values
+		c, err := decode[T](l, values)
+		if err != nil {
+			logger().Error(err)
+			return
+		}
+		v.set(c)
+		logger().Info("config changed")
+	})
+}
+
+// reload decodes last source values again, with changed secrets.
+func (v *Value[T]) reload(l *loader) {
+	v.applyMu.Lock()
+	defer v.applyMu.Unlock()
+	select {
+	case <-v.stop:
+		return
+	default:
+	}
+	c, err := decode[T](l, v.values)
+	if err != nil {
+		logger().Error(err)
+		return
+	}
+	if reflect.DeepEqual(*c, *v.p.Load()) {
+		return
+	}
+	v.set(c)
+	logger().Info("config changed, secret")
+}
+
+// WatchSource calls changed on each change of w values until stop is closed.
+// Failed queries are retried after retryInterval.
+func WatchSource(w Watchable, last map[string]string, stop <-chan struct{}, changed func(map[string]string)) {
+	var index uint64
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		values, idx, err := w.Wait(index)
+		if err != nil {
+			logger().Error(err)
+			select {
+			case <-time.After(retryInterval):
+			case <-stop:
+				return
+			}
+			continue
+		}
+		index = idx
+		if reflect.DeepEqual(values, last) {
+			continue
+		}
+		last = values
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		changed(values)
+	}
+}
+
+// set stores new configuration and notifies subscribers.
+func (v *Value[T]) set(c *T) {
+	v.p.Store(c)
+	select {
+	case <-v.ch:
+	default:
+	}
+	v.ch <- *c
+	v.mu.Lock()
+	subs := make([]func(T), 0, len(v.subs))
+	for _, f := range v.subs {
+		subs = append(subs, f)
+	}
+	v.mu.Unlock()
+	for _, f := range subs {
+		f(*c)
+	}
+}
+
+// Get returns current configuration.
+func (v *Value[T]) Get() T {
+	return *v.p.Load()
+}
+
+// Subscribe registers function called with new configuration on each change.
+// Returns function which removes subscription.
+func (v *Value[T]) Subscribe(f func(T)) func() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.subsID++
+	id := v.subsID
+	v.subs[id] = f
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		delete(v.subs, id)
+	}
+}
+
+// Changes returns channel of configuration changes.
+// Only the latest change is buffered, slow reader misses intermediate changes.
+func (v *Value[T]) Changes() <-chan T {
+	return v.ch
+}
+
+// Close stops watching.
+func (v *Value[T]) Close() {
+	v.once.Do(func() { close(v.stop) })
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSource is watchable source fed by test.
+type testSource struct {
+	updates chan map[string]string
+	current map[string]string
+}
+
+func (s *testSource) Values() (map[string]string, error) {
+	return s.current, nil
+}
+
+func (s *testSource) Wait(index uint64) (map[string]string, uint64, error) {
+	if index == 0 {
+		return s.current, 1, nil
+	}
+	s.current = <-s.updates
+	return s.current, index + 1, nil
+}
+
+type watchConfig struct {
+	Workers int `config:"workers" default:"1"`
+}
+
+func (c *watchConfig) Validate() error {
+	if c.Workers < 1 {
+		return errors.New("workers must be positive")
+	}
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	src := &testSource{updates: make(chan map[string]string), current: Map{}}
+	v, err := Watch[watchConfig](WithSource(src))
+	assert.NoError(t, err)
+	defer v.Close()
+	assert.Equal(t, 1, v.Get().Workers)
+
+	got := make(chan int, 10)
+	v.Subscribe(func(c watchConfig) { got <- c.Workers })
+
+	src.updates <- Map{"workers": "4"}
+	assert.Equal(t, 4, (<-v.Changes()).Workers)
+	assert.Equal(t, 4, <-got)
+	assert.Equal(t, 4, v.Get().Workers)
+
+	// invalid change is not applied
+	src.updates <- Map{"workers": "0"}
+	src.updates <- Map{"workers": "x"}
+	src.updates <- Map{"workers": "2"}
+	select {
+	case w := <-got:
+		assert.Equal(t, 2, w)
+	case <-time.After(time.Second):
+		t.Fatal("change not delivered")
+	}
+}
+
+func TestWatchInvalid(t *testing.T) {
+	_, err := Watch[watchConfig](WithSource(Map{"workers": "0"}))
+	assert.Error(t, err)
+}
+
+// changingSecrets is secret provider which notifies subscribers on Set.
+type changingSecrets struct {
+	mu    sync.Mutex
+	value string
+	subs  []func()
+}
+
+func (s *changingSecrets) Secret(path, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value, nil
+}
+
+func (s *changingSecrets) SubscribeSecrets(f func()) func() {
+	s.subs = append(s.subs, f)
+	return func() {}
+}
+
+func (s *changingSecrets) Set(value string) {
+	s.mu.Lock()
+	s.value = value
+	s.mu.Unlock()
+	for _, f := range s.subs {
+		f()
+	}
+}
+
+func TestWatchSecrets(t *testing.T) {
+	type secretConfig struct {
+		Password string `config:"password" vault:"secret/mongo#password"`
+	}
+	secrets := &changingSecrets{value: "p1"}
+	v, err := Watch[secretConfig](WithSource(Map{}), WithSecrets(secrets))
+	assert.NoError(t, err)
+	defer v.Close()
+	assert.Equal(t, "p1", v.Get().Password)
+
+	secrets.Set("p2")
+	assert.Equal(t, "p2", (<-v.Changes()).Password)
+	assert.Equal(t, "p2", v.Get().Password)
+}
@@ -26,6 +26,10 @@ const (
 	// EnvWait if defined dcy will not start until those services are not found in consul.
 	// Usefull in development environment to controll start order.
 	EnvWait = "SVCKIT_DCY_CHECK_SVCS"
+
+	// EnvironmentKey is KV key with environment class (prod, staging, dev),
+	// used when Consul node has no env meta.
+	EnvironmentKey = "svckit/environment"
 )
 
 const (
@@ -39,7 +43,8 @@ var (
 	consul      *api.Client
 	l           sync.RWMutex
 	cache       = map[string]Addresses{}
-	subscribers = map[string][]func(Addresses){}
+	subscribers = map[string][]subscriber{}
+	lastSubID   uint64
 
 	domain        string
 	dc            string
@@ -47,6 +52,9 @@ var (
 	advertiseAddr string
 	bindAddr      string
 	consulAddr    = localConsulAdr
+
+	// environment class from node meta
+	nodeEnvironment string
 )
 
 // Address is service address returned from Consul.
@@ -124,6 +132,7 @@ func init() {
 
 	mustConnect()
 	updateEnv()
+	updateEnvironment()
 }
 
 func updateEnv() {
@@ -310,7 +319,7 @@ func query(name string, dc string) (Addresses, error) {
 	}
 	srvs := parseConsulServiceEntries(ses)
 	if len(srvs) == 0 {
-		return nil, fmt.Errorf(fmt.Sprintf("service %s not found in consul %s", name, consulAddr))
+		return nil, fmt.Errorf("service %s not found in consul %s", name, consulAddr)
 	}
 	updateCache(name, dc, srvs)
 	go func() {
@@ -383,9 +392,32 @@ func self() error {
 	nodeName = c["NodeName"].(string)
 	advertiseAddr = c["AdvertiseAddr"].(string)
 	bindAddr = c["BindAddr"].(string)
+	nodeEnvironment = ""
+	if m, ok := s["Meta"]; ok {
+		for _, k := range []string{"env", "environment"} {
+			if e, ok := m[k].(string); ok && e != "" {
+				nodeEnvironment = e
+				break
+			}
+		}
+	}
 	return nil
 }
 
+// updateEnvironment sets environment class from node meta (env or environment key),
+// or from EnvironmentKey in KV.
+func updateEnvironment() {
+	e := nodeEnvironment
+	if e == "" {
+		if v, err := KV(EnvironmentKey); err == nil {
+			e = strings.TrimSpace(string(v))
+		}
+	}
+	if e != "" {
+		env.SetEnvironment(e)
+	}
+}
+
 // Call consul LockKey api function.
 func LockKey(key string) (*api.Lock, error) {
 	return consul.LockKey(key)
@@ -396,6 +428,11 @@ func NodeName() string {
 	return nodeName
 }
 
+// AdvertiseAddr returns address which local Consul agent advertises to the cluster.
+func AdvertiseAddr() string {
+	return advertiseAddr
+}
+
 // Dc returns datacenter name.
 func Dc() string {
 	return dc
@@ -414,6 +451,59 @@ func KV(key string) ([]byte, error) {
 	return pair.Value, nil
 }
 
+// KVPut writes key to Consul key value storage.
+func KVPut(key string, value []byte) error {
+	if consul == nil {
+		return fmt.Errorf("consul %s not connected", consulAddr)
+	}
+	_, err := consul.KV().Put(&api.KVPair{Key: key, Value: value}, nil)
+	return err
+}
+
+// KVWait is blocking query for key.
+// Returns when key index in Consul changes from waitIndex or after wait time.
+func KVWait(key string, waitIndex uint64, wait time.Duration) ([]byte, uint64, error) {
+	if consul == nil {
+		return nil, 0, fmt.Errorf("consul %s not connected", consulAddr)
+	}
+	pair, qm, err := consul.KV().Get(key, &api.QueryOptions{WaitIndex: waitIndex, WaitTime: wait})
+	if err != nil {
+		return nil, 0, err
+	}
+	if pair == nil {
+		return nil, qm.LastIndex, fmt.Errorf("key not found")
+	}
+	return pair.Value, qm.LastIndex, nil
+}
+
+// KVList reads all keys under prefix from Consul key value storage.
+// Keys in the result are relative to prefix, directory keys are skipped.
+// Returns Consul index usable as wait index in KVListWait.
+func KVList(prefix string) (map[string][]byte, uint64, error) {
+	return KVListWait(prefix, 0, 0)
+}
+
+// KVListWait is blocking query for keys under prefix.
+// Returns when index in Consul changes from waitIndex or after wait time.
+func KVListWait(prefix string, waitIndex uint64, wait time.Duration) (map[string][]byte, uint64, error) {
+	if consul == nil {
+		return nil, 0, fmt.Errorf("consul %s not connected", consulAddr)
+	}
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	pairs, qm, err := consul.KV().List(prefix, &api.QueryOptions{WaitIndex: waitIndex, WaitTime: wait})
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make(map[string][]byte, len(pairs))
+	for _, p := range pairs {
+		if strings.HasSuffix(p.Key, "/") {
+			continue
+		}
+		kvs[strings.TrimPrefix(p.Key, prefix)] = p.Value
+	}
+	return kvs, qm.LastIndex, nil
+}
+
 // URL discovers host from url.
 // If there are multiple services will randomly choose one.
 func URL(url string) string {
@@ -505,6 +595,34 @@ func Agent() *api.Agent {
 	return consul.Agent()
 }
 
+// Catalog returns ref to consul catalog.
+// Only for use in sr package below.
+func Catalog() *api.Catalog {
+	return consul.Catalog()
+}
+
+// Raw returns handle for raw queries to consul endpoints
+// not covered by the vendored api (Connect, weights...).
+// Only for use in sr package below.
+func Raw() *api.Raw {
+	return consul.Raw()
+}
+
+// Ping checks that consul agent is reachable and that cluster has leader.
+func Ping() error {
+	if consul == nil {
+		return fmt.Errorf("consul %s not connected", consulAddr)
+	}
+	leader, err := consul.Status().Leader()
+	if err != nil {
+		return err
+	}
+	if leader == "" {
+		return fmt.Errorf("consul %s has no leader", consulAddr)
+	}
+	return nil
+}
+
 // MustConnect connects to real consul.
 // Useful in tests, when dcy is started in test mode to force to connect to real consul.
 func MustConnect() {
@@ -514,25 +632,52 @@ func MustConnect() {
 // Subscribe on service changes.
 // Changes in Consul for service `name` will be passed to handler.
 func Subscribe(name string, handler func(Addresses)) {
+	subscribe(name, handler)
+}
+
+// Watch subscribes on service changes like Subscribe,
+// and returns function which removes exactly this subscription.
+// Use it instead of Unsubscribe when there can be more subscribers with the same handler
+// (method values of different objects, closures from the same function literal).
+func Watch(name string, handler func(Addresses)) (stop func()) {
+	id := subscribe(name, handler)
+	return func() {
+		l.Lock()
+		defer l.Unlock()
+		a := subscribers[name]
+		for i, s := range a {
+			if s.id == id {
+				subscribers[name] = append(a[:i:i], a[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+type subscriber struct {
+	id      uint64
+	handler func(Addresses)
+}
+
+func subscribe(name string, handler func(Addresses)) uint64 {
 	l.Lock()
 	defer l.Unlock()
-	a := subscribers[name]
-	if a == nil {
-		a = make([]func(Addresses), 0)
-	}
-	a = append(a, handler)
-	subscribers[name] = a
+	lastSubID++
+	subscribers[name] = append(subscribers[name], subscriber{id: lastSubID, handler: handler})
+	return lastSubID
 }
 
 func notify(name string, srvs Addresses) {
 	if s, ok := subscribers[name]; ok {
 		for _, h := range s {
-			h(srvs)
+			h.handler(srvs)
 		}
 	}
 }
 
 // Unsubscribe from service changes.
+// Handlers are compared by code pointer, so this removes first subscription with
+// the same function (or method), see Watch.
 func Unsubscribe(name string, handler func(Addresses)) {
 	l.Lock()
 	defer l.Unlock()
@@ -541,7 +686,7 @@ func Unsubscribe(name string, handler func(Addresses)) {
 		return
 	}
 	for i, h := range a {
-		sf1 := reflect.ValueOf(h)
+		sf1 := reflect.ValueOf(h.handler)
 		sf2 := reflect.ValueOf(handler)
 		if sf1.Pointer() == sf2.Pointer() {
 			a = append(a[:i], a[i+1:]...)
@@ -148,3 +148,19 @@ func TestSubscribe(t *testing.T) {
 	assert.Len(t, subscribers["svc"], 1)
 
 }
+
+func TestWatch(t *testing.T) {
+	var calls []int
+	h := func(i int) func(Addresses) { return func(Addresses) { calls = append(calls, i) } }
+	stop1 := Watch("watch", h(1))
+	stop2 := Watch("watch", h(2))
+	assert.Len(t, subscribers["watch"], 2)
+	stop2()
+	l.Lock()
+	notify("watch", nil)
+	l.Unlock()
+	assert.Equal(t, []int{1}, calls)
+	stop1()
+	stop1()
+	assert.Len(t, subscribers["watch"], 0)
+}
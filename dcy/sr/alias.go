@@ -0,0 +1,55 @@
+package sr
+
+import "fmt"
+
+type alias struct {
+	node      string
+	serviceID string
+}
+
+// agentAliasCheck is alias check registration,
+// not supported in vendored consul api.
+type agentAliasCheck struct {
+	ID           string
+	Name         string
+	ServiceID    string
+	AliasNode    string `json:",omitempty"`
+	AliasService string `json:",omitempty"`
+}
+
+// Alias adds alias check for hard dependency of the service.
+// Service is marked unhealthy when aliased service (or node if serviceID is empty) is unhealthy.
+// Node can be empty when aliased service is registered on the local agent.
+func Alias(node, serviceID string) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.aliases = append(s.aliases, alias{node: node, serviceID: serviceID})
+	}
+}
+
+func (a alias) target() string {
+	if a.serviceID == "" {
+		return a.node
+	}
+	return a.serviceID
+}
+
+func (s *serviceRegistrator) aliasCheckID(a alias) string {
+	return fmt.Sprintf("%s_alias_%s", s.id, a.target())
+}
+
+func (s *serviceRegistrator) registerAliases() error {
+	for _, a := range s.aliases {
+		target := a.target()
+		check := &agentAliasCheck{
+			ID:           s.aliasCheckID(a),
+			Name:         fmt.Sprintf("Service '%s' alias for '%s'", s.name, target),
+			ServiceID:    s.id,
+			AliasNode:    a.node,
+			AliasService: a.serviceID,
+		}
+		if _, err := consulRaw().Write("/v1/agent/check/register", check, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
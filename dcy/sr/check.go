@@ -0,0 +1,88 @@
+package sr
+
+import (
+	"fmt"
+	"time"
+)
+
+// CheckSpec describes additional Consul check of the service.
+// Exactly one of HTTP, TCP or GRPC must be set.
+type CheckSpec struct {
+	Name string `json:"name"`
+	// HTTP url, check is passing on 2xx response
+	HTTP string `json:"http,omitempty"`
+	// TCP address in host:port format
+	TCP string `json:"tcp,omitempty"`
+	// GRPC address in host:port/service format
+	GRPC string `json:"grpc,omitempty"`
+	// Interval in time.Duration format, default is heartbeat interval
+	Interval string `json:"interval,omitempty"`
+	// Timeout in time.Duration format
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// agentCheck is http, tcp or grpc check registration.
+type agentCheck struct {
+	ID        string
+	Name      string
+	ServiceID string
+	HTTP      string `json:",omitempty"`
+	TCP       string `json:",omitempty"`
+	GRPC      string `json:",omitempty"`
+	Interval  string
+	Timeout   string `json:",omitempty"`
+}
+
+// Check adds Consul check of the service.
+func Check(c CheckSpec) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.checks = append(s.checks, c)
+	}
+}
+
+func (c CheckSpec) validate() error {
+	n := 0
+	for _, t := range []string{c.HTTP, c.TCP, c.GRPC} {
+		if t != "" {
+			n++
+		}
+	}
+	if c.Name == "" || n != 1 {
+		return fmt.Errorf("check requires name and one of http, tcp or grpc")
+	}
+	for _, d := range []string{c.Interval, c.Timeout} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("check %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *serviceRegistrator) checkID(c CheckSpec) string {
+	return fmt.Sprintf("%s_check_%s", s.id, c.Name)
+}
+
+func (s *serviceRegistrator) registerChecks() error {
+	for _, c := range s.checks {
+		check := &agentCheck{
+			ID:        s.checkID(c),
+			Name:      fmt.Sprintf("Service '%s' %s check", s.name, c.Name),
+			ServiceID: s.id,
+			HTTP:      c.HTTP,
+			TCP:       c.TCP,
+			GRPC:      c.GRPC,
+			Interval:  c.Interval,
+			Timeout:   c.Timeout,
+		}
+		if check.Interval == "" {
+			check.Interval = fmt.Sprintf("%ds", s.interval)
+		}
+		if _, err := consulRaw().Write("/v1/agent/check/register", check, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
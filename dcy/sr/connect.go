@@ -0,0 +1,43 @@
+package sr
+
+// Upstream is Connect upstream declaration of the sidecar proxy.
+// Proxy listens on LocalBindPort and forwards to the DestinationName service.
+type Upstream struct {
+	DestinationName string
+	LocalBindPort   int
+	Datacenter      string `json:",omitempty"`
+}
+
+type agentServiceConnect struct {
+	Native         bool                 `json:",omitempty"`
+	SidecarService *agentSidecarService `json:",omitempty"`
+}
+
+type agentSidecarService struct {
+	Port  int                `json:",omitempty"`
+	Proxy *agentServiceProxy `json:",omitempty"`
+}
+
+type agentServiceProxy struct {
+	Upstreams []Upstream `json:",omitempty"`
+}
+
+// Sidecar registers Connect sidecar proxy alongside the service.
+// Sidecar is deregistered by Consul together with the service.
+func Sidecar(upstreams ...Upstream) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.connect = &agentServiceConnect{
+			SidecarService: &agentSidecarService{
+				Proxy: &agentServiceProxy{Upstreams: upstreams},
+			},
+		}
+	}
+}
+
+// ConnectNative registers service as Connect native,
+// application itself handles Connect mTLS.
+func ConnectNative() func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.connect = &agentServiceConnect{Native: true}
+	}
+}
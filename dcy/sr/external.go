@@ -0,0 +1,49 @@
+package sr
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/minus5/svckit/dcy"
+)
+
+// External is third-party service (managed database, SaaS endpoint...)
+// registered in consul catalog under external node.
+type External struct {
+	node string
+	id   string
+}
+
+// RegisterExternal registers service name on external node with address addr.
+// Registered service is discoverable through dcy like any other service.
+// External services have no agent and no ttl check, registration stays in
+// catalog until Deregister is called.
+func RegisterExternal(node, name, addr string, port int) (*External, error) {
+	e := &External{
+		node: node,
+		id:   fmt.Sprintf("%s:%d", name, port),
+	}
+	reg := &api.CatalogRegistration{
+		Node:    node,
+		Address: addr,
+		Service: &api.AgentService{
+			ID:      e.id,
+			Service: name,
+			Address: addr,
+			Port:    port,
+		},
+	}
+	if _, err := dcy.Catalog().Register(reg, nil); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Deregister removes external service from catalog.
+func (e *External) Deregister() error {
+	_, err := dcy.Catalog().Deregister(&api.CatalogDeregistration{
+		Node:      e.node,
+		ServiceID: e.id,
+	}, nil)
+	return err
+}
@@ -0,0 +1,54 @@
+package sr
+
+import (
+	"fmt"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/health"
+)
+
+// agentGRPCCheck is grpc check registration,
+// not supported in vendored consul api.
+type agentGRPCCheck struct {
+	ID        string
+	Name      string
+	ServiceID string
+	GRPC      string
+	Interval  string
+}
+
+// GRPCHealth registers Consul gRPC check against the grpc.health.v1 service
+// exposed on the service port, and wires health readiness into srv
+// (google.golang.org/grpc/health.Server).
+// Status updates of srv start after service is registered and stop on Deregister.
+// S is grpc_health_v1.HealthCheckResponse_ServingStatus.
+//
+// Example:
+//
+//	srv := grpchealth.NewServer()
+//	sr.New(port, sr.GRPCHealth(srv, "my.Service"))
+func GRPCHealth[S ~int32](srv health.GRPCHealthServer[S], service string) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.grpcService = &service
+		s.serveGRPC = func() func() { return health.ServeGRPC(srv, service) }
+	}
+}
+
+func (s *serviceRegistrator) registerGRPCCheck() error {
+	if s.grpcService == nil {
+		return nil
+	}
+	target := fmt.Sprintf("%s:%d", dcy.AdvertiseAddr(), s.port)
+	if *s.grpcService != "" {
+		target = target + "/" + *s.grpcService
+	}
+	check := &agentGRPCCheck{
+		ID:        fmt.Sprintf("%s_grpc_check", s.id),
+		Name:      fmt.Sprintf("Service '%s' grpc check", s.name),
+		ServiceID: s.id,
+		GRPC:      target,
+		Interval:  fmt.Sprintf("%ds", s.interval),
+	}
+	_, err := consulRaw().Write("/v1/agent/check/register", check, nil, nil)
+	return err
+}
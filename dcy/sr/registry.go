@@ -0,0 +1,91 @@
+package sr
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	registryMu    sync.Mutex
+	registrations = map[string]*serviceRegistrator{}
+)
+
+// Registration describes state of one service registered by this process.
+type Registration struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Port          int       `json:"port"`
+	Tags          []string  `json:"tags,omitempty"`
+	CheckID       string    `json:"check_id"`
+	Status        string    `json:"status"`
+	Note          string    `json:"note,omitempty"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	LastError     string    `json:"last_error,omitempty"`
+	Failures      int       `json:"failures"`
+}
+
+// heartbeat is result of the last ttl update.
+type heartbeat struct {
+	status checkStatus
+	time   time.Time
+	err    error
+}
+
+func addRegistration(s *serviceRegistrator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registrations[s.id] = s
+}
+
+func removeRegistration(s *serviceRegistrator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registrations, s.id)
+}
+
+// Registrations returns current registrations of this process.
+func Registrations() []Registration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	rs := make([]Registration, 0, len(registrations))
+	for _, s := range registrations {
+		rs = append(rs, s.registration())
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].ID < rs[j].ID })
+	return rs
+}
+
+// HttpHandler exposes current registrations as json.
+// Useful for debugging why instance is not receiving traffic.
+func HttpHandler(w http.ResponseWriter, r *http.Request) {
+	buf, err := json.MarshalIndent(Registrations(), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
+
+func (s *serviceRegistrator) registration() Registration {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	r := Registration{
+		ID:            s.id,
+		Name:          s.name,
+		Port:          s.port,
+		Tags:          s.tags,
+		CheckID:       s.checkId,
+		Status:        s.last.status.status.String(),
+		Note:          string(s.last.status.note),
+		LastHeartbeat: s.last.time,
+		Failures:      s.failures,
+	}
+	if s.last.err != nil {
+		r.LastError = s.last.err.Error()
+	}
+	return r
+}
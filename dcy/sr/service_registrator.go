@@ -1,7 +1,11 @@
 package sr
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/consul/api"
@@ -9,6 +13,7 @@ import (
 	"github.com/minus5/svckit/env"
 	"github.com/minus5/svckit/health"
 	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
 )
 
 // Name sets the service name.
@@ -20,26 +25,125 @@ func Name(name string) func(*serviceRegistrator) {
 }
 
 // HealthCheck sets the health check handler.
+// Without handler check is passing, unless changed with SetStatus.
 func HealthCheck(handler healthCheckHandler) func(*serviceRegistrator) {
 	return func(s *serviceRegistrator) {
 		s.handler = handler
 	}
 }
 
+// Readiness sets health.Ready as health check handler, check follows
+// aggregated status of all readiness probes registered in health package.
+func Readiness() func(*serviceRegistrator) {
+	return HealthCheck(health.Ready)
+}
+
+// HealthPollInterval sets interval of checking health handler between heartbeats.
+// Changed status is sent to Consul immediately, so routing follows application readiness
+// without waiting for the next heartbeat, changed note only with the heartbeat.
+// Handler is called on every poll, so keep it cheap. Default is 5s, 0 disables polling.
+func HealthPollInterval(d time.Duration) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.pollInterval = d
+	}
+}
+
+// Tags sets service tags.
+func Tags(tags ...string) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.tags = tags
+	}
+}
+
+// DeregisterCriticalAfter sets interval after which consul will deregister
+// service whose ttl check stays in critical state.
+// Cleans up instances which crashed without deregistering.
+func DeregisterCriticalAfter(d time.Duration) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.deregisterAfter = d
+	}
+}
+
+// OnHeartbeatFailure sets handler called when n consecutive ttl updates fail.
+// After that application is not visible in the cluster, handler can
+// e.g. flip health status or restart registration.
+func OnHeartbeatFailure(n int, handler func(error)) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.maxFailures = n
+		s.onFailure = handler
+	}
+}
+
+// Weights sets service weights used in DNS SRV responses
+// and by weighted load balancing when instance is passing or warning.
+func Weights(passing, warning int) func(*serviceRegistrator) {
+	return func(s *serviceRegistrator) {
+		s.weights = &agentWeights{Passing: passing, Warning: warning}
+	}
+}
+
+// consul handles, replaced in tests
+var (
+	consulAgent = dcy.Agent
+	consulRaw   = dcy.Raw
+)
+
 type serviceRegistrator struct {
-	id        string
-	name      string
-	port      int
-	ttl       int
-	interval  int
-	agent     *api.Agent
-	checkId   string
-	close     chan bool
-	closed    chan struct{}
-	setStatus chan health.Status
-	handler   healthCheckHandler
+	id       string
+	name     string
+	port     int
+	tags     []string
+	ttl      int
+	interval int
+	agent    *api.Agent
+
+	pollInterval time.Duration
+	checkId      string
+	close        chan bool
+	closed       chan struct{}
+	setStatus    chan checkStatus
+	handler      healthCheckHandler
+	connect      *agentServiceConnect
+	aliases      []alias
+	checks       []CheckSpec
+
+	grpcService *string
+	serveGRPC   func() func()
+	stopGRPC    func()
+
+	deregisterAfter time.Duration
+	maxFailures     int
+	failures        int
+	onFailure       func(error)
+
+	weights *agentWeights
+	changes chan change
+
+	stateMu sync.Mutex
+	last    heartbeat
+}
+
+// agentServiceRegistration extends api registration with fields
+// which are not supported in vendored consul api.
+type agentServiceRegistration struct {
+	api.AgentServiceRegistration
+	Connect *agentServiceConnect `json:",omitempty"`
+	Weights *agentWeights        `json:",omitempty"`
 }
 
+type agentWeights struct {
+	Passing int
+	Warning int
+}
+
+// checkStatus is status with optional human-readable output for the ttl check.
+type checkStatus struct {
+	status health.Status
+	note   []byte
+}
+
+const visiblePollInterval = 250 * time.Millisecond
+
 type healthCheckHandler func() (health.Status, []byte)
 
 // New ...
@@ -51,7 +155,10 @@ func New(port int, opts ...func(*serviceRegistrator)) (*serviceRegistrator, erro
 		interval:  9,
 		close:     make(chan bool),
 		closed:    make(chan struct{}),
-		setStatus: make(chan health.Status),
+		setStatus: make(chan checkStatus),
+		changes:   make(chan change),
+
+		pollInterval: 5 * time.Second,
 	}
 	// apply options
 	for _, opt := range opts {
@@ -64,29 +171,130 @@ func New(port int, opts ...func(*serviceRegistrator)) (*serviceRegistrator, erro
 	if err := s.register(); err != nil {
 		return nil, err
 	}
+	if s.serveGRPC != nil {
+		s.stopGRPC = s.serveGRPC()
+	}
+	addRegistration(s)
 	go s.loop()
 	return s, nil
 }
 
+// SetStatus sets check status and output note.
+// Note is shown in Consul UI as check output (e.g. "mongo unreachable").
+// After Deregister or Stop status is ignored.
+func (s *serviceRegistrator) SetStatus(status health.Status, note string) {
+	select {
+	case s.setStatus <- checkStatus{status: status, note: []byte(note)}:
+	case <-s.closed:
+	}
+}
+
 // Passing sets status to passing.
 func (s *serviceRegistrator) Passing() {
-	s.setStatus <- health.Passing
+	s.SetStatus(health.Passing, "")
 }
 
 // Warn sets status to warn.
 func (s *serviceRegistrator) Warn() {
-	s.setStatus <- health.Warn
+	s.SetStatus(health.Warn, "")
 }
 
 // Fail sets status to fail.
 func (s *serviceRegistrator) Fail() {
-	s.setStatus <- health.Fail
+	s.SetStatus(health.Fail, "")
+}
+
+// SetWeights changes service weights at runtime.
+// Useful for gradual traffic shifting to/from this instance.
+func (s *serviceRegistrator) SetWeights(passing, warning int) error {
+	w := &agentWeights{Passing: passing, Warning: warning}
+	return s.reconfigure(func(s *serviceRegistrator) bool {
+		if s.weights != nil && *s.weights == *w {
+			return false
+		}
+		s.weights = w
+		return true
+	})
+}
+
+// ErrClosed is returned when registration is changed after Deregister or Stop.
+var ErrClosed = errors.New("service registration closed")
+
+// change of the registration, applied in loop goroutine.
+type change struct {
+	apply func(*serviceRegistrator) bool
+	done  chan error
+}
+
+// reconfigure calls apply in loop goroutine, service is re-registered if apply returns true.
+func (s *serviceRegistrator) reconfigure(apply func(*serviceRegistrator) bool) error {
+	c := change{apply: apply, done: make(chan error, 1)}
+	select {
+	case s.changes <- c:
+		return <-c.done
+	case <-s.closed:
+		return ErrClosed
+	}
+}
+
+// apply changes registration and re-registers service.
+// Checks which are not in the changed registration are deregistered.
+func (s *serviceRegistrator) apply(fn func(*serviceRegistrator) bool) (bool, error) {
+	old := s.checkIDs()
+	s.stateMu.Lock()
+	changed := fn(s)
+	s.stateMu.Unlock()
+	if !changed {
+		return false, nil
+	}
+	if err := s.register(); err != nil {
+		return true, err
+	}
+	current := s.checkIDs()
+	for id := range old {
+		if current[id] {
+			continue
+		}
+		if err := s.agent.CheckDeregister(id); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// checkIDs returns ids of alias and additional checks.
+func (s *serviceRegistrator) checkIDs() map[string]bool {
+	ids := map[string]bool{}
+	for _, a := range s.aliases {
+		ids[s.aliasCheckID(a)] = true
+	}
+	for _, c := range s.checks {
+		ids[s.checkID(c)] = true
+	}
+	return ids
+}
+
+// WaitVisible blocks until this instance appears in Consul health query,
+// so it is discoverable (and routable) by other services.
+// Returns error if instance is not visible after timeout.
+func (s *serviceRegistrator) WaitVisible(timeout time.Duration) error {
+	self := dcy.Address{Address: dcy.AdvertiseAddr(), Port: s.port}
+	deadline := time.Now().Add(timeout)
+	for {
+		addrs, err := dcy.Services(s.name)
+		if err == nil && addrs.Contains(self) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s not visible in consul after %v", self, timeout)
+		}
+		time.Sleep(visiblePollInterval)
+	}
 }
 
 // Deregister service in consul.
 func (s *serviceRegistrator) Deregister() {
-	s.close <- true
-	<-s.closed
+	s.stop(true)
 }
 
 // Close alias for Deregister.
@@ -96,52 +304,121 @@ func (s *serviceRegistrator) Close() {
 
 // Stop sending ttl to consul without deregister.
 func (s *serviceRegistrator) Stop() {
-	s.close <- false
-	<-s.closed
+	s.stop(false)
+}
+
+func (s *serviceRegistrator) stop(dereg bool) {
+	select {
+	case s.close <- dereg:
+		<-s.closed
+	case <-s.closed:
+	}
 }
 
 func (s *serviceRegistrator) loop() {
-	status := health.Passing
-	var note []byte
+	// status set through SetStatus, combined with handler status
+	manual := checkStatus{status: health.Passing}
 
-	readAndUpdateStatus := func() {
+	read := func() (health.Status, []byte) {
+		status, note := manual.status, manual.note
 		if s.handler != nil {
-			status, note = s.handler()
+			hs, hn := s.handler()
+			status.Add(hs)
+			note = joinNotes(note, hn)
 		}
-		s.updateStatus(status, note)
+		return status, note
+	}
+	readAndUpdateStatus := func() {
+		s.updateStatus(read())
+	}
+	var poll <-chan time.Time
+	if s.pollInterval > 0 && s.handler != nil {
+		ticker := time.NewTicker(s.pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
 	}
 
 	readAndUpdateStatus()
+	heartbeat := time.NewTimer(s.nextHeartbeat())
+	defer heartbeat.Stop()
 	for {
 		select {
-		case <-time.After(time.Duration(s.interval) * time.Second):
+		case <-heartbeat.C:
 			readAndUpdateStatus()
-		case newStatus := <-s.setStatus:
-			if status != newStatus {
-				status = newStatus
+			heartbeat.Reset(s.nextHeartbeat())
+		case <-poll:
+			status, note := read()
+			if s.statusChanged(status) {
+				logger().S("id", s.id).S("status", status.String()).Info("health changed")
 				s.updateStatus(status, note)
 			}
+		case c := <-s.changes:
+			changed, err := s.apply(c.apply)
+			if changed {
+				// re-registered check starts as critical, update it immediately
+				readAndUpdateStatus()
+			}
+			c.done <- err
+		case cs := <-s.setStatus:
+			if manual.status != cs.status || !bytes.Equal(manual.note, cs.note) {
+				manual = cs
+				readAndUpdateStatus()
+			}
 		case dereg := <-s.close:
+			if s.stopGRPC != nil {
+				s.stopGRPC()
+			}
 			if dereg {
 				s.deregister()
 			}
+			removeRegistration(s)
 			close(s.closed)
 			return
 		}
 	}
 }
 
+// statusChanged reports whether status differs from the last sent to Consul, or sending failed.
+// Note changes alone (e.g. error text with timestamp) wait for the next heartbeat.
+func (s *serviceRegistrator) statusChanged(status health.Status) bool {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.last.err != nil || s.last.status.status != status
+}
+
+func joinNotes(n1, n2 []byte) []byte {
+	if len(n1) == 0 {
+		return n2
+	}
+	if len(n2) == 0 {
+		return n1
+	}
+	return append(append(append([]byte{}, n1...), '\n'), n2...)
+}
+
+// nextHeartbeat returns interval with up to 10% jitter subtracted,
+// so heartbeats of many instances don't hit agent in the same moment.
+func (s *serviceRegistrator) nextHeartbeat() time.Duration {
+	interval := time.Duration(s.interval) * time.Second
+	return interval - time.Duration(rand.Int63n(int64(interval/10)+1))
+}
+
 func (s *serviceRegistrator) deregister() {
 	_ = s.agent.ServiceDeregister(s.id)
 }
 
 func (s *serviceRegistrator) register() error {
-	s.agent = dcy.Agent()
+	s.agent = consulAgent()
 
-	service := &api.AgentServiceRegistration{
-		ID:   s.id,
-		Name: s.name,
-		Port: s.port,
+	service := &agentServiceRegistration{
+		AgentServiceRegistration: api.AgentServiceRegistration{
+			ID:   s.id,
+			Name: s.name,
+			Port: s.port,
+			Tags: s.tags,
+		},
+		Connect: s.connect,
+		Weights: s.weights,
 	}
 	check := &api.AgentCheckRegistration{
 		ID:        s.checkId,
@@ -153,14 +430,23 @@ func (s *serviceRegistrator) register() error {
 			//Status: "passing",
 		},
 	}
+	if s.deregisterAfter > 0 {
+		check.DeregisterCriticalServiceAfter = s.deregisterAfter.String()
+	}
 
-	if err := s.agent.ServiceRegister(service); err != nil {
+	if _, err := consulRaw().Write("/v1/agent/service/register", service, nil, nil); err != nil {
 		return err
 	}
 	if err := s.agent.CheckRegister(check); err != nil {
 		return err
 	}
-	return nil
+	if err := s.registerGRPCCheck(); err != nil {
+		return err
+	}
+	if err := s.registerChecks(); err != nil {
+		return err
+	}
+	return s.registerAliases()
 }
 
 func (s *serviceRegistrator) updateStatus(status health.Status, note []byte) {
@@ -171,8 +457,28 @@ func (s *serviceRegistrator) updateStatus(status health.Status, note []byte) {
 	case health.Warn:
 		fn = s.agent.WarnTTL
 	}
+	sw := metric.NewStopwatch()
 	err := fn(s.checkId, string(note))
+	s.stateMu.Lock()
+	s.last = heartbeat{status: checkStatus{status: status, note: note}, time: time.Now(), err: err}
+	if err != nil {
+		s.failures++
+	} else {
+		s.failures = 0
+	}
+	failures := s.failures
+	s.stateMu.Unlock()
 	if err != nil {
-		log.Error(err)
+		metric.Time("sr.heartbeat.fail", sw.GetNs())
+		logger().S("id", s.id).I("failures", failures).Error(err)
+		if s.onFailure != nil && failures == s.maxFailures {
+			s.onFailure(err)
+		}
+		return
 	}
+	metric.Time("sr.heartbeat.pass", sw.GetNs())
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.sr")
 }
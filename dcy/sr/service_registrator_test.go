@@ -1,10 +1,19 @@
 package sr
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/health"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,6 +31,7 @@ func TestServiceRegistrator(t *testing.T) {
 	assert.Equal(t, port, svc.Port)
 	assert.Equal(t, 1, len(checks))
 	assert.Equal(t, "passing", checks[0].Status)
+	assert.Nil(t, sr.WaitVisible(5*time.Second))
 	//time.Sleep(10 * time.Second)
 
 	sr.Warn()
@@ -37,6 +47,11 @@ func TestServiceRegistrator(t *testing.T) {
 	sr.Passing()
 	svc, checks = consulService(t, name)
 	assert.Equal(t, "passing", checks[0].Status)
+
+	sr.SetStatus(health.Warn, "mongo unreachable")
+	svc, checks = consulService(t, name)
+	assert.Equal(t, "warning", checks[0].Status)
+	assert.Equal(t, "mongo unreachable", checks[0].Output)
 	//time.Sleep(10 * time.Second)
 
 	sr.Deregister()
@@ -67,3 +82,271 @@ func consulService(t *testing.T, name string) (*api.AgentService, []*api.AgentCh
 	}
 	return nil, nil
 }
+
+func TestConnectRegistration(t *testing.T) {
+	s := &serviceRegistrator{}
+	Sidecar(Upstream{DestinationName: "mongo", LocalBindPort: 27017})(s)
+	reg := agentServiceRegistration{
+		AgentServiceRegistration: api.AgentServiceRegistration{ID: "svc:80", Name: "svc", Port: 80},
+		Connect:                  s.connect,
+	}
+	buf, err := json.Marshal(reg)
+	assert.Nil(t, err)
+	assert.Contains(t, string(buf), `"Name":"svc"`)
+	assert.Contains(t, string(buf), `"Connect":{"SidecarService":{"Proxy":{"Upstreams":[{"DestinationName":"mongo","LocalBindPort":27017}]}}}`)
+
+	ConnectNative()(s)
+	assert.True(t, s.connect.Native)
+	assert.Nil(t, s.connect.SidecarService)
+}
+
+func TestNextHeartbeatJitter(t *testing.T) {
+	s := &serviceRegistrator{interval: 9}
+	for i := 0; i < 100; i++ {
+		d := s.nextHeartbeat()
+		assert.True(t, d <= 9*time.Second)
+		assert.True(t, d >= 8100*time.Millisecond)
+	}
+}
+
+func TestStatusChanged(t *testing.T) {
+	s := &serviceRegistrator{}
+	s.last = heartbeat{status: checkStatus{status: health.Passing}}
+	assert.False(t, s.statusChanged(health.Passing))
+	assert.True(t, s.statusChanged(health.Fail))
+	s.last = heartbeat{status: checkStatus{status: health.Fail, note: []byte("mongo: unreachable")}}
+	assert.False(t, s.statusChanged(health.Fail))
+	s.last.err = errors.New("consul unavailable")
+	assert.True(t, s.statusChanged(health.Fail))
+}
+
+func TestRegistrations(t *testing.T) {
+	s := &serviceRegistrator{id: "svc:80", name: "svc", port: 80, tags: []string{"v1"}}
+	s.last = heartbeat{status: checkStatus{status: health.Warn, note: []byte("slow mongo")}, time: time.Now()}
+	addRegistration(s)
+	defer removeRegistration(s)
+
+	rs := Registrations()
+	assert.Equal(t, 1, len(rs))
+	assert.Equal(t, "svc", rs[0].Name)
+	assert.Equal(t, []string{"v1"}, rs[0].Tags)
+	assert.Equal(t, "warn", rs[0].Status)
+	assert.Equal(t, "slow mongo", rs[0].Note)
+}
+
+func TestSpecOptions(t *testing.T) {
+	spec, err := ParseSpec([]byte(`{"services":[{"name":"svc","port":80,"tags":["v1"],
+		"weights":{"passing":10,"warning":1},"deregister_critical_after":"30m",
+		"aliases":["mongo:27017","node01/nsqd:4150"]}]}`))
+	assert.Nil(t, err)
+	opts, err := spec.Services[0].options()
+	assert.Nil(t, err)
+	s := &serviceRegistrator{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	assert.Equal(t, "svc", s.name)
+	assert.Equal(t, []string{"v1"}, s.tags)
+	assert.Equal(t, &agentWeights{Passing: 10, Warning: 1}, s.weights)
+	assert.Equal(t, 30*time.Minute, s.deregisterAfter)
+	assert.Equal(t, []alias{{serviceID: "mongo:27017"}, {node: "node01", serviceID: "nsqd:4150"}}, s.aliases)
+
+	_, err = ServiceSpec{Name: "svc"}.options()
+	assert.NotNil(t, err)
+}
+
+// fakeConsul records agent requests and replies with empty 200 response.
+type fakeConsul struct {
+	mu       sync.Mutex
+	requests []string
+	srv      *httptest.Server
+}
+
+func startFakeConsul(t *testing.T) *fakeConsul {
+	fc := &fakeConsul{}
+	fc.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fc.mu.Lock()
+		fc.requests = append(fc.requests, r.Method+" "+r.URL.Path)
+		fc.mu.Unlock()
+	}))
+	c, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(fc.srv.URL, "http://")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	consulAgent, consulRaw = c.Agent, c.Raw
+	return fc
+}
+
+func (fc *fakeConsul) close() {
+	consulAgent, consulRaw = dcy.Agent, dcy.Raw
+	fc.srv.Close()
+}
+
+func (fc *fakeConsul) received(req string) bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, r := range fc.requests {
+		if r == req {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSpecYAML(t *testing.T) {
+	spec, err := ParseSpec([]byte(`
+# registrations
+services:
+  - name: svc
+    port: 80
+    tags: [v1, "canary"]
+    weights:
+      passing: 10
+      warning: 1
+    aliases:
+    - mongo:27017
+    checks:
+      - name: http
+        http: http://localhost:80/health
+        interval: 5s
+  - name: admin
+    port: 81
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(spec.Services))
+	ss := spec.Services[0]
+	assert.Equal(t, "svc", ss.Name)
+	assert.Equal(t, 80, ss.Port)
+	assert.Equal(t, []string{"v1", "canary"}, ss.Tags)
+	assert.Equal(t, 10, ss.Weights.Passing)
+	assert.Equal(t, []string{"mongo:27017"}, ss.Aliases)
+	assert.Equal(t, []CheckSpec{{Name: "http", HTTP: "http://localhost:80/health", Interval: "5s"}}, ss.Checks)
+	assert.Equal(t, "admin", spec.Services[1].Name)
+	assert.Equal(t, 81, spec.Services[1].Port)
+
+	_, err = ParseSpec([]byte("services:\n  - name: svc\n    port: 80\n   tags: [v1]\n"))
+	assert.NotNil(t, err)
+	_, err = ParseSpec([]byte("services: {name: svc}\n"))
+	assert.NotNil(t, err)
+
+	_, err = ServiceSpec{Name: "svc", Port: 80, Checks: []CheckSpec{{Name: "x"}}}.options()
+	assert.NotNil(t, err)
+}
+
+func TestUpdateDeregistersRemovedChecks(t *testing.T) {
+	fc := startFakeConsul(t)
+	defer fc.close()
+	s, err := New(8081, Name("svc"), Alias("", "mongo"), Alias("", "nsqd"),
+		Check(CheckSpec{Name: "http", HTTP: "http://localhost:8081"}))
+	assert.Nil(t, err)
+	defer s.Deregister()
+	assert.Nil(t, s.update(Name("svc"), Alias("", "mongo")))
+	assert.True(t, fc.received("PUT /v1/agent/check/deregister/svc:8081_alias_nsqd"))
+	assert.True(t, fc.received("PUT /v1/agent/check/deregister/svc:8081_check_http"))
+	assert.False(t, fc.received("PUT /v1/agent/check/deregister/svc:8081_alias_mongo"))
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	fc := startFakeConsul(t)
+	defer fc.close()
+	s, err := New(8082, Name("svc"), HealthPollInterval(time.Millisecond))
+	assert.Nil(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.Nil(t, s.update(Name("svc"), Tags(fmt.Sprintf("v%d", i))))
+			assert.Nil(t, s.SetWeights(i+1, 1))
+			Registrations()
+			s.Warn()
+		}(i)
+	}
+	wg.Wait()
+	s.Deregister()
+	assert.Equal(t, ErrClosed, s.update(Name("svc"), Tags("v1")))
+	assert.Equal(t, ErrClosed, s.SetWeights(1, 1))
+	s.Deregister()
+}
+
+func TestDefaultHealthPassing(t *testing.T) {
+	fc := startFakeConsul(t)
+	defer fc.close()
+	s, err := New(8084, Name("svc"))
+	assert.Nil(t, err)
+	defer s.Deregister()
+	// status is sent again after re-registration, before SetWeights returns
+	assert.Nil(t, s.SetWeights(10, 1))
+	assert.True(t, fc.received("PUT /v1/agent/check/pass/svc:8084_ttl_check"))
+
+	health.AddReadiness("sr.test", func() error { return errors.New("down") })
+	defer health.RemoveReadiness("sr.test")
+	s, err = New(8085, Name("svc"), Readiness())
+	assert.Nil(t, err)
+	defer s.Deregister()
+	assert.Nil(t, s.SetWeights(10, 1))
+	assert.False(t, fc.received("PUT /v1/agent/check/pass/svc:8085_ttl_check"))
+}
+
+func TestSetStatusAfterClose(t *testing.T) {
+	s := &serviceRegistrator{setStatus: make(chan checkStatus), closed: make(chan struct{})}
+	close(s.closed)
+	done := make(chan struct{})
+	go func() {
+		s.SetStatus(health.Warn, "note")
+		s.Passing()
+		s.Warn()
+		s.Fail()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SetStatus blocked after close")
+	}
+}
+
+func TestSetWeightsKeepsStatus(t *testing.T) {
+	fc := startFakeConsul(t)
+	defer fc.close()
+	s, err := New(8083, Name("svc"), HealthCheck(nil))
+	assert.Nil(t, err)
+	defer s.Deregister()
+	s.Warn()
+	fc.mu.Lock()
+	fc.requests = nil
+	fc.mu.Unlock()
+	assert.Nil(t, s.SetWeights(10, 1))
+	assert.True(t, fc.received("PUT /v1/agent/service/register"))
+	assert.True(t, fc.received("PUT /v1/agent/check/warn/svc:8083_ttl_check"))
+}
+
+type grpcHealthServer struct {
+	mu       sync.Mutex
+	statuses map[string]int32
+}
+
+func (g *grpcHealthServer) SetServingStatus(service string, status int32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.statuses[service] = status
+}
+
+func TestGRPCHealthStartsAfterRegistration(t *testing.T) {
+	fc := startFakeConsul(t)
+	defer fc.close()
+	srv := &grpcHealthServer{statuses: map[string]int32{}}
+	opt := GRPCHealth[int32](srv, "svc")
+	opt(&serviceRegistrator{})
+	assert.Equal(t, 0, len(srv.statuses))
+
+	s, err := New(8084, Name("svc"), opt)
+	assert.Nil(t, err)
+	assert.True(t, fc.received("PUT /v1/agent/check/register"))
+	srv.mu.Lock()
+	_, ok := srv.statuses["svc"]
+	srv.mu.Unlock()
+	assert.True(t, ok)
+	assert.NotNil(t, s.stopGRPC)
+	s.Deregister()
+}
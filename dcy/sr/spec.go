@@ -0,0 +1,156 @@
+package sr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/internal/yaml"
+)
+
+// Spec is declarative description of services registered by the process.
+// Can be loaded from json or yaml (see ParseSpec) or built in Go.
+type Spec struct {
+	Services []ServiceSpec `json:"services"`
+}
+
+// ServiceSpec describes one service registration.
+type ServiceSpec struct {
+	Name string   `json:"name"`
+	Port int      `json:"port"`
+	Tags []string `json:"tags,omitempty"`
+	// Weights when passing and warning, defaults to consul defaults
+	Weights *struct {
+		Passing int `json:"passing"`
+		Warning int `json:"warning"`
+	} `json:"weights,omitempty"`
+	// DeregisterCriticalAfter in time.Duration format (e.g. "30m")
+	DeregisterCriticalAfter string `json:"deregister_critical_after,omitempty"`
+	// Aliases are service ids of hard dependencies, in node/service format
+	// or just service for services on the local agent
+	Aliases []string `json:"aliases,omitempty"`
+	// Checks are additional http, tcp or grpc checks
+	Checks []CheckSpec `json:"checks,omitempty"`
+}
+
+var (
+	appliedMu sync.Mutex
+	applied   = map[string]*serviceRegistrator{}
+)
+
+// ParseSpec parses json or yaml registration spec.
+// Yaml is converted to json, supported are block maps and lists,
+// inline lists of scalars and scalars.
+//
+//	services:
+//	  - name: api
+//	    port: 8080
+//	    tags: [v1]
+//	    checks:
+//	      - name: http
+//	        http: http://localhost:8080/health
+func ParseSpec(buf []byte) (Spec, error) {
+	var spec Spec
+	if !bytes.HasPrefix(bytes.TrimSpace(buf), []byte("{")) {
+		v, err := yaml.Parse(buf)
+		if err != nil {
+			return spec, err
+		}
+		if buf, err = json.Marshal(v); err != nil {
+			return spec, err
+		}
+	}
+	err := json.Unmarshal(buf, &spec)
+	return spec, err
+}
+
+// Apply reconciles registrations to the spec.
+// New services are registered, changed are re-registered,
+// and services registered by previous Apply which are not in the spec are deregistered.
+func Apply(spec Spec) error {
+	appliedMu.Lock()
+	defer appliedMu.Unlock()
+
+	ids := map[string]bool{}
+	for _, ss := range spec.Services {
+		opts, err := ss.options()
+		if err != nil {
+			return err
+		}
+		id := fmt.Sprintf("%s:%d", ss.Name, ss.Port)
+		ids[id] = true
+		if s, ok := applied[id]; ok {
+			if err := s.update(opts...); err != nil {
+				return err
+			}
+			continue
+		}
+		s, err := New(ss.Port, opts...)
+		if err != nil {
+			return err
+		}
+		applied[id] = s
+	}
+	for id, s := range applied {
+		if !ids[id] {
+			s.Deregister()
+			delete(applied, id)
+		}
+	}
+	return nil
+}
+
+func (ss ServiceSpec) options() ([]func(*serviceRegistrator), error) {
+	if ss.Name == "" || ss.Port == 0 {
+		return nil, fmt.Errorf("service name and port are required")
+	}
+	opts := []func(*serviceRegistrator){Name(ss.Name), Tags(ss.Tags...)}
+	if ss.Weights != nil {
+		opts = append(opts, Weights(ss.Weights.Passing, ss.Weights.Warning))
+	}
+	if ss.DeregisterCriticalAfter != "" {
+		d, err := time.ParseDuration(ss.DeregisterCriticalAfter)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, DeregisterCriticalAfter(d))
+	}
+	for _, a := range ss.Aliases {
+		node, svc := "", a
+		if i := strings.Index(a, "/"); i >= 0 {
+			node, svc = a[:i], a[i+1:]
+		}
+		opts = append(opts, Alias(node, svc))
+	}
+	for _, c := range ss.Checks {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, Check(c))
+	}
+	return opts, nil
+}
+
+// update applies options to the existing registration,
+// and re-registers service if anything is changed.
+func (s *serviceRegistrator) update(opts ...func(*serviceRegistrator)) error {
+	n := &serviceRegistrator{}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return s.reconfigure(func(s *serviceRegistrator) bool {
+		if reflect.DeepEqual(s.tags, n.tags) &&
+			reflect.DeepEqual(s.weights, n.weights) &&
+			reflect.DeepEqual(s.aliases, n.aliases) &&
+			reflect.DeepEqual(s.checks, n.checks) &&
+			s.deregisterAfter == n.deregisterAfter {
+			return false
+		}
+		s.tags, s.weights, s.aliases, s.checks, s.deregisterAfter = n.tags, n.weights, n.aliases, n.checks, n.deregisterAfter
+		return true
+	})
+}
@@ -0,0 +1,73 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set with ldflags:
+//
+//	go build -ldflags "-X github.com/minus5/svckit/env.version=1.2.3 \
+//		-X github.com/minus5/svckit/env.commit=$(git rev-parse HEAD) \
+//		-X github.com/minus5/svckit/env.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without ldflags commit and build time are from vcs info embedded by go build.
+var (
+	commit    string
+	buildTime string
+)
+
+// BuildInfo is build metadata of the application.
+type BuildInfo struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Commit returns git commit of the build.
+func Commit() string {
+	if commit != "" {
+		return commit
+	}
+	return vcs("vcs.revision")
+}
+
+// BuildTime returns build time (or commit time from vcs info).
+func BuildTime() string {
+	if buildTime != "" {
+		return buildTime
+	}
+	return vcs("vcs.time")
+}
+
+func vcs(key string) string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range bi.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// Build returns build metadata.
+func Build() BuildInfo {
+	return BuildInfo{
+		Version:   Version(),
+		Commit:    Commit(),
+		BuildTime: BuildTime(),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// VersionHandler exposes build metadata as json (on /version of httpi).
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Build())
+}
@@ -6,9 +6,14 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 )
 
+// EnvVersion is environment variable with application version.
+const EnvVersion = "SVCKIT_VERSION"
+
 var (
+	version  string
 	dc       string
 	nodeName string
 	appName  string
@@ -46,6 +51,18 @@ func NodeName() string {
 	return hostname
 }
 
+// Version returns application version, set by SetVersion, ldflags (see Build) or from EnvVersion.
+func Version() string {
+	if version != "" {
+		return version
+	}
+	return os.Getenv(EnvVersion)
+}
+
+func SetVersion(v string) {
+	version = v
+}
+
 func SetAppName(name string) {
 	appName = name
 }
@@ -61,6 +78,88 @@ func SetNodeName(name string) {
 	nodeName = name
 }
 
+// EnvName is environment variable with environment name, dev (or development, local) marks developer machine.
+// When set it overrides environment from Consul (see SetEnvironment).
+const EnvName = "SVCKIT_ENV"
+
+// Environment classes.
+const (
+	Prod    = "prod"
+	Staging = "staging"
+	Dev     = "dev"
+)
+
+var (
+	envMu         sync.RWMutex
+	environment   string
+	envSubscribed []func(string)
+)
+
+// Environment returns environment class (Prod, Staging, Dev) from EnvName,
+// or as set by SetEnvironment (dcy sets it from Consul node meta or KV).
+// Empty when unknown.
+func Environment() string {
+	if e := normalizeEnvironment(os.Getenv(EnvName)); e != "" {
+		return e
+	}
+	envMu.RLock()
+	defer envMu.RUnlock()
+	return environment
+}
+
+// normalizeEnvironment maps environment name to class, unknown name is returned lowercased.
+func normalizeEnvironment(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "prod", "production", "live":
+		return Prod
+	case "staging", "stage", "stg":
+		return Staging
+	case "dev", "development", "local":
+		return Dev
+	}
+	return name
+}
+
+// SetEnvironment sets environment name and notifies OnEnvironment subscribers.
+func SetEnvironment(name string) {
+	envMu.Lock()
+	old := environment
+	environment = normalizeEnvironment(name)
+	subs := envSubscribed
+	envMu.Unlock()
+	if old == environment {
+		return
+	}
+	e := Environment()
+	for _, f := range subs {
+		f(e)
+	}
+}
+
+// OnEnvironment registers function called when environment is set by SetEnvironment.
+// Used to adjust defaults (log level, sampling) to environment.
+func OnEnvironment(f func(string)) {
+	envMu.Lock()
+	defer envMu.Unlock()
+	envSubscribed = append(envSubscribed, f)
+}
+
+// InProd returns true in production environment.
+func InProd() bool {
+	return Environment() == Prod
+}
+
+// InStaging returns true in staging environment.
+func InStaging() bool {
+	return Environment() == Staging
+}
+
+// InDev returns true when running on developer machine.
+func InDev() bool {
+	return Environment() == Dev
+}
+
 // Hack to know that I'm in running in tests http://stackoverflow.com/a/36666114
 func InTest() bool {
 	return flag.Lookup("test.v") != nil
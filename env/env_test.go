@@ -0,0 +1,41 @@
+package env
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	SetVersion("1.2.3")
+	commit, buildTime = "abc", "2026-01-02T03:04:05Z"
+	defer func() { SetVersion(""); commit, buildTime = "", "" }()
+
+	w := httptest.NewRecorder()
+	VersionHandler(w, httptest.NewRequest("GET", "/version", nil))
+	var b BuildInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+	assert.Equal(t, BuildInfo{Version: "1.2.3", Commit: "abc", BuildTime: "2026-01-02T03:04:05Z", GoVersion: runtime.Version()}, b)
+}
+
+func TestEnvironment(t *testing.T) {
+	var changes []string
+	OnEnvironment(func(e string) { changes = append(changes, e) })
+	defer SetEnvironment("")
+
+	SetEnvironment("Production")
+	assert.True(t, InProd())
+	assert.False(t, InDev())
+	SetEnvironment("prod")
+	SetEnvironment("stage")
+	assert.True(t, InStaging())
+
+	os.Setenv(EnvName, "local")
+	defer os.Unsetenv(EnvName)
+	assert.True(t, InDev())
+	assert.Equal(t, []string{Prod, Staging}, changes)
+}
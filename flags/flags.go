@@ -0,0 +1,233 @@
+// Package flags is feature flag subsystem backed by Consul KV.
+//
+// Each flag is key under Prefix (env/<app>/flags/<name>) with value true, false
+// or json rule:
+//
+//	{"enabled": true, "dcs": ["s1"], "nodes": ["node01"], "percent": 25}
+//
+// Flag is on when it is enabled and instance dc is in dcs (if listed) and
+// instance falls into percentage rollout. Nodes listed in nodes have flag always on.
+// Percentage rollout is stable, hash of flag name and node (or key in BoolFor).
+// Missing flag is off.
+//
+//	if flags.Bool("new-checkout") {
+//		...
+//	}
+//
+// Flags are watched in Consul and changes are applied live.
+// Current states are listed by Handler (e.g. mount on /debug/flags).
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minus5/svckit/config"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// Prefix returns KV prefix of application feature flags (env/<app>/flags).
+func Prefix() string {
+	return fmt.Sprintf("env/%s/flags", env.AppName())
+}
+
+// Rule is feature flag rollout rule.
+type Rule struct {
+	Enabled bool     `json:"enabled"`
+	Dcs     []string `json:"dcs,omitempty"`
+	Nodes   []string `json:"nodes,omitempty"`
+	// Percent of instances (or keys) with flag on, 0-100. Default is 100.
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// ParseRule parses flag value, true/false or json rule.
+func ParseRule(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	if b, err := strconv.ParseBool(s); err == nil {
+		return Rule{Enabled: b}, nil
+	}
+	var r Rule
+	if err := json.Unmarshal([]byte(s), &r); err != nil {
+		return r, fmt.Errorf("invalid flag rule %q: %w", s, err)
+	}
+	return r, nil
+}
+
+// Eval returns flag state for dc, node and rollout key.
+func (r Rule) Eval(name, dc, node, key string) bool {
+	if !r.Enabled {
+		return false
+	}
+	if contains(r.Nodes, node) {
+		return true
+	}
+	if len(r.Dcs) > 0 && !contains(r.Dcs, dc) {
+		return false
+	}
+	if r.Percent == nil {
+		return true
+	}
+	return bucket(name, key) < *r.Percent
+}
+
+func contains(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket returns stable value in [0, 100) for name and key.
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name + "/" + key))
+	return float64(h.Sum32()%10000) / 100
+}
+
+// Set is set of feature flags from one source.
+type Set struct {
+	dc, node string
+	mu       sync.RWMutex
+	rules    map[string]Rule
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSet creates flags set for instance in dc on node, with rules read from src.
+// Watchable src is watched for changes.
+func NewSet(src config.Source, dc, node string) *Set {
+	s := &Set{dc: dc, node: node, rules: map[string]Rule{}, stop: make(chan struct{})}
+	if w, ok := src.(config.Watchable); ok {
+		values, _, err := w.Wait(0)
+		if err != nil {
+			logger().Error(err)
+		}
+		s.update(values)
+		go config.WatchSource(w, values, s.stop, s.update)
+		return s
+	}
+	values, err := src.Values()
+	if err != nil {
+		logger().Error(err)
+	}
+	s.update(values)
+	return s
+}
+
+// update replaces rules, invalid rules are logged and flag is off.
+func (s *Set) update(values map[string]string) {
+	rules := make(map[string]Rule, len(values))
+	for name, v := range values {
+		r, err := ParseRule(v)
+		if err != nil {
+			logger().S("flag", name).Error(err)
+			continue
+		}
+		rules[name] = r
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, r := range rules {
+		if old, ok := s.rules[name]; !ok || !reflect.DeepEqual(old, r) {
+			logger().S("flag", name).J("rule", mustJSON(r)).Info("flag changed")
+		}
+	}
+	s.rules = rules
+}
+
+// Close stops watching, it is safe to call it more than once.
+func (s *Set) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// Bool returns state of flag for this instance.
+func (s *Set) Bool(name string) bool {
+	return s.BoolFor(name, s.node)
+}
+
+// BoolFor returns state of flag for rollout key (e.g. user id),
+// percentage rollout is by key instead of node.
+func (s *Set) BoolFor(name, key string) bool {
+	s.mu.RLock()
+	r, ok := s.rules[name]
+	s.mu.RUnlock()
+	return ok && r.Eval(name, s.dc, s.node, key)
+}
+
+// State is flag state listed by Handler.
+type State struct {
+	Name string `json:"name"`
+	On   bool   `json:"on"`
+	Rule Rule   `json:"rule"`
+}
+
+// States returns all flags sorted by name.
+func (s *Set) States() []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	states := make([]State, 0, len(s.rules))
+	for name, r := range s.rules {
+		states = append(states, State{Name: name, On: r.Eval(name, s.dc, s.node, s.node), Rule: r})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Handler lists flag states as json.
+func (s *Set) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(mustJSON(s.States()))
+	}
+}
+
+var (
+	defaultOnce sync.Once
+	defaultSet  *Set
+)
+
+// Default returns set of application flags from Consul KV (under Prefix), created on first use.
+func Default() *Set {
+	defaultOnce.Do(func() {
+		defaultSet = NewSet(config.KV(Prefix()), env.Dc(), env.NodeName())
+	})
+	return defaultSet
+}
+
+// Bool returns state of application flag for this instance.
+func Bool(name string) bool {
+	return Default().Bool(name)
+}
+
+// BoolFor returns state of application flag for rollout key.
+func BoolFor(name, key string) bool {
+	return Default().BoolFor(name, key)
+}
+
+// Handler lists application flags as json.
+//
+//	httpi.Handle("/debug/flags", flags.Handler())
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Default().Handler()(w, r)
+	}
+}
+
+func mustJSON(v interface{}) []byte {
+	buf, _ := json.Marshal(v)
+	return buf
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.flags")
+}
@@ -0,0 +1,79 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minus5/svckit/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	s := NewSet(config.Map{
+		"on":       "true",
+		"off":      "false",
+		"dc":       `{"enabled": true, "dcs": ["s1"]}`,
+		"other-dc": `{"enabled": true, "dcs": ["s2"], "nodes": ["node02"]}`,
+		"node":     `{"enabled": true, "dcs": ["s2"], "nodes": ["node01"]}`,
+		"disabled": `{"enabled": false, "nodes": ["node01"]}`,
+		"none":     `{"enabled": true, "percent": 0}`,
+		"half":     `{"enabled": true, "percent": 50}`,
+		"invalid":  `{enabled`,
+	}, "s1", "node01")
+
+	assert.True(t, s.Bool("on"))
+	assert.False(t, s.Bool("off"))
+	assert.True(t, s.Bool("dc"))
+	assert.False(t, s.Bool("other-dc"))
+	assert.True(t, s.Bool("node"))
+	assert.False(t, s.Bool("disabled"))
+	assert.False(t, s.Bool("none"))
+	assert.False(t, s.Bool("invalid"))
+	assert.False(t, s.Bool("missing"))
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		if s.BoolFor("half", fmt.Sprint(i)) {
+			on++
+		}
+	}
+	assert.InDelta(t, 500, on, 60)
+	assert.Equal(t, s.BoolFor("half", "42"), s.BoolFor("half", "42"))
+
+	w := httptest.NewRecorder()
+	s.Handler()(w, httptest.NewRequest("GET", "/debug/flags", nil))
+	var states []State
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &states))
+	assert.Len(t, states, 8)
+	assert.Equal(t, "dc", states[0].Name)
+	assert.True(t, states[0].On)
+}
+
+type testSource struct {
+	updates chan map[string]string
+}
+
+func (s *testSource) Values() (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (s *testSource) Wait(index uint64) (map[string]string, uint64, error) {
+	if index == 0 {
+		return map[string]string{}, 1, nil
+	}
+	return <-s.updates, index + 1, nil
+}
+
+func TestWatch(t *testing.T) {
+	src := &testSource{updates: make(chan map[string]string)}
+	s := NewSet(src, "s1", "node01")
+	assert.False(t, s.Bool("new-checkout"))
+	src.updates <- map[string]string{"new-checkout": "true"}
+	// second update is received after the first one is applied
+	src.updates <- map[string]string{"new-checkout": "true"}
+	assert.True(t, s.Bool("new-checkout"))
+	s.Close()
+	s.Close()
+}
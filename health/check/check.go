@@ -0,0 +1,171 @@
+// Package check contains ready-made health probes for common dependencies.
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/health/check"
+//	health.AddReadiness("mongo", check.Mongo())
+//	health.AddReadiness("nsq", check.NSQ())
+//	health.AddReadiness("consul", check.Consul())
+//	health.AddReadiness("backend", check.HTTP("http://backend/ping"))
+//	health.AddReadiness("redis", check.TCP("127.0.0.1:6379"))
+//	health.AddReadiness("disk", check.Disk("/var/lib/my-app", 10))
+//	health.AddReadiness("goroutines", check.Goroutines(10000))
+//
+// Every probe is limited by Timeout.
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/nsq"
+)
+
+// Timeout is default timeout of the probes.
+var Timeout = 2 * time.Second
+
+// ErrTimeout is returned when probe is not finished in time.
+var ErrTimeout = errors.New("check timeout")
+
+// WithTimeout limits probe duration to d.
+// Probe keeps running in background after timeout.
+func WithTimeout(d time.Duration, probe func() error) func() error {
+	return func() error {
+		done := make(chan error, 1)
+		go func() { done <- probe() }()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(d):
+			return ErrTimeout
+		}
+	}
+}
+
+// TCP checks that tcp connection to addr (host:port) can be established.
+func TCP(addr string) func() error {
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+		defer cancel()
+		return dial(ctx, addr)
+	}
+}
+
+func dial(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTP checks that GET url returns 2xx status.
+func HTTP(url string) func() error {
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+		defer cancel()
+		return get(ctx, url)
+	}
+}
+
+func get(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(rsp.Body, 64*1024))
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s status %s", url, rsp.Status)
+	}
+	return nil
+}
+
+// Service checks that at least one instance of the service discovered in Consul accepts tcp connections.
+func Service(name string) func() error {
+	return service(name, dial)
+}
+
+// Mongo checks that at least one mongo instance discovered in Consul responds to ping command.
+func Mongo() func() error {
+	return service("mongo", mongoPing)
+}
+
+func service(name string, check func(context.Context, string) error) func() error {
+	return func() error {
+		addrs, err := dcy.Services(name)
+		if err != nil {
+			return err
+		}
+		return anyPasses(addrs.String(), check)
+	}
+}
+
+// NSQ checks that at least one nsqlookupd discovered in Consul responds to /ping.
+func NSQ() func() error {
+	return func() error {
+		addrs, err := dcy.Services(nsq.LookupdHTTPServiceName)
+		if err != nil {
+			return err
+		}
+		return anyPasses(addrs.String(), func(ctx context.Context, addr string) error {
+			return get(ctx, "http://"+addr+"/ping")
+		})
+	}
+}
+
+// Consul checks that Consul agent is reachable and that cluster has leader.
+func Consul() func() error {
+	return WithTimeout(Timeout, dcy.Ping)
+}
+
+// Pinger is implemented by database clients (e.g. mgo.Session).
+type Pinger interface {
+	Ping() error
+}
+
+// Ping checks dependency using its Ping method.
+func Ping(p Pinger) func() error {
+	return WithTimeout(Timeout, p.Ping)
+}
+
+// anyPasses returns nil if check passes for any of addrs, or all errors.
+// Addrs are checked in parallel, all within Timeout.
+func anyPasses(addrs []string, check func(context.Context, string) error) error {
+	if len(addrs) == 0 {
+		return errors.New("no instances")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	type result struct {
+		i   int
+		err error
+	}
+	results := make(chan result, len(addrs))
+	for i, a := range addrs {
+		go func(i int, a string) {
+			results <- result{i, check(ctx, a)}
+		}(i, a)
+	}
+	errs := make([]string, len(addrs))
+	for range addrs {
+		r := <-results
+		if r.err == nil {
+			return nil
+		}
+		errs[r.i] = r.err.Error()
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
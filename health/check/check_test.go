@@ -0,0 +1,81 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	assert.NoError(t, TCP(addr)())
+	ln.Close()
+	assert.Error(t, TCP(addr)())
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ping" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	assert.NoError(t, HTTP(srv.URL+"/ping")())
+	assert.Error(t, HTTP(srv.URL+"/other")())
+}
+
+func TestWithTimeout(t *testing.T) {
+	slow := WithTimeout(10*time.Millisecond, func() error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+	assert.Equal(t, ErrTimeout, slow())
+	failing := WithTimeout(time.Second, func() error { return errors.New("down") })
+	assert.EqualError(t, failing(), "down")
+}
+
+func TestAnyPasses(t *testing.T) {
+	fail := func(_ context.Context, a string) error {
+		if a == "ok" {
+			return nil
+		}
+		return errors.New(a + " down")
+	}
+	assert.NoError(t, anyPasses([]string{"a", "ok"}, fail))
+	assert.EqualError(t, anyPasses([]string{"a", "b"}, fail), "a down; b down")
+	assert.Error(t, anyPasses(nil, fail))
+}
+
+func TestResources(t *testing.T) {
+	assert.NoError(t, Disk("/", 0)())
+	assert.Error(t, Disk("/", 101)())
+	assert.Error(t, Disk("/not/existing", 0)())
+
+	assert.NoError(t, Memory(1<<40)())
+	assert.Error(t, Memory(1)())
+
+	assert.NoError(t, Goroutines(1e6)())
+	assert.Error(t, Goroutines(1)())
+}
+
+func TestAnyPassesParallel(t *testing.T) {
+	defer func(d time.Duration) { Timeout = d }(Timeout)
+	Timeout = 50 * time.Millisecond
+	hang := func(ctx context.Context, a string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	start := time.Now()
+	assert.Error(t, anyPasses([]string{"a", "b", "c"}, hang))
+	assert.True(t, time.Since(start) < 2*Timeout)
+}
@@ -0,0 +1,160 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+)
+
+// opMsg is mongo wire protocol OP_MSG opcode (mongo 3.6+).
+const opMsg = 2013
+
+// mongoPing sends {ping: 1} command to mongo at addr and checks that reply is ok.
+// Without driver dependency, only what ping needs of wire protocol and bson is implemented.
+func mongoPing(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(mongoPingMsg()); err != nil {
+		return err
+	}
+	doc, err := readMongoReply(conn)
+	if err != nil {
+		return err
+	}
+	return mongoOk(doc)
+}
+
+// mongoPingMsg returns OP_MSG with {ping: 1, $db: "admin"} command.
+func mongoPingMsg() []byte {
+	var doc bytes.Buffer
+	doc.Write([]byte{0x10})
+	doc.WriteString("ping\x00")
+	binary.Write(&doc, binary.LittleEndian, int32(1))
+	doc.Write([]byte{0x02})
+	doc.WriteString("$db\x00")
+	binary.Write(&doc, binary.LittleEndian, int32(len("admin")+1))
+	doc.WriteString("admin\x00")
+	doc.WriteByte(0)
+
+	var msg bytes.Buffer
+	// header: length, request id, response to, opcode
+	binary.Write(&msg, binary.LittleEndian, []int32{int32(16 + 4 + 1 + 4 + doc.Len()), 1, 0, opMsg})
+	binary.Write(&msg, binary.LittleEndian, uint32(0)) // flags
+	msg.WriteByte(0)                                   // section with body document
+	binary.Write(&msg, binary.LittleEndian, int32(4+doc.Len()))
+	msg.Write(doc.Bytes())
+	return msg.Bytes()
+}
+
+// readMongoReply reads OP_MSG reply and returns its body document.
+func readMongoReply(r io.Reader) ([]byte, error) {
+	var header [4]int32
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header[3] != opMsg {
+		return nil, fmt.Errorf("mongo reply opcode %d", header[3])
+	}
+	if header[0] < 16+4+1+5 || header[0] > 16*1024*1024 {
+		return nil, fmt.Errorf("mongo reply length %d", header[0])
+	}
+	body := make([]byte, header[0]-16)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	// flags, section kind
+	if body[4] != 0 {
+		return nil, fmt.Errorf("mongo reply section kind %d", body[4])
+	}
+	doc := body[5:]
+	n := int(binary.LittleEndian.Uint32(doc))
+	if n < 5 || n > len(doc) {
+		return nil, errors.New("mongo reply invalid document")
+	}
+	return doc[:n], nil
+}
+
+// mongoOk returns nil if command reply document has ok: 1, otherwise errmsg.
+func mongoOk(doc []byte) error {
+	var ok bool
+	errmsg := "not ok"
+	doc = doc[4 : len(doc)-1]
+	for len(doc) > 0 {
+		typ := doc[0]
+		end := bytes.IndexByte(doc[1:], 0)
+		if end < 0 {
+			return errors.New("mongo reply invalid document")
+		}
+		name := string(doc[1 : end+1])
+		doc = doc[end+2:]
+		n, err := bsonSize(typ, doc)
+		if err != nil {
+			return err
+		}
+		v := doc[:n]
+		switch {
+		case name == "ok" && typ == 0x01:
+			ok = math.Float64frombits(binary.LittleEndian.Uint64(v)) == 1
+		case name == "ok" && typ == 0x10:
+			ok = binary.LittleEndian.Uint32(v) == 1
+		case name == "ok" && typ == 0x12:
+			ok = binary.LittleEndian.Uint64(v) == 1
+		case name == "errmsg" && typ == 0x02 && n > 4:
+			errmsg = string(v[4 : len(v)-1])
+		}
+		doc = doc[n:]
+	}
+	if !ok {
+		return fmt.Errorf("mongo ping: %s", errmsg)
+	}
+	return nil
+}
+
+// bsonSize returns size of bson element value of type typ at the start of v.
+func bsonSize(typ byte, v []byte) (int, error) {
+	var n int
+	switch typ {
+	case 0x01, 0x09, 0x11, 0x12: // double, datetime, timestamp, int64
+		n = 8
+	case 0x02, 0x0D, 0x0E: // string, javascript, symbol
+		if len(v) >= 4 {
+			n = 4 + int(binary.LittleEndian.Uint32(v))
+		}
+	case 0x03, 0x04: // document, array
+		if len(v) >= 4 {
+			n = int(binary.LittleEndian.Uint32(v))
+		}
+	case 0x05: // binary
+		if len(v) >= 4 {
+			n = 4 + 1 + int(binary.LittleEndian.Uint32(v))
+		}
+	case 0x07: // object id
+		n = 12
+	case 0x08: // bool
+		n = 1
+	case 0x0A, 0xFF, 0x7F: // null, min key, max key
+		n = 0
+	case 0x10: // int32
+		n = 4
+	case 0x13: // decimal128
+		n = 16
+	default:
+		return 0, fmt.Errorf("mongo reply unsupported bson type %#x", typ)
+	}
+	if n < 0 || n > len(v) {
+		return 0, errors.New("mongo reply invalid document")
+	}
+	return n, nil
+}
@@ -0,0 +1,88 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mongoServer replies to each OP_MSG with reply document.
+func mongoServer(t *testing.T, reply []byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var header [4]int32
+			if err := binary.Read(conn, binary.LittleEndian, &header); err != nil {
+				conn.Close()
+				continue
+			}
+			body := make([]byte, header[0]-16)
+			io.ReadFull(conn, body)
+			assert.Contains(t, string(body), "ping")
+
+			var msg bytes.Buffer
+			binary.Write(&msg, binary.LittleEndian, []int32{int32(16 + 4 + 1 + len(reply)), 2, header[1], opMsg})
+			binary.Write(&msg, binary.LittleEndian, uint32(0))
+			msg.WriteByte(0)
+			msg.Write(reply)
+			conn.Write(msg.Bytes())
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// bsonDoc builds bson document from elements.
+func bsonDoc(elements ...[]byte) []byte {
+	var doc bytes.Buffer
+	for _, e := range elements {
+		doc.Write(e)
+	}
+	doc.WriteByte(0)
+	out := make([]byte, 4, 4+doc.Len())
+	binary.LittleEndian.PutUint32(out, uint32(4+doc.Len()))
+	return append(out, doc.Bytes()...)
+}
+
+func bsonDouble(name string, v float64) []byte {
+	e := append([]byte{0x01}, name+"\x00"...)
+	return binary.LittleEndian.AppendUint64(e, math.Float64bits(v))
+}
+
+func bsonString(name, v string) []byte {
+	e := append([]byte{0x02}, name+"\x00"...)
+	e = binary.LittleEndian.AppendUint32(e, uint32(len(v)+1))
+	return append(e, v+"\x00"...)
+}
+
+func TestMongoPing(t *testing.T) {
+	nested := append([]byte{0x03}, "topologyVersion\x00"...)
+	nested = append(nested, bsonDoc(bsonDouble("counter", 1))...)
+	addr := mongoServer(t, bsonDoc(bsonString("version", "6.0"), nested, bsonDouble("ok", 1)))
+	assert.NoError(t, mongoPing(context.Background(), addr))
+
+	addr = mongoServer(t, bsonDoc(bsonDouble("ok", 0), bsonString("errmsg", "unauthorized")))
+	assert.EqualError(t, mongoPing(context.Background(), addr), "mongo ping: unauthorized")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr = ln.Addr().String()
+	ln.Close()
+	assert.Error(t, mongoPing(context.Background(), addr))
+}
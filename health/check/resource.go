@@ -0,0 +1,97 @@
+package check
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/minus5/svckit/dcy"
+)
+
+// ResourceOption configures resource threshold check.
+type ResourceOption func(*limit)
+
+// LimitFromKV reads check limit from Consul KV key on each run.
+// Given limit is used when key is missing or not a number.
+//
+//	health.AddReadinessEvery("goroutines", time.Minute,
+//		check.Goroutines(10000, check.LimitFromKV("config/my-app/max_goroutines")))
+func LimitFromKV(key string) ResourceOption {
+	return func(l *limit) {
+		l.kvKey = key
+	}
+}
+
+// limit is threshold of the resource check.
+type limit struct {
+	value float64
+	kvKey string
+}
+
+func newLimit(v float64, opts []ResourceOption) *limit {
+	l := &limit{value: v}
+	for _, o := range opts {
+		o(l)
+	}
+	return l
+}
+
+func (l *limit) get() float64 {
+	if l.kvKey == "" {
+		return l.value
+	}
+	buf, err := dcy.KV(l.kvKey)
+	if err != nil {
+		return l.value
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(buf)), 64)
+	if err != nil {
+		return l.value
+	}
+	return v
+}
+
+// Disk checks that at least minFreePercent of the file system containing path is free.
+func Disk(path string, minFreePercent float64, opts ...ResourceOption) func() error {
+	l := newLimit(minFreePercent, opts)
+	return func() error {
+		total, free, err := diskUsage(path)
+		if err != nil {
+			return err
+		}
+		if total == 0 {
+			return nil
+		}
+		pct := float64(free) * 100 / float64(total)
+		if min := l.get(); pct < min {
+			return fmt.Errorf("%s free space %.1f%% below %.1f%%", path, pct, min)
+		}
+		return nil
+	}
+}
+
+// Memory checks that memory obtained by Go runtime from the OS is below maxBytes.
+func Memory(maxBytes uint64, opts ...ResourceOption) func() error {
+	l := newLimit(float64(maxBytes), opts)
+	return func() error {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if max := uint64(l.get()); m.Sys > max {
+			return fmt.Errorf("memory %d bytes above %d", m.Sys, max)
+		}
+		return nil
+	}
+}
+
+// Goroutines checks that number of goroutines is below max.
+func Goroutines(max int, opts ...ResourceOption) func() error {
+	l := newLimit(float64(max), opts)
+	return func() error {
+		n := runtime.NumGoroutine()
+		if max := int(l.get()); n > max {
+			return fmt.Errorf("%d goroutines above %d", n, max)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package check
+
+// diskUsage is not supported, disk check always passes.
+func diskUsage(path string) (uint64, uint64, error) {
+	return 0, 0, nil
+}
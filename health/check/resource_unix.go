@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package check
+
+import "syscall"
+
+// diskUsage returns total and available bytes of the file system containing path.
+func diskUsage(path string) (uint64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return uint64(st.Blocks) * uint64(st.Bsize), uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"crypto/subtle"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/env"
+)
+
+// DashboardHandler returns handler rendering human readable status page
+// with readiness and liveness checks and history of recent results (on /health of httpi).
+// Requests must be authorized by token with "Authorization: Bearer <token>" header
+// or basic auth password (browser asks for it).
+// If token is empty all requests are forbidden.
+func DashboardHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, pass, ok := r.BasicAuth(); ok {
+			auth = pass
+		}
+		if token == "" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="health"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		dashboardTemplate.Execute(w, dashboard{
+			App:      env.AppName(),
+			Node:     env.Hostname(),
+			Time:     time.Now(),
+			Draining: Draining(),
+			Starting: Starting(),
+			Sections: []section{
+				dashboardSection("Readiness", readiness, ReadyReport()),
+				dashboardSection("Liveness", liveness, LiveReport()),
+			},
+		})
+	}
+}
+
+type dashboard struct {
+	App      string
+	Node     string
+	Time     time.Time
+	Draining bool
+	Starting bool
+	Sections []section
+}
+
+type section struct {
+	Title string
+	Report
+	History map[string][]Status
+}
+
+func dashboardSection(title string, r *registry, rpt Report) section {
+	s := section{Title: title, Report: rpt, History: map[string][]Status{}}
+	for _, c := range rpt.Checks {
+		s.History[c.Name] = r.history(c.Name)
+	}
+	return s
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"duration": func(d Duration) string { return time.Duration(d).String() },
+	"ago": func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return time.Since(*t).Truncate(time.Second).String() + " ago"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>{{.App}} health</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #ddd; }
+.passing { color: #2a2; } .warn { color: #e90; } .fail { color: #d22; }
+.spark { font-family: monospace; letter-spacing: -1px; }
+</style>
+</head>
+<body>
+<h2>{{.App}} on {{.Node}}</h2>
+<p>{{.Time.Format "2006-01-02 15:04:05"}}{{if .Starting}}, starting{{end}}{{if .Draining}}, draining{{end}}</p>
+{{range .Sections}}
+<h3>{{.Title}}: <span class="{{.Status}}">{{.Status}}</span> ({{duration .Latency}})</h3>
+<table>
+<tr><th>check</th><th>status</th><th>latency</th><th>last success</th><th>last error</th><th>history</th></tr>
+{{$history := .History}}{{range .Checks}}<tr>
+<td>{{.Name}}{{if .Informational}} (informational){{end}}</td>
+<td class="{{.Status}}">{{.Status}}</td>
+<td>{{duration .Latency}}</td>
+<td>{{ago .LastSuccess}}</td>
+<td>{{if .LastError}}{{.LastError}} ({{ago .LastErrorTime}}){{else}}-{{end}}</td>
+<td class="spark">{{range index $history .Name}}<span class="{{.}}">&#9608;</span>{{end}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>`))
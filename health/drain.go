@@ -0,0 +1,80 @@
+package health
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/signal"
+)
+
+// EnvDrainPeriod sets drain period (e.g. 10s).
+const EnvDrainPeriod = "SVCKIT_DRAIN_PERIOD"
+
+// DrainPeriod is time between marking service not ready and shutdown,
+// for load balancers and Consul to stop sending traffic.
+// Default is 5s, 0 in dev and test environment.
+var DrainPeriod = 5 * time.Second
+
+var (
+	draining   int32
+	drainStart int64
+)
+
+func init() {
+	if env.InDev() || env.InTest() {
+		DrainPeriod = 0
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvDrainPeriod)); err == nil {
+		DrainPeriod = d
+	}
+	// readiness is flipped as first shutdown step, traffic drains while other hooks run;
+	// listeners are closed after the rest of drain period
+	signal.OnShutdown("health readiness", signal.PriorityReadiness, -1, func() {
+		SetDraining(true)
+		logger().S("period", DrainPeriod.String()).Info("draining")
+	})
+	signal.OnShutdown("health drain", signal.PriorityStopAccepting-1, -1, func() {
+		waitDrain(DrainPeriod)
+	})
+}
+
+// Drain marks service as draining and waits period.
+// While draining readiness is warn: /health/ready returns 429 (not ready for Kubernetes)
+// and Consul check (/health_check) is warning.
+// On shutdown it is done by signal.Shutdown, before stop accepting hooks.
+func Drain(period time.Duration) {
+	SetDraining(true)
+	logger().S("period", period.String()).Info("draining")
+	waitDrain(period)
+}
+
+// waitDrain waits until period since drain start passes.
+func waitDrain(period time.Duration) {
+	start := atomic.LoadInt64(&drainStart)
+	if start == 0 {
+		return
+	}
+	if d := period - time.Since(time.Unix(0, start)); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// SetDraining sets drain mode.
+func SetDraining(d bool) {
+	var v int32
+	var start int64
+	if d {
+		v = 1
+		start = time.Now().UnixNano()
+	}
+	if atomic.SwapInt32(&draining, v) != v {
+		atomic.StoreInt64(&drainStart, start)
+	}
+}
+
+// Draining returns true if service is in drain mode.
+func Draining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
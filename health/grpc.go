@@ -0,0 +1,125 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Serving status values of the grpc.health.v1 protocol
+// (grpc_health_v1.HealthCheckResponse_ServingStatus).
+const (
+	grpcServing    = 1
+	grpcNotServing = 2
+)
+
+var (
+	grpcMu       sync.RWMutex
+	grpcServices = map[string][]string{}
+)
+
+// GRPCService registers gRPC service whose serving status depends only on
+// named readiness checks. Without checks service depends on all readiness checks.
+// Registered services are reported by GRPCCheck and ServeGRPC.
+func GRPCService(service string, checks ...string) {
+	grpcMu.Lock()
+	defer grpcMu.Unlock()
+	grpcServices[service] = checks
+}
+
+// GRPCCheck returns grpc.health.v1 serving status of service.
+// Empty service name is the overall server status.
+// Returns false for unknown service (Check should respond with codes.NotFound).
+// Warn is reported as serving, drain mode as not serving.
+//
+// svckit doesn't depend on grpc, so Health server is thin adapter around GRPCCheck:
+//
+//	func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+//		status, ok := health.GRPCCheck(req.Service)
+//		if !ok {
+//			return nil, grpcstatus.Error(codes.NotFound, "unknown service")
+//		}
+//		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_ServingStatus(status)}, nil
+//	}
+//
+// Or use google.golang.org/grpc/health.Server (which implements Watch too) with ServeGRPC.
+func GRPCCheck(service string) (int32, bool) {
+	statuses := grpcStatuses(ReadyReport())
+	s, ok := statuses[service]
+	return s, ok
+}
+
+// grpcStatuses returns serving status of overall server and each registered service.
+func grpcStatuses(r Report) map[string]int32 {
+	byName := make(map[string]Status, len(r.Checks))
+	for _, c := range r.Checks {
+		byName[c.Name] = c.Status
+	}
+	statuses := map[string]int32{"": grpcStatus(r.Status)}
+	grpcMu.RLock()
+	defer grpcMu.RUnlock()
+	for service, checks := range grpcServices {
+		status := r.Status
+		if len(checks) > 0 {
+			status = Passing
+			for _, name := range checks {
+				status.Add(byName[name])
+			}
+		}
+		statuses[service] = grpcStatus(status)
+	}
+	return statuses
+}
+
+func grpcStatus(s Status) int32 {
+	if s == Fail || Draining() {
+		return grpcNotServing
+	}
+	return grpcServing
+}
+
+// GRPCHealthServer is implemented by google.golang.org/grpc/health.Server.
+// S is grpc_health_v1.HealthCheckResponse_ServingStatus.
+type GRPCHealthServer[S ~int32] interface {
+	SetServingStatus(service string, status S)
+}
+
+// ServeGRPC periodically sets serving status of service in the gRPC health server
+// from the aggregated readiness status. Warn is reported as serving.
+// Empty service name is the overall server status.
+// Services registered by GRPCService are set too.
+// Returns function which stops updates.
+//
+// Example:
+//
+//	srv := grpchealth.NewServer()
+//	health.ServeGRPC(srv, "")
+func ServeGRPC[S ~int32](srv GRPCHealthServer[S], service string) func() {
+	stop := make(chan struct{})
+	set := func() {
+		statuses := grpcStatuses(ReadyReport())
+		srv.SetServingStatus(service, S(statuses[""]))
+		names := make([]string, 0, len(statuses))
+		for name := range statuses {
+			if name != "" && name != service {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			srv.SetServingStatus(name, S(statuses[name]))
+		}
+	}
+	set()
+	go func() {
+		for {
+			select {
+			case <-time.After(10 * time.Second):
+				set()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
@@ -86,10 +86,15 @@ func (s Status) String() string {
 	return "fail"
 }
 
-// HttpHandler exposes status to http
+// HttpHandler exposes status to http.
+// In drain mode status is at least warn.
 func HttpHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Application", env.AppName())
-	w.WriteHeader(status.ToHtmlStatus())
+	s := status
+	if Draining() {
+		s.Add(Warn)
+	}
+	w.WriteHeader(s.ToHtmlStatus())
 	w.Write([]byte(note))
 }
 
@@ -125,10 +130,14 @@ func loop() {
 }
 
 func check() {
+	old := status
 	status, note = handler()
 	checkTime = time.Now()
 	sendNotification()
 	sendMetric()
+	if status != old {
+		publish(old, status, string(note))
+	}
 }
 
 func sendNotification() {
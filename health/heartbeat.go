@@ -0,0 +1,47 @@
+package health
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat is health component of long running worker goroutine.
+type Heartbeat struct {
+	name    string
+	timeout time.Duration
+	last    int64
+}
+
+// AddHeartbeat registers readiness probe which fails when worker
+// doesn't call Beat for longer than timeout (e.g. main loop silently stopped).
+//
+//	hb := health.AddHeartbeat("consumer", time.Minute)
+//	defer hb.Stop()
+//	for m := range msgs {
+//		hb.Beat()
+//		...
+//	}
+func AddHeartbeat(name string, timeout time.Duration, opts ...CheckOption) *Heartbeat {
+	h := &Heartbeat{name: name, timeout: timeout}
+	h.Beat()
+	AddReadiness(name, h.check, opts...)
+	return h
+}
+
+// Beat reports that worker is alive.
+func (h *Heartbeat) Beat() {
+	atomic.StoreInt64(&h.last, time.Now().UnixNano())
+}
+
+// Stop removes readiness probe, call it when worker exits normally.
+func (h *Heartbeat) Stop() {
+	RemoveReadiness(h.name)
+}
+
+func (h *Heartbeat) check() error {
+	if d := time.Since(time.Unix(0, atomic.LoadInt64(&h.last))); d > h.timeout {
+		return fmt.Errorf("no heartbeat for %s", d.Truncate(time.Millisecond))
+	}
+	return nil
+}
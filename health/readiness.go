@@ -0,0 +1,428 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/metric"
+)
+
+// registry is set of named probes.
+// Kind (readiness, liveness) is used in metric names and tags.
+type registry struct {
+	kind   string
+	mu     sync.RWMutex
+	probes map[string]*probe
+	state  map[string]*checkState
+	// status of the last run, transitions are published when publish is set
+	status  Status
+	publish func(old, new Status, cause string)
+}
+
+// probe is registered check function.
+// Scheduled probe (interval > 0) is run in background and last result is cached.
+type probe struct {
+	fn            func() error
+	interval      time.Duration
+	informational bool
+	stop          chan struct{}
+	last          *CheckResult
+}
+
+// CheckOption configures registered check.
+type CheckOption func(*probe)
+
+// Critical check failure fails the report. This is default.
+func Critical() CheckOption {
+	return func(p *probe) {
+		p.informational = false
+	}
+}
+
+// Informational check failure is reported as warn on the check,
+// and doesn't change report status (e.g. optional dependency).
+func Informational() CheckOption {
+	return func(p *probe) {
+		p.informational = true
+	}
+}
+
+// checkState is kept between probe runs.
+type checkState struct {
+	lastSuccess   time.Time
+	lastError     string
+	lastErrorTime time.Time
+	history       []Status
+}
+
+// historySize is number of recent results kept for each probe.
+const historySize = 60
+
+func newRegistry(kind string) *registry {
+	return &registry{kind: kind, probes: map[string]*probe{}, state: map[string]*checkState{}}
+}
+
+// add registers probe, with interval > 0 probe is scheduled in background.
+func (r *registry) add(name string, fn func() error, interval time.Duration, opts []CheckOption) {
+	p := &probe{fn: fn, interval: interval}
+	for _, o := range opts {
+		o(p)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.probes[name]; ok && old.stop != nil {
+		close(old.stop)
+	}
+	r.probes[name] = p
+	if interval > 0 {
+		p.stop = make(chan struct{})
+		go r.schedule(name, p)
+	}
+}
+
+func (r *registry) remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.probes[name]; ok && p.stop != nil {
+		close(p.stop)
+	}
+	delete(r.probes, name)
+	delete(r.state, name)
+}
+
+// schedule runs probe every interval until stopped, caching last result.
+func (r *registry) schedule(name string, p *probe) {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		c := r.check(name, p)
+		r.mu.Lock()
+		select {
+		case <-p.stop:
+			r.mu.Unlock()
+			return
+		default:
+		}
+		changed := p.last == nil || p.last.Status != c.Status
+		p.last = &c
+		r.mu.Unlock()
+		if changed && r.publish != nil {
+			// report status could change
+			r.run()
+		}
+		select {
+		case <-t.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// check runs probe and returns its result.
+// Emits health.check.status gauge (0 passing, 1 warn, 2 fail) and
+// health.check.latency timing, tagged with kind and check name.
+func (r *registry) check(name string, p *probe) CheckResult {
+	c := CheckResult{Name: name, Status: Passing}
+	start := time.Now()
+	err := p.fn()
+	c.Latency = Duration(time.Since(start))
+	if err != nil {
+		c.Status = Fail
+		c.Error = err.Error()
+	}
+	r.update(name, &c, start)
+	tags := []metric.Tag{metric.T("kind", r.kind), metric.T("check", name)}
+	metric.Gauge("health.check.status", int(c.Status), tags...)
+	metric.Time("health.check.latency", int(c.Latency), tags...)
+	return c
+}
+
+// result returns cached result for scheduled probe, or runs it.
+// Scheduled probe without result yet is failing (not checked yet).
+func (r *registry) result(name string, p *probe) CheckResult {
+	if p.interval == 0 {
+		return r.check(name, p)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p.last == nil {
+		return CheckResult{Name: name, Status: Fail, Error: "not checked yet"}
+	}
+	return *p.last
+}
+
+// run runs all probes sorted by name.
+// Emits overall status as health.<kind>.status gauge.
+// Scheduled probes are not run, their cached result is used.
+func (r *registry) run() Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.probes))
+	for name := range r.probes {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	rpt := Report{Status: Passing, Time: time.Now(), Checks: []CheckResult{}}
+	for _, name := range names {
+		r.mu.RLock()
+		p, ok := r.probes[name]
+		r.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		c := r.result(name, p)
+		if p.informational {
+			c.Informational = true
+			if c.Status == Fail {
+				c.Status = Warn
+			}
+		} else {
+			rpt.Status.Add(c.Status)
+		}
+		rpt.Checks = append(rpt.Checks, c)
+	}
+	rpt.Latency = Duration(time.Since(rpt.Time))
+	metric.Gauge("health."+r.kind+".status", int(rpt.Status))
+	r.transition(rpt)
+	return rpt
+}
+
+// transition publishes change of the report status since the last run.
+func (r *registry) transition(rpt Report) {
+	if r.publish == nil {
+		return
+	}
+	r.mu.Lock()
+	old := r.status
+	r.status = rpt.Status
+	r.mu.Unlock()
+	if old != rpt.Status {
+		r.publish(old, rpt.Status, string(rpt.Note()))
+	}
+}
+
+// update stores result in probe state and fills last success and error in the result.
+func (r *registry) update(name string, c *CheckResult, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.state[name]
+	if !ok {
+		s = &checkState{}
+		r.state[name] = s
+	}
+	if c.Error == "" {
+		s.lastSuccess = t
+	} else {
+		s.lastError, s.lastErrorTime = c.Error, t
+	}
+	s.history = append(s.history, c.Status)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	if !s.lastSuccess.IsZero() {
+		ls := s.lastSuccess
+		c.LastSuccess = &ls
+	}
+	if s.lastError != "" {
+		let := s.lastErrorTime
+		c.LastError, c.LastErrorTime = s.lastError, &let
+	}
+}
+
+// history returns recent results of the probe, oldest first.
+func (r *registry) history(name string) []Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.state[name]
+	if !ok {
+		return nil
+	}
+	return append([]Status(nil), s.history...)
+}
+
+var (
+	readiness = newRegistry("readiness")
+	liveness  = newRegistry("liveness")
+)
+
+func init() {
+	readiness.publish = publish
+}
+
+// CheckResult is result of one probe.
+// Error is error of this run, LastError of the last failed run.
+type CheckResult struct {
+	Name          string     `json:"name"`
+	Status        Status     `json:"status"`
+	Informational bool       `json:"informational,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	Latency       Duration   `json:"latency"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// Report is aggregated result of all probes.
+// Status is the worst status of all checks.
+type Report struct {
+	Status  Status        `json:"status"`
+	Time    time.Time     `json:"time"`
+	Latency Duration      `json:"latency"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+// Duration is encoded in json as string (e.g. 1.5ms).
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+// Note returns one line for each not passing check.
+func (r Report) Note() []byte {
+	var note bytes.Buffer
+	for _, c := range r.Checks {
+		if c.Status != Passing {
+			note.WriteString(c.Name + ": " + c.Error + "\n")
+		}
+	}
+	return note.Bytes()
+}
+
+// AddReadiness registers readiness probe under name.
+// Readiness means that service is able to serve requests (dependencies are reachable).
+// Service is ready when all registered probes return nil.
+// Failure of informational probe (see Informational option) doesn't change readiness.
+func AddReadiness(name string, probe func() error, opts ...CheckOption) {
+	readiness.add(name, probe, 0, opts)
+}
+
+// AddReadinessEvery registers readiness probe which is run in background every interval.
+// Readiness report uses last result, so slow probe never blocks readiness endpoint.
+// Until first run completes probe is failing.
+func AddReadinessEvery(name string, interval time.Duration, probe func() error, opts ...CheckOption) {
+	readiness.add(name, probe, interval, opts)
+}
+
+// RemoveReadiness removes readiness probe.
+func RemoveReadiness(name string) {
+	readiness.remove(name)
+}
+
+// AddLiveness registers liveness probe under name.
+// Liveness means that process is up and not stuck, failing liveness should restart the process.
+// Don't check dependencies in liveness probes, use readiness for that.
+func AddLiveness(name string, probe func() error, opts ...CheckOption) {
+	liveness.add(name, probe, 0, opts)
+}
+
+// AddLivenessEvery registers liveness probe which is run in background every interval.
+func AddLivenessEvery(name string, interval time.Duration, probe func() error, opts ...CheckOption) {
+	liveness.add(name, probe, interval, opts)
+}
+
+// RemoveLiveness removes liveness probe.
+func RemoveLiveness(name string) {
+	liveness.remove(name)
+}
+
+// Ready runs all readiness probes and returns aggregated status.
+// Passing when all probes pass, Fail otherwise.
+// Note contains one line for each failed probe.
+// Without registered probes service is considered ready.
+func Ready() (Status, []byte) {
+	r := ReadyReport()
+	return r.Status, r.Note()
+}
+
+// ReadyReport runs all readiness probes and returns report with result of each probe.
+// In drain mode report has drain check with warn status.
+// First passing report ends startup phase, during startup failing checks are warn.
+func ReadyReport() Report {
+	r := readiness.run()
+	if r.Status == Passing {
+		StartupDone()
+	}
+	startup(&r)
+	if Draining() {
+		r.Status.Add(Warn)
+		r.Checks = append(r.Checks, CheckResult{Name: "drain", Status: Warn, Error: "draining"})
+	}
+	return r
+}
+
+// Live runs all liveness probes and returns aggregated status.
+// Without registered probes service is considered live.
+func Live() (Status, []byte) {
+	r := LiveReport()
+	return r.Status, r.Note()
+}
+
+// LiveReport runs all liveness probes and returns report with result of each probe.
+// During startup failing checks are warn.
+func LiveReport() Report {
+	r := liveness.run()
+	startup(&r)
+	return r
+}
+
+// LiveHandler exposes liveness report as json (on /health/live of httpi).
+func LiveHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, LiveReport())
+}
+
+// ReadyHandler exposes readiness report as json (on /health/ready of httpi).
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, ReadyReport())
+}
+
+// writeReport writes report with http status usable both for Consul checks and Kubernetes probes:
+// 200 passing, 429 warn (Consul warning), 503 fail.
+func writeReport(w http.ResponseWriter, r Report) {
+	code := r.Status.ToHtmlStatus()
+	if r.Status == Fail {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Application", env.AppName())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(r)
+}
+
+// MarshalJSON encodes status as string.
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes status from string.
+func (s *Status) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	switch str {
+	case "passing":
+		*s = Passing
+	case "warn":
+		*s = Warn
+	default:
+		*s = Fail
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/metric/metrictest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	status, note := Ready()
+	assert.Equal(t, Passing, status)
+	assert.Empty(t, note)
+
+	AddReadiness("mongo", func() error { return nil })
+	AddReadiness("nsq", func() error { return errors.New("no lookupds") })
+	status, note = Ready()
+	assert.Equal(t, Fail, status)
+	assert.Equal(t, "nsq: no lookupds\n", string(note))
+
+	RemoveReadiness("nsq")
+	status, _ = Ready()
+	assert.Equal(t, Passing, status)
+	RemoveReadiness("mongo")
+}
+
+type servingStatus int32
+
+type grpcHealthServer struct {
+	statuses map[string]servingStatus
+}
+
+func (s *grpcHealthServer) SetServingStatus(service string, status servingStatus) {
+	s.statuses[service] = status
+}
+
+func TestServeGRPC(t *testing.T) {
+	srv := &grpcHealthServer{statuses: map[string]servingStatus{}}
+	AddReadiness("mongo", func() error { return errors.New("unreachable") })
+	stop := ServeGRPC(srv, "svc")
+	stop()
+	assert.Equal(t, servingStatus(grpcNotServing), srv.statuses["svc"])
+	RemoveReadiness("mongo")
+}
+
+func TestGRPCCheck(t *testing.T) {
+	AddReadiness("mongo", func() error { return errors.New("unreachable") })
+	AddReadiness("nsq", func() error { return nil })
+	defer RemoveReadiness("mongo")
+	defer RemoveReadiness("nsq")
+	GRPCService("pkg.Events", "nsq")
+	GRPCService("pkg.Store", "mongo")
+
+	s, ok := GRPCCheck("")
+	assert.True(t, ok)
+	assert.Equal(t, int32(grpcNotServing), s)
+	s, _ = GRPCCheck("pkg.Events")
+	assert.Equal(t, int32(grpcServing), s)
+	s, _ = GRPCCheck("pkg.Store")
+	assert.Equal(t, int32(grpcNotServing), s)
+	_, ok = GRPCCheck("pkg.Unknown")
+	assert.False(t, ok)
+
+	srv := &grpcHealthServer{statuses: map[string]servingStatus{}}
+	ServeGRPC(srv, "")()
+	assert.Equal(t, servingStatus(grpcServing), srv.statuses["pkg.Events"])
+	assert.Equal(t, servingStatus(grpcNotServing), srv.statuses["pkg.Store"])
+
+	SetDraining(true)
+	defer SetDraining(false)
+	s, _ = GRPCCheck("pkg.Events")
+	assert.Equal(t, int32(grpcNotServing), s)
+}
+
+func TestHandlers(t *testing.T) {
+	AddLiveness("loop", func() error { return nil })
+	AddReadiness("mongo", func() error { return errors.New("unreachable") })
+	defer RemoveLiveness("loop")
+	defer RemoveReadiness("mongo")
+
+	w := httptest.NewRecorder()
+	LiveHandler(w, httptest.NewRequest("GET", "/health/live", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var r Report
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.Equal(t, Passing, r.Status)
+	assert.Equal(t, "loop", r.Checks[0].Name)
+	assert.NotNil(t, r.Checks[0].LastSuccess)
+
+	w = httptest.NewRecorder()
+	ReadyHandler(w, httptest.NewRequest("GET", "/health/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	r = Report{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+	assert.Equal(t, Fail, r.Status)
+	assert.Equal(t, "unreachable", r.Checks[0].Error)
+}
+
+func TestCheckState(t *testing.T) {
+	var err error
+	AddReadiness("mongo", func() error { return err })
+	defer RemoveReadiness("mongo")
+
+	c := ReadyReport().Checks[0]
+	assert.Equal(t, Passing, c.Status)
+	assert.NotNil(t, c.LastSuccess)
+	assert.Nil(t, c.LastErrorTime)
+
+	err = errors.New("unreachable")
+	c = ReadyReport().Checks[0]
+	assert.Equal(t, Fail, c.Status)
+	assert.Equal(t, "unreachable", c.Error)
+	assert.Equal(t, "unreachable", c.LastError)
+	assert.NotNil(t, c.LastSuccess)
+
+	err = nil
+	c = ReadyReport().Checks[0]
+	assert.Equal(t, Passing, c.Status)
+	assert.Empty(t, c.Error)
+	assert.Equal(t, "unreachable", c.LastError)
+	assert.True(t, c.LastSuccess.After(*c.LastErrorTime))
+}
+
+func TestDrain(t *testing.T) {
+	defer SetDraining(false)
+	Drain(0)
+	status, note := Ready()
+	assert.Equal(t, Warn, status)
+	assert.Equal(t, "drain: draining\n", string(note))
+
+	w := httptest.NewRecorder()
+	ReadyHandler(w, httptest.NewRequest("GET", "/health/ready", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+}
+
+func TestDrainPeriod(t *testing.T) {
+	defer SetDraining(false)
+	SetDraining(true)
+	w := httptest.NewRecorder()
+	HttpHandler(w, httptest.NewRequest("GET", "/health_check", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// drain period is counted from readiness flip
+	time.Sleep(30 * time.Millisecond)
+	start := time.Now()
+	waitDrain(50 * time.Millisecond)
+	d := time.Since(start)
+	assert.True(t, d < 40*time.Millisecond, d)
+
+	SetDraining(false)
+	start = time.Now()
+	waitDrain(time.Second)
+	assert.True(t, time.Since(start) < 10*time.Millisecond)
+}
+
+func TestScheduled(t *testing.T) {
+	release := make(chan struct{})
+	AddReadinessEvery("slow", time.Hour, func() error {
+		<-release
+		return errors.New("unreachable")
+	})
+	defer RemoveReadiness("slow")
+
+	r := ReadyReport()
+	assert.Equal(t, Fail, r.Status)
+	assert.Equal(t, "not checked yet", r.Checks[0].Error)
+
+	close(release)
+	for i := 0; i < 100; i++ {
+		if r = ReadyReport(); r.Checks[0].Error != "not checked yet" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, "unreachable", r.Checks[0].Error)
+	assert.NotNil(t, r.Checks[0].LastErrorTime)
+}
+
+func TestStartup(t *testing.T) {
+	atomic.StoreInt32(&started, 0)
+	SetStartupWindow(time.Hour)
+	defer SetStartupWindow(0)
+	defer StartupDone()
+
+	fail := errors.New("loading")
+	AddReadiness("cache", func() error { return fail })
+	defer RemoveReadiness("cache")
+
+	r := ReadyReport()
+	assert.Equal(t, Warn, r.Status)
+	assert.Equal(t, Warn, r.Checks[0].Status)
+	assert.Equal(t, "startup", r.Checks[1].Name)
+	assert.Equal(t, Fail, StartupReport().Status)
+
+	fail = nil
+	assert.Equal(t, Passing, ReadyReport().Status)
+	assert.False(t, Starting())
+	assert.Equal(t, Passing, StartupReport().Status)
+
+	fail = errors.New("unreachable")
+	assert.Equal(t, Fail, ReadyReport().Status)
+}
+
+func TestInformational(t *testing.T) {
+	AddReadiness("cache", func() error { return errors.New("unreachable") }, Informational())
+	defer RemoveReadiness("cache")
+
+	r := ReadyReport()
+	assert.Equal(t, Passing, r.Status)
+	assert.Equal(t, Warn, r.Checks[0].Status)
+	assert.True(t, r.Checks[0].Informational)
+
+	AddReadiness("cache", func() error { return errors.New("unreachable") }, Critical())
+	assert.Equal(t, Fail, ReadyReport().Status)
+}
+
+func TestDashboard(t *testing.T) {
+	AddReadiness("mongo", func() error { return errors.New("unreachable") })
+	defer RemoveReadiness("mongo")
+	h := DashboardHandler("secret")
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	ReadyReport()
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.SetBasicAuth("admin", "secret")
+	h(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<td>mongo</td>")
+	assert.Contains(t, body, "unreachable")
+	assert.Equal(t, 2, strings.Count(body, `<span class="fail">&#9608;</span>`))
+}
+
+func TestMetrics(t *testing.T) {
+	rec := metrictest.Start(t)
+	AddReadiness("mongo", func() error { return errors.New("unreachable") })
+	defer RemoveReadiness("mongo")
+	ReadyReport()
+
+	tags := []metric.Tag{metric.T("kind", "readiness"), metric.T("check", "mongo")}
+	rec.AssertGauge(t, "health.check.status", int(Fail), tags...)
+	rec.AssertTimingBetween(t, "health.check.latency", 0, time.Second, tags...)
+	rec.AssertGauge(t, "health.readiness.status", int(Fail))
+}
+
+func TestWatchdog(t *testing.T) {
+	exited := make(chan error, 1)
+	defer func(f func(error)) { exit = f }(exit)
+	exit = func(err error) { exited <- err }
+
+	AddReadiness("loop", func() error { return errors.New("stuck") })
+	defer RemoveReadiness("loop")
+	stop := watchdog(20*time.Millisecond, 5*time.Millisecond)
+	defer stop()
+
+	select {
+	case err := <-exited:
+		assert.Contains(t, err.Error(), "readiness failing")
+	case <-time.After(time.Second):
+		t.Fatal("watchdog didn't exit")
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	hb := AddHeartbeat("worker", 10*time.Millisecond)
+	assert.Equal(t, Passing, ReadyReport().Status)
+	time.Sleep(20 * time.Millisecond)
+	status, note := Ready()
+	assert.Equal(t, Fail, status)
+	assert.Contains(t, string(note), "worker: no heartbeat")
+	hb.Beat()
+	assert.Equal(t, Passing, ReadyReport().Status)
+	hb.Stop()
+	assert.Empty(t, ReadyReport().Checks)
+}
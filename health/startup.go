@@ -0,0 +1,83 @@
+package health
+
+import (
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// EnvStartupWindow sets startup window (e.g. 60s).
+const EnvStartupWindow = "SVCKIT_STARTUP_WINDOW"
+
+var (
+	startTime     = time.Now()
+	startupWindow int64
+	started       int32
+)
+
+func init() {
+	if d, err := time.ParseDuration(os.Getenv(EnvStartupWindow)); err == nil {
+		SetStartupWindow(d)
+	}
+}
+
+// SetStartupWindow sets maximal duration of startup phase, measured from process start.
+// During startup (warming caches, preloading data) failing readiness and liveness
+// checks are reported as warn, so service is not ready but also not unhealthy.
+// Startup ends with first passing readiness report, StartupDone or when window elapses,
+// after that normal readiness rules apply.
+// Default window is 0, without startup phase.
+func SetStartupWindow(d time.Duration) {
+	atomic.StoreInt64(&startupWindow, int64(d))
+}
+
+// StartupDone ends startup phase.
+func StartupDone() {
+	atomic.StoreInt32(&started, 1)
+}
+
+// Starting returns true while service is in startup phase.
+func Starting() bool {
+	if atomic.LoadInt32(&started) == 1 {
+		return false
+	}
+	if time.Since(startTime) >= time.Duration(atomic.LoadInt64(&startupWindow)) {
+		StartupDone()
+		return false
+	}
+	return true
+}
+
+// startup downgrades failing checks to warn while starting.
+func startup(r *Report) {
+	if !Starting() {
+		return
+	}
+	r.Status = Warn
+	for i, c := range r.Checks {
+		if c.Status == Fail {
+			r.Checks[i].Status = Warn
+		}
+	}
+	r.Checks = append(r.Checks, CheckResult{Name: "startup", Status: Warn, Error: "starting"})
+}
+
+// StartupReport returns passing report when startup is done,
+// readiness report with fail status while starting.
+func StartupReport() Report {
+	if !Starting() {
+		return Report{Status: Passing, Time: time.Now(), Checks: []CheckResult{{Name: "startup", Status: Passing}}}
+	}
+	r := ReadyReport()
+	if Starting() {
+		r.Status = Fail
+	}
+	return r
+}
+
+// StartupHandler exposes startup report as json (on /health/startup of httpi).
+// Suitable for Kubernetes startup probe.
+func StartupHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, StartupReport())
+}
@@ -0,0 +1,49 @@
+package health
+
+import "sync"
+
+var (
+	subsMu sync.Mutex
+	subsID int
+	subs   = map[int]func(old, new Status, cause string){}
+)
+
+// Subscribe registers function called on each health status transition:
+// of the handler set by Set (checked every 10s or on Run),
+// and of the readiness checks (AddReadiness, on each readiness evaluation and scheduled check change).
+// Cause is the note of the new status.
+// Returns function which removes subscription.
+//
+// Example, stop consuming when not healthy:
+//
+//	health.Subscribe(func(old, new health.Status, cause string) {
+//		if new == health.Fail {
+//			consumer.Pause()
+//			return
+//		}
+//		consumer.Resume()
+//	})
+func Subscribe(f func(old, new Status, cause string)) func() {
+	subsMu.Lock()
+	defer subsMu.Unlock()
+	subsID++
+	id := subsID
+	subs[id] = f
+	return func() {
+		subsMu.Lock()
+		defer subsMu.Unlock()
+		delete(subs, id)
+	}
+}
+
+func publish(old, new Status, cause string) {
+	subsMu.Lock()
+	fs := make([]func(old, new Status, cause string), 0, len(subs))
+	for _, f := range subs {
+		fs = append(fs, f)
+	}
+	subsMu.Unlock()
+	for _, f := range fs {
+		f(old, new, cause)
+	}
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	type transition struct {
+		old, new Status
+		cause    string
+	}
+	var got []transition
+	unsubscribe := Subscribe(func(old, new Status, cause string) {
+		got = append(got, transition{old, new, cause})
+	})
+	defer Set(notImplemented)
+
+	Set(func() (Status, []byte) { return Passing, nil })
+	Set(func() (Status, []byte) { return Fail, []byte("mongo unreachable") })
+	Set(func() (Status, []byte) { return Fail, []byte("mongo unreachable") })
+	Set(func() (Status, []byte) { return Passing, nil })
+	unsubscribe()
+	Set(func() (Status, []byte) { return Warn, nil })
+
+	assert.Equal(t, []transition{
+		{Passing, Fail, "mongo unreachable"},
+		{Fail, Passing, ""},
+	}, got)
+}
+
+func TestSubscribeReadiness(t *testing.T) {
+	var got []Status
+	unsubscribe := Subscribe(func(old, new Status, cause string) {
+		got = append(got, new)
+	})
+	defer unsubscribe()
+	defer RemoveReadiness("mongo")
+
+	var err error
+	AddReadiness("mongo", func() error { return err })
+	Ready()
+	err = errors.New("unreachable")
+	Ready()
+	Ready()
+	err = nil
+	Ready()
+
+	assert.Equal(t, []Status{Fail, Passing}, got)
+}
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+)
+
+// EnvWatchdog starts watchdog with the duration (e.g. 5m).
+const EnvWatchdog = "SVCKIT_HEALTH_WATCHDOG"
+
+// exit terminates the process, replaced in tests.
+var exit = func(err error) {
+	logger().Fatal(err)
+}
+
+func init() {
+	if d, err := time.ParseDuration(os.Getenv(EnvWatchdog)); err == nil && d > 0 {
+		Watchdog(d)
+	}
+}
+
+// Watchdog terminates the process if readiness is failing continuously for longer than after,
+// so orchestrator (Nomad, Kubernetes) can restart wedged instance.
+// Warn (startup, drain, informational checks) is not failing.
+// Readiness is checked every after/10 (at least every second).
+// Returns function which stops the watchdog.
+func Watchdog(after time.Duration) func() {
+	interval := after / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return watchdog(after, interval)
+}
+
+func watchdog(after, interval time.Duration) func() {
+	stop := make(chan struct{})
+	go func() {
+		var failingSince time.Time
+		for {
+			select {
+			case <-time.After(interval):
+			case <-stop:
+				return
+			}
+			r := ReadyReport()
+			if r.Status != Fail {
+				failingSince = time.Time{}
+				continue
+			}
+			if failingSince.IsZero() {
+				failingSince = r.Time
+				continue
+			}
+			if d := r.Time.Sub(failingSince); d >= after {
+				metric.Counter("health.watchdog.exit")
+				logger().S("after", after.String()).Jc("note", r.Note()).Notice("watchdog terminating process")
+				exit(fmt.Errorf("readiness failing for %s", d.Truncate(time.Second)))
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
@@ -3,10 +3,13 @@ package httpi
 import (
 	_ "expvar"
 	"net/http"
+	"os"
 
 	"github.com/minus5/svckit/env"
 	"github.com/minus5/svckit/health"
 	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/metric/prometheus"
 	"github.com/minus5/svckit/signal"
 
 	"github.com/codegangsta/negroni"
@@ -28,15 +31,15 @@ type Router struct {
 }
 
 func (r *Router) Route(path string, f func(http.ResponseWriter, *http.Request)) *mux.Route {
-	return r.muxRouter.HandleFunc(path, f)
+	return r.Handle(path, http.HandlerFunc(f))
 }
 
 func (r *Router) Handle(path string, handler http.Handler) *mux.Route {
-	return r.muxRouter.Handle(path, handler)
+	return r.muxRouter.Handle(path, recordRoute(handler))
 }
 
 func (r *Router) HandlePath(path string, handler http.Handler) *mux.Route {
-	return r.muxRouter.PathPrefix(path).Handler(handler)
+	return r.muxRouter.PathPrefix(path).Handler(recordRoute(handler))
 }
 
 func (r *Router) Subrouter(path string) *Router {
@@ -50,7 +53,7 @@ func (r *Router) Subrouter(path string) *Router {
 //isto kao gore raspetlja url varijable
 func (r *Router) RouteVars(path string,
 	f func(http.ResponseWriter, *http.Request, map[string]string)) *mux.Route {
-	return r.muxRouter.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+	return r.Route(path, func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		f(w, r, vars)
 	})
@@ -61,6 +64,7 @@ func (r *Router) RouteVars(path string,
 //
 //   httpi.Start(":8123", httpi.LogRequests())
 func (r *Router) Start(listen string) {
+	log.AppStart()
 	n := r.Handler()
 	// Run i ListenAndServe (koji se zove unutra)
 	// nikada ne nastavi na slijedecoj liniji
@@ -80,13 +84,28 @@ func (r *Router) Start(listen string) {
 func (r *Router) Handler() *negroni.Negroni {
 	if !r.noDebug {
 		//ping
-		r.muxRouter.HandleFunc("/ping", PingHttpResponse)
+		r.Route("/ping", PingHttpResponse)
+		//verzija i build info (na /version)
+		r.Route("/version", env.VersionHandler)
 		//dodaj /health_check
-		r.muxRouter.HandleFunc("/health_check", health.HttpHandler)
+		r.Route("/health_check", health.HttpHandler)
+		//liveness, readiness i startup (za Consul i Kubernetes)
+		r.Route("/health/live", health.LiveHandler)
+		r.Route("/health/ready", health.ReadyHandler)
+		r.Route("/health/startup", health.StartupHandler)
+		//status stranica (na /health), zasticena tokenom
+		r.Route("/health", health.DashboardHandler(os.Getenv(log.EnvAdminToken)))
 		//otvori expvar interface (na /debug/vars)
-		r.muxRouter.Handle("/debug/vars", http.DefaultServeMux)
+		r.Handle("/debug/vars", http.DefaultServeMux)
+		//runtime log config (na /debug/log), zasticen tokenom
+		r.Handle("/debug/log", log.HttpHandler(os.Getenv(log.EnvAdminToken)))
+		//prometheus metrics (na /metrics), ako je ukljucen prometheus.Enable
+		r.Handle("/metrics", prometheus.Handler())
+		//trenutne vrijednosti metrika (na /debug/metrics), ako je ukljucen metric.EnableMemory
+		r.Handle("/debug/metrics", metric.MemoryHandler())
 	}
-	handlers := []negroni.Handler{negroni.NewRecovery(), NewStats()}
+	// RequestID prije Metrics, da trace bude u contextu za exemplare
+	handlers := []negroni.Handler{negroni.NewRecovery(), NewRequestID(), NewStats(), NewMetrics()}
 	if r.log {
 		handlers = append(handlers, NewRequestLogger())
 	}
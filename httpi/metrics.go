@@ -0,0 +1,88 @@
+package httpi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+
+	"github.com/codegangsta/negroni"
+	"github.com/gorilla/mux"
+)
+
+// Metrics is middleware which records request metrics tagged with route, method and status class:
+//
+//	http.requests        counter
+//	http.duration        timing
+//	http.response_bytes  counter
+//	http.in_flight       gauge, without tags
+//
+// Route is mux path template (e.g. /users/{id}) of the route added through Router,
+// or "unmatched" for requests without route (404s, scanners), so paths don't explode cardinality.
+type Metrics struct {
+	inFlight int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+type matchedKey struct{}
+
+// matched is route template set by the router handler (see recordRoute).
+type matched struct {
+	route string
+}
+
+func (m *Metrics) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	match := &matched{}
+	r = r.WithContext(context.WithValue(r.Context(), matchedKey{}, match))
+	metric.Gauge("http.in_flight", int(atomic.AddInt64(&m.inFlight, 1)))
+	start := time.Now()
+	next(rw, r)
+	duration := time.Since(start)
+	route := match.route
+	if route == "" {
+		route = "unmatched"
+	}
+	metric.Gauge("http.in_flight", int(atomic.AddInt64(&m.inFlight, -1)))
+
+	status, size := http.StatusOK, 0
+	if res, ok := rw.(negroni.ResponseWriter); ok && res.Written() {
+		status, size = res.Status(), res.Size()
+	}
+	tags := []metric.Tag{
+		metric.T("route", route),
+		metric.T("method", r.Method),
+		metric.T("status", statusClass(status)),
+	}
+	metric.CounterT("http.requests", 1, tags...)
+	metric.TimeContext(r.Context(), "http.duration", int(duration.Nanoseconds()), tags...)
+	metric.CounterT("http.response_bytes", size, tags...)
+}
+
+// recordRoute wraps route handler to pass route matched by mux to Metrics.
+func recordRoute(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m, ok := r.Context().Value(matchedKey{}).(*matched); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				if tpl, err := route.GetPathTemplate(); err == nil {
+					m.route = tpl
+				}
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// statusClass returns status class, e.g. 2xx.
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return strconv.Itoa(status)
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
@@ -0,0 +1,31 @@
+package httpi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/metric/metrictest"
+)
+
+func TestMetrics(t *testing.T) {
+	rec := metrictest.Start(t)
+
+	r := NewRouter().NoDebug()
+	r.Route("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/12", nil))
+	rec.AssertCount(t, "http.requests", 1, metric.T("route", "/users/{id}"), metric.T("method", "GET"), metric.T("status", "4xx"))
+	rec.AssertTimingBetween(t, "http.duration", 0, time.Second, metric.T("route", "/users/{id}"))
+
+	r.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/other/path", nil))
+	rec.AssertCount(t, "http.requests", 1, metric.T("route", "unmatched"), metric.T("status", "4xx"))
+
+	sub := r.Subrouter("/api")
+	sub.Route("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/items/3", nil))
+	rec.AssertCount(t, "http.requests", 1, metric.T("route", "/api/items/{id}"), metric.T("status", "2xx"))
+}
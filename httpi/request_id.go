@@ -0,0 +1,28 @@
+package httpi
+
+import (
+	"net/http"
+
+	"github.com/minus5/svckit/log"
+)
+
+// RequestID puts request id (from X-Request-Id header or generated) and
+// W3C traceparent ids into request context, so that log.FromContext adds them to log records.
+type RequestID struct{}
+
+func NewRequestID() *RequestID {
+	return &RequestID{}
+}
+
+func (*RequestID) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id := r.Header.Get(log.RequestIDHeader)
+	if id == "" {
+		id = log.NewRequestID()
+	}
+	rw.Header().Set(log.RequestIDHeader, id)
+	ctx := log.WithRequestID(r.Context(), id)
+	if traceID, spanID, ok := log.ParseTraceparent(r.Header.Get("traceparent")); ok {
+		ctx = log.WithTrace(ctx, traceID, spanID)
+	}
+	next(rw, r.WithContext(ctx))
+}
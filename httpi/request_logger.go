@@ -22,7 +22,8 @@ func (l *RequestLogger) ServeHTTP(rw http.ResponseWriter, r *http.Request, next
 	duration := time.Since(start)
 	res := rw.(negroni.ResponseWriter)
 
-	logger().S("method", r.Method).
+	log.FromContext(r.Context()).S("lib", "svckit.httpi").
+		S("method", r.Method).
 		S("url", r.URL.Path).
 		S("status", http.StatusText(res.Status())).
 		I("code", res.Status()).
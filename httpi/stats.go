@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"github.com/minus5/svckit/metric"
 	"strings"
-	"time"
 )
 
 type Stats struct {
@@ -21,7 +20,6 @@ func (s *Stats) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.Han
 		method = parts[1]
 	}
 
-	start := time.Now()
+	defer metric.Timed(fmt.Sprintf("http.%s", method))()
 	next(rw, r)
-	metric.Time(fmt.Sprintf("http.%s", method), int(time.Since(start).Nanoseconds()))
 }
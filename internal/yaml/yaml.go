@@ -0,0 +1,278 @@
+// Package yaml parses subset of yaml used for configuration files and specs:
+// block maps, block lists, inline lists of scalars and scalars.
+// Anything else (anchors, tags, block scalars, inline maps, multiple documents) is an error.
+// Quoting and comment helpers are shared with toml, which has the same syntax for them.
+package yaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// line is non empty line without comment.
+type line struct {
+	n      int
+	indent int
+	text   string
+}
+
+type parser struct {
+	ls []line
+	i  int
+}
+
+// Parse returns map[string]interface{} or []interface{} with values
+// of the same types, or scalars (string, bool, float64, nil).
+func Parse(buf []byte) (interface{}, error) {
+	p := &parser{}
+	for i, l := range strings.Split(string(buf), "\n") {
+		l = StripComment(strings.TrimRight(l, "\r"))
+		text := strings.TrimSpace(l)
+		if text == "" {
+			continue
+		}
+		if text == "---" {
+			if len(p.ls) > 0 {
+				return nil, fmt.Errorf("yaml line %d: multiple documents not supported", i+1)
+			}
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " "))
+		if strings.HasPrefix(l[indent:], "\t") {
+			return nil, fmt.Errorf("yaml line %d: tab indentation", i+1)
+		}
+		p.ls = append(p.ls, line{n: i + 1, indent: indent, text: text})
+	}
+	if len(p.ls) == 0 {
+		return nil, nil
+	}
+	v, err := p.block(p.ls[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.ls) {
+		return nil, fmt.Errorf("yaml line %d: unexpected indentation", p.ls[p.i].n)
+	}
+	return v, nil
+}
+
+func isItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses map or list starting at current line.
+func (p *parser) block(indent int) (interface{}, error) {
+	if isItem(p.ls[p.i].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+// nested parses value of the key or list item on the next lines.
+// List can be at the same indentation as the parent key.
+func (p *parser) nested(indent int, key bool) (interface{}, error) {
+	if p.i >= len(p.ls) {
+		return nil, nil
+	}
+	l := p.ls[p.i]
+	if l.indent > indent || (key && l.indent == indent && isItem(l.text)) {
+		return p.block(l.indent)
+	}
+	return nil, nil
+}
+
+func (p *parser) mapping(indent int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for p.i < len(p.ls) {
+		l := p.ls[p.i]
+		if l.indent < indent || (l.indent == indent && isItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("yaml line %d: unexpected indentation", l.n)
+		}
+		k, v, ok := splitKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml line %d: expected key: value", l.n)
+		}
+		p.i++
+		var err error
+		if v == "" {
+			m[k], err = p.nested(indent, true)
+		} else {
+			m[k], err = value(v)
+		}
+		if err != nil {
+			return nil, lineError(l, err)
+		}
+	}
+	return m, nil
+}
+
+func (p *parser) list(indent int) ([]interface{}, error) {
+	var items []interface{}
+	for p.i < len(p.ls) {
+		l := p.ls[p.i]
+		if l.indent < indent || (l.indent == indent && !isItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("yaml line %d: unexpected indentation", l.n)
+		}
+		item := strings.TrimPrefix(l.text, "-")
+		content := strings.TrimLeft(item, " ")
+		var (
+			v   interface{}
+			err error
+		)
+		switch _, _, isMap := splitKey(content); {
+		case content == "":
+			p.i++
+			v, err = p.nested(indent, false)
+		case isMap:
+			// map item, next keys are at the indentation of the first key
+			p.ls[p.i] = line{n: l.n, indent: indent + 1 + len(item) - len(content), text: content}
+			v, err = p.mapping(p.ls[p.i].indent)
+		default:
+			p.i++
+			v, err = value(content)
+		}
+		if err != nil {
+			return nil, lineError(l, err)
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+func lineError(l line, err error) error {
+	if strings.HasPrefix(err.Error(), "yaml line") {
+		return err
+	}
+	return fmt.Errorf("yaml line %d: %w", l.n, err)
+}
+
+// splitKey splits key: value, key can be quoted.
+func splitKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return "", "", false
+		}
+		return Unquote(text[:end+2]), strings.TrimSpace(rest[1:]), true
+	}
+	if strings.HasSuffix(text, ":") {
+		return text[:len(text)-1], "", true
+	}
+	i := strings.Index(text, ": ")
+	if i <= 0 {
+		return "", "", false
+	}
+	return text[:i], strings.TrimSpace(text[i+2:]), true
+}
+
+func value(s string) (interface{}, error) {
+	switch s[0] {
+	case '[':
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated list")
+		}
+		return InlineList(s[1 : len(s)-1])
+	case '{', '|', '>', '&', '*', '!':
+		return nil, fmt.Errorf("unsupported value %s", s)
+	}
+	return Scalar(s), nil
+}
+
+// InlineList returns scalar items of inline list, s is content between brackets.
+func InlineList(s string) ([]interface{}, error) {
+	items := []interface{}{}
+	var quote byte
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			c := s[i]
+			switch {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c == '[' || c == '{':
+				return nil, fmt.Errorf("nested list or map not supported")
+			case c != ',':
+				continue
+			}
+		}
+		item := strings.TrimSpace(s[start:i])
+		start = i + 1
+		if item == "" {
+			// allow trailing comma and empty list
+			if i < len(s) {
+				return nil, fmt.Errorf("empty list item")
+			}
+			continue
+		}
+		items = append(items, Scalar(item))
+	}
+	return items, nil
+}
+
+// Scalar converts unquoted value to string, bool, float64 or nil.
+func Scalar(s string) interface{} {
+	if s[0] == '"' || s[0] == '\'' {
+		return Unquote(s)
+	}
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// Unquote removes single or double quotes from s.
+func Unquote(s string) string {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return s
+	}
+	if s[0] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+		return s[1 : len(s)-1]
+	}
+	return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+}
+
+// StripComment removes # comment which is not inside quotes from line l.
+func StripComment(l string) string {
+	var quote rune
+	for i, c := range l {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || l[i-1] == ' ' || l[i-1] == '\t'):
+			return strings.TrimRight(l[:i], " \t")
+		}
+	}
+	return l
+}
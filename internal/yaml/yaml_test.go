@@ -0,0 +1,50 @@
+package yaml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse([]byte(`---
+# spec
+name: api # comment
+port: 8080
+tags: [v1, "v 2"]
+checks:
+- name: http
+  http: 'http://localhost:8080/#health'
+empty:
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":   "api",
+		"port":   8080.0,
+		"tags":   []interface{}{"v1", "v 2"},
+		"checks": []interface{}{map[string]interface{}{"name": "http", "http": "http://localhost:8080/#health"}},
+		"empty":  nil,
+	}, v)
+
+	for _, s := range []string{
+		"a: &x 1\n",
+		"a: |\n  text\n",
+		"a: {b: 1}\n",
+		"a:\n\tb: 1\n",
+		"a: 1\n---\nb: 2\n",
+		"a: [[1]]\n",
+		"a: 1\n  b: 2\n",
+	} {
+		_, err := Parse([]byte(s))
+		assert.Error(t, err, s)
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	assert.Equal(t, `a: "#b"`, StripComment(`a: "#b" # comment`))
+	assert.Equal(t, "it's", Unquote(`'it''s'`))
+	assert.Equal(t, "a\tb", Unquote(`"a\tb"`))
+	items, err := InlineList(`a, "b, c", 1,`)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b, c", 1.0}, items)
+}
@@ -27,6 +27,9 @@ type Agregator struct {
 	msg         string
 	callerDepth int
 	output      io.Writer
+	minLevel    *Level
+	err         error
+	summary     bool // dedup summary record
 }
 
 const (
@@ -82,11 +85,44 @@ func (a *Agregator) freeBuf() {
 }
 
 func (a *Agregator) write() error {
-	if a.file == "" { //zbog testova
+	if !a.enabled() || !sample(a.level, a.msg, a.t) {
+		return nil
+	}
+	if a.file == "" && callerEnabled() { //zbog testova
 		a.file, a.line = getCaller(a.callerDepth)
 	}
+	if a.duplicate() {
+		return nil
+	}
+	a.addStack()
+	a.redact()
 	a.msg = limitStrLen(strconv.QuoteToASCII(a.msg))
 	a.getBuf()
+	switch getFormat() {
+	case FormatLogfmt:
+		a.logfmt()
+	case FormatConsole:
+		a.console()
+	default:
+		a.json()
+	}
+	var err error
+	if lw, ok := a.output.(levelWriter); ok {
+		_, err = lw.WriteLevel(levelOf(a.level), *a.buf)
+	} else {
+		_, err = a.output.Write(*a.buf)
+	}
+	a.freeBuf()
+	if err != nil {
+		countSinkError("output")
+	} else {
+		countWritten(levelOf(a.level))
+	}
+	a.fireHooks()
+	return err
+}
+
+func (a *Agregator) json() {
 	a.timeFile(a.t, a.file, a.line)
 	a.s("level", a.level)
 	for _, atr := range a.attrs {
@@ -94,9 +130,6 @@ func (a *Agregator) write() error {
 	}
 	a.s("msg", a.msg)
 	*a.buf = append(*a.buf, "}\n"...)
-	_, err := a.output.Write(*a.buf)
-	a.freeBuf()
-	return err
 }
 
 func (a *Agregator) timeFile(t time.Time, file string, line int) {
@@ -124,10 +157,12 @@ func (a *Agregator) timeFile(t time.Time, file string, line int) {
 	itoa(buf, offset/3600, 2)
 	*buf = append(*buf, `:00`...)
 
-	*buf = append(*buf, `", "file":"`...)
-	*buf = append(*buf, file...)
-	*buf = append(*buf, ':')
-	itoa(buf, line, -1)
+	if file != "" {
+		*buf = append(*buf, `", "file":"`...)
+		*buf = append(*buf, file...)
+		*buf = append(*buf, ':')
+		itoa(buf, line, -1)
+	}
 	if len(prefix) > 0 {
 		*buf = append(*buf, `", `...)
 		*buf = append(*buf, prefix...)
@@ -144,6 +179,7 @@ var (
 	LevelFatal  = `"fatal"`
 	LevelNotice = `"notice"`
 	LevelEvent  = `"event"`
+	LevelWarn   = `"warn"`
 )
 
 // unquoted versions
@@ -154,6 +190,7 @@ var (
 	LevelFatalUnquoted  = strings.Trim(LevelFatal, `"`)
 	LevelNoticeUnquoted = strings.Trim(LevelNotice, `"`)
 	LevelEventUnquoted  = strings.Trim(LevelEvent, `"`)
+	LevelWarnUnquoted   = strings.Trim(LevelWarn, `"`)
 )
 
 var (
@@ -169,10 +206,25 @@ func escapeKey(key string) string {
 	return key
 }
 
-func (a *Agregator) Debug(msg string) {
-	if !debugLogLevelEnabled {
-		return
+// Level sets minimal level for this logger, overrides global level.
+func (a *Agregator) Level(l Level) *Agregator {
+	a.minLevel = &l
+	return a
+}
+
+func (a *Agregator) enabled() bool {
+	if a.minLevel != nil {
+		return levelOf(a.level) >= *a.minLevel
 	}
+	if c := a.component(); c != "" {
+		if l, ok := componentLevel(c); ok {
+			return levelOf(a.level) >= l
+		}
+	}
+	return enabled(levelOf(a.level))
+}
+
+func (a *Agregator) Debug(msg string) {
 	a.level = LevelDebug
 	a.msg = msg
 	a.write()
@@ -184,6 +236,12 @@ func (a *Agregator) Info(msg string) {
 	a.write()
 }
 
+func (a *Agregator) Warn(msg string) {
+	a.level = LevelWarn
+	a.msg = msg
+	a.write()
+}
+
 func (a *Agregator) ErrorS(msg string) {
 	a.level = LevelError
 	a.msg = msg
@@ -192,6 +250,7 @@ func (a *Agregator) ErrorS(msg string) {
 
 func (a *Agregator) Error(err error) {
 	a.level = LevelError
+	a.err = err
 	if err != nil {
 		a.msg = err.Error()
 	} else {
@@ -214,12 +273,14 @@ func (a *Agregator) Event(msg string) {
 
 func (a *Agregator) Fatal(err error) {
 	a.level = LevelFatal
+	a.err = err
 	if err != nil {
 		a.msg = err.Error()
 	} else {
 		a.msg = ""
 	}
 	a.write()
+	Flush()
 	os.Exit(-1)
 }
 
@@ -346,6 +407,9 @@ func splitLevelMessage(line string) (string, string) {
 	if strings.Contains(line, "[NOTICE]") {
 		return LevelNotice, replace(line, "[NOTICE]")
 	}
+	if strings.Contains(line, "[WARN]") {
+		return LevelWarn, replace(line, "[WARN]")
+	}
 	return LevelDebug, line
 }
 
@@ -355,12 +419,5 @@ func getCaller(depth int) (string, int) {
 		longFile = "???"
 		line = 0
 	}
-	shortFile := longFile
-	for i := len(longFile) - 1; i > 0; i-- {
-		if longFile[i] == '/' {
-			shortFile = longFile[i+1:]
-			break
-		}
-	}
-	return shortFile, line
+	return shortFile(longFile), line
 }
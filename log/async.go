@@ -0,0 +1,197 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy is behavior of the Async output when buffer is full.
+type Policy int
+
+// Overflow policies.
+const (
+	Block Policy = iota
+	DropOldest
+	DropNewest
+)
+
+// Environment variables for async output.
+const (
+	EnvAsync       = "SVCKIT_LOG_ASYNC"        // buffer size (number of lines), enables async output
+	EnvAsyncPolicy = "SVCKIT_LOG_ASYNC_POLICY" // block, drop-oldest or drop-newest
+)
+
+// Async is output which writes to the underlying writer from background goroutine.
+// Log calls don't wait for slow writes (syslog, network).
+type Async struct {
+	w       io.Writer
+	policy  Policy
+	lines   chan []byte
+	flush   chan chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	dropped int64
+}
+
+// NewAsync creates async output to w with buffer of size lines.
+func NewAsync(w io.Writer, size int, policy Policy) *Async {
+	a := &Async{
+		w:       w,
+		policy:  policy,
+		lines:   make(chan []byte, size),
+		flush:   make(chan chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+// Write implements io.Writer.
+// Lines written after Close are dropped.
+func (a *Async) Write(p []byte) (int, error) {
+	select {
+	case <-a.closing:
+		a.drop()
+		return len(p), nil
+	default:
+	}
+	line := make([]byte, len(p))
+	copy(line, p)
+	switch a.policy {
+	case Block:
+		select {
+		case a.lines <- line:
+		case <-a.closing:
+			a.drop()
+		}
+	case DropNewest:
+		select {
+		case a.lines <- line:
+		default:
+			a.drop()
+		}
+	case DropOldest:
+		for {
+			select {
+			case a.lines <- line:
+				return len(p), nil
+			default:
+			}
+			select {
+			case <-a.lines:
+				a.drop()
+			default:
+			}
+		}
+	}
+	return len(p), nil
+}
+
+func (a *Async) drop() {
+	atomic.AddInt64(&a.dropped, 1)
+	countDropped("async")
+}
+
+// Dropped returns number of lines dropped because buffer was full or output was closed.
+func (a *Async) Dropped() int64 {
+	return atomic.LoadInt64(&a.dropped)
+}
+
+// Flush waits until all buffered lines are written.
+func (a *Async) Flush() {
+	c := make(chan struct{})
+	select {
+	case a.flush <- c:
+		<-c
+	case <-a.done:
+	}
+}
+
+// Close flushes buffered lines and closes underlying writer if it is io.Closer.
+func (a *Async) Close() error {
+	a.once.Do(func() {
+		close(a.closing)
+	})
+	<-a.done
+	if c, ok := a.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (a *Async) loop() {
+	defer close(a.done)
+	for {
+		select {
+		case line := <-a.lines:
+			a.w.Write(line)
+		case c := <-a.flush:
+			for len(a.lines) > 0 {
+				a.w.Write(<-a.lines)
+			}
+			if f, ok := a.w.(interface{ Flush() }); ok {
+				f.Flush()
+			}
+			close(c)
+		case <-a.closing:
+			for len(a.lines) > 0 {
+				a.w.Write(<-a.lines)
+			}
+			return
+		}
+	}
+}
+
+// SetAsync makes writes to the current output asynchronous.
+// When output is Tee each of its outputs gets own buffer.
+func SetAsync(size int, policy Policy) {
+	if t, ok := out.(*Tee); ok {
+		for i, s := range t.sinks {
+			t.sinks[i].W = NewAsync(s.W, size, policy)
+		}
+		return
+	}
+	SetOutput(NewAsync(out, size, policy))
+}
+
+// Flush flushes buffered output and hooks (e.g. otlp exporter).
+// Should be called before application exit.
+func Flush() {
+	if f, ok := out.(interface{ Flush() }); ok {
+		defer timeFlush(time.Now())
+		f.Flush()
+	}
+	flushHooks()
+}
+
+// Close flushes and closes output and hooks.
+func Close() error {
+	err := closeHooks()
+	if c, ok := out.(io.Closer); ok {
+		if e := c.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func initAsync() {
+	size, err := strconv.Atoi(os.Getenv(EnvAsync))
+	if err != nil || size <= 0 {
+		return
+	}
+	policy := Block
+	switch os.Getenv(EnvAsyncPolicy) {
+	case "drop-oldest":
+		policy = DropOldest
+	case "drop-newest":
+		policy = DropNewest
+	}
+	SetAsync(size, policy)
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingWriter blocks writes until released.
+type blockingWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	a := NewAsync(w, 2, DropNewest)
+	for i := 0; i < 10; i++ {
+		a.Write([]byte("x\n"))
+	}
+	// one line can be taken by the writer goroutine
+	assert.True(t, a.Dropped() >= 7)
+	close(w.release)
+	assert.Nil(t, a.Close())
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	a := NewAsync(w, 2, DropOldest)
+	for _, l := range []string{"1", "2", "3", "4", "5"} {
+		a.Write([]byte(l))
+	}
+	close(w.release)
+	a.Flush()
+	assert.True(t, a.Dropped() >= 2)
+	assert.Contains(t, w.buf.String(), "45")
+}
+
+func TestAsyncWriteAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAsync(&buf, 2, Block)
+	a.Write([]byte("1"))
+	assert.Nil(t, a.Close())
+	assert.Equal(t, "1", buf.String())
+	for i := 0; i < 5; i++ {
+		a.Write([]byte("x"))
+	}
+	assert.Equal(t, int64(5), a.Dropped())
+	assert.Equal(t, "1", buf.String())
+}
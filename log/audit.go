@@ -0,0 +1,148 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvAuditFile is path of the audit log file.
+	EnvAuditFile = "SVCKIT_LOG_AUDIT_FILE"
+
+	auditRetries = 3
+	levelAudit   = `"audit"`
+)
+
+var (
+	auditMu   sync.Mutex
+	auditSink io.Writer
+)
+
+// SetAuditSink sets durable output for audit records.
+// Write must return only when record is durably stored (fsynced, acknowledged).
+func SetAuditSink(w io.Writer) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditSink = w
+}
+
+// AuditEntry is audit record builder.
+// Audit records are written synchronously to the audit sink in json format,
+// they are never sampled, deduplicated or dropped, and don't depend on log level.
+//
+// Example:
+//
+//	err := log.Audit().S("user", user).S("action", "login").Log("login")
+type AuditEntry struct {
+	a *Agregator
+}
+
+// Audit returns new audit record builder.
+func Audit() *AuditEntry {
+	return &AuditEntry{a: newAgregator(3)}
+}
+
+func (e *AuditEntry) S(key string, val string) *AuditEntry {
+	e.a.S(key, val)
+	return e
+}
+
+func (e *AuditEntry) I(key string, val int) *AuditEntry {
+	e.a.I(key, val)
+	return e
+}
+
+func (e *AuditEntry) B(key string, val bool) *AuditEntry {
+	e.a.B(key, val)
+	return e
+}
+
+func (e *AuditEntry) J(key string, val []byte) *AuditEntry {
+	e.a.J(key, val)
+	return e
+}
+
+// Log writes audit record, retrying on failure.
+// Returns error if record could not be stored.
+func (e *AuditEntry) Log(msg string) error {
+	a := e.a
+	a.level = levelAudit
+	a.file, a.line = getCaller(2)
+	a.redact()
+	a.msg = limitStrLen(strconv.QuoteToASCII(msg))
+	a.getBuf()
+	defer a.freeBuf()
+	a.json()
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditSink == nil {
+		return errors.New("audit sink not set")
+	}
+	var err error
+	backoff := 100 * time.Millisecond
+	for i := 0; i < auditRetries; i++ {
+		if _, err = auditSink.Write(*a.buf); err == nil {
+			return nil
+		}
+		countSinkError("audit")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return err
+}
+
+// AuditFile is append only audit log file, each write is fsynced.
+type AuditFile struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewAuditFile opens audit file for appending.
+func NewAuditFile(path string) (*AuditFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditFile{f: f}, nil
+}
+
+// Write appends p to the file and syncs it to disk.
+func (f *AuditFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.f.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.f.Sync()
+}
+
+// Close closes the file.
+func (f *AuditFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Close()
+}
+
+func initAudit() {
+	path := os.Getenv(EnvAuditFile)
+	if path == "" {
+		return
+	}
+	f, err := NewAuditFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit file open failed: %s\n", err)
+		return
+	}
+	SetAuditSink(f)
+}
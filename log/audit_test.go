@@ -0,0 +1,34 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	defer SetAuditSink(nil)
+	assert.Error(t, Audit().Log("login"))
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := NewAuditFile(path)
+	assert.NoError(t, err)
+	SetAuditSink(f)
+	SetLevel(FatalLevel)
+	defer SetLevel(DebugLevel)
+	assert.NoError(t, Audit().S("user", "pero").S("password", "x").Log("login"))
+	assert.NoError(t, f.Close())
+
+	buf, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	line := string(buf)
+	assert.Contains(t, line, `"file":"audit_test.go:`)
+	assert.True(t, strings.HasSuffix(line, `"level":"audit", "user":"pero", "password":"***", "msg":"login"}`+"\n"), line)
+
+	SetAuditSink(errWriter{})
+	assert.Equal(t, errors.New("failed"), Audit().Log("login"))
+}
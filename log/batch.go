@@ -0,0 +1,138 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize          = 1000
+	batchFlushInterval = time.Second
+	batchBufferSize    = 8192
+)
+
+// BatchLine is log line or record buffered in BatchWriter.
+type BatchLine struct {
+	Time time.Time
+	Line []byte
+	// Record is set for records added with WriteRecord, Line is nil then
+	Record *Record
+}
+
+// BatchWriter buffers written lines and pushes them in batches
+// from background goroutine. Base for outputs to remote collectors.
+// Line is dropped if buffer is full or writer is closed.
+type BatchWriter struct {
+	push    func([]BatchLine) error
+	lines   chan BatchLine
+	flush   chan chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewBatchWriter creates BatchWriter which sends batches with push.
+func NewBatchWriter(push func([]BatchLine) error) *BatchWriter {
+	b := &BatchWriter{
+		push:    push,
+		lines:   make(chan BatchLine, batchBufferSize),
+		flush:   make(chan chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// Write implements io.Writer.
+func (b *BatchWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	b.add(BatchLine{Time: time.Now(), Line: line})
+	return len(p), nil
+}
+
+// WriteRecord buffers structured record, for writers used as Hook.
+func (b *BatchWriter) WriteRecord(r Record) {
+	b.add(BatchLine{Time: r.Time, Record: &r})
+}
+
+func (b *BatchWriter) add(bl BatchLine) {
+	select {
+	case <-b.closing:
+		countDropped("batch")
+		return
+	default:
+	}
+	select {
+	case b.lines <- bl:
+	default:
+		countDropped("batch")
+	}
+}
+
+// Flush pushes buffered lines.
+func (b *BatchWriter) Flush() {
+	c := make(chan struct{})
+	select {
+	case b.flush <- c:
+		<-c
+	case <-b.done:
+	}
+}
+
+// Close flushes buffered lines and stops background goroutine.
+// Lines written after Close are dropped.
+func (b *BatchWriter) Close() error {
+	b.once.Do(func() {
+		close(b.closing)
+	})
+	<-b.done
+	return nil
+}
+
+func (b *BatchWriter) loop() {
+	defer close(b.done)
+	var batch []BatchLine
+	push := func() {
+		if len(batch) == 0 {
+			return
+		}
+		start := time.Now()
+		err := b.push(batch)
+		timeFlush(start)
+		if err != nil {
+			countSinkError("batch")
+			// can't log through myself
+			fmt.Fprintf(os.Stderr, "log batch push failed: %s\n", err)
+		}
+		batch = nil
+	}
+	ticker := time.NewTicker(batchFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case bl := <-b.lines:
+			batch = append(batch, bl)
+			if len(batch) >= batchSize {
+				push()
+			}
+		case <-ticker.C:
+			push()
+		case c := <-b.flush:
+			for len(b.lines) > 0 {
+				batch = append(batch, <-b.lines)
+			}
+			push()
+			close(c)
+		case <-b.closing:
+			for len(b.lines) > 0 {
+				batch = append(batch, <-b.lines)
+			}
+			push()
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// EnvCaller disables caller file:line in records when set to 0 or false.
+	EnvCaller = "SVCKIT_LOG_CALLER"
+	// EnvStack sets levels with stack trace in records, e.g. "error,fatal".
+	EnvStack = "SVCKIT_LOG_STACK"
+
+	maxStackDepth = 32
+)
+
+var (
+	callerDisabled int32
+	stackLevels    int32 // bit mask of levels
+)
+
+// SetCaller enables or disables caller file:line in each record.
+func SetCaller(enabled bool) {
+	var v int32
+	if !enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&callerDisabled, v)
+}
+
+func callerEnabled() bool {
+	return atomic.LoadInt32(&callerDisabled) == 0
+}
+
+// SetStackLevels sets levels on which structured stack trace is added to the record in stack field.
+// Capturing stack is expensive so it is disabled by default.
+// Call without arguments to disable.
+func SetStackLevels(levels ...Level) {
+	var mask int32
+	for _, l := range levels {
+		mask |= 1 << uint(l)
+	}
+	atomic.StoreInt32(&stackLevels, mask)
+}
+
+// StackLevels returns levels with stack trace.
+func StackLevels() []Level {
+	mask := atomic.LoadInt32(&stackLevels)
+	var levels []Level
+	for l := DebugLevel; l <= FatalLevel; l++ {
+		if mask&(1<<uint(l)) != 0 {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+func stackEnabled(l Level) bool {
+	return atomic.LoadInt32(&stackLevels)&(1<<uint(l)) != 0
+}
+
+// Frame is one stack trace frame.
+type Frame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// stack returns stack trace starting at the caller frame for Agregator callerDepth,
+// called from addStack in write.
+func stack(depth int) []Frame {
+	pc := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(depth+2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	var fs []Frame
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.goexit" {
+			break
+		}
+		fs = append(fs, Frame{Func: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			break
+		}
+	}
+	return fs
+}
+
+// addStack adds stack trace field to the record.
+func (a *Agregator) addStack() {
+	if !stackEnabled(levelOf(a.level)) {
+		return
+	}
+	buf, err := json.Marshal(stack(a.callerDepth))
+	if err != nil {
+		return
+	}
+	a.attrs = append(a.attrs, &attr{key: "stack", val: string(buf)})
+}
+
+func initCaller() {
+	if e, ok := os.LookupEnv(EnvCaller); ok && (e == "0" || e == "false") {
+		SetCaller(false)
+	}
+	e := os.Getenv(EnvStack)
+	if e == "" {
+		return
+	}
+	var levels []Level
+	for _, s := range strings.Split(e, ",") {
+		if l, ok := ParseLevel(strings.TrimSpace(s)); ok {
+			levels = append(levels, l)
+		}
+	}
+	SetStackLevels(levels...)
+}
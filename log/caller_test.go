@@ -0,0 +1,44 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetCaller(t *testing.T) {
+	defer SetCaller(true)
+	SetCaller(false)
+	a := newTestAgregator()
+	a.file = ""
+	a.Info("msg")
+	assert.Equal(t, `{"time":"2009-11-10T23:05:06.000000+00:00", "level":"info", "msg":"msg"}`, currentBuffer(a))
+}
+
+func TestStackLevels(t *testing.T) {
+	defer SetStackLevels()
+	SetStackLevels(ErrorLevel, FatalLevel)
+	assert.Equal(t, []Level{ErrorLevel, FatalLevel}, StackLevels())
+
+	o := out
+	defer SetOutput(o)
+	buf := bytes.NewBuffer(nil)
+	SetOutput(buf)
+
+	Info("no stack")
+	e, err := NewEntry(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Nil(t, e.Attrs()["stack"])
+
+	buf.Reset()
+	Error(errors.New("with stack"))
+	var rec struct {
+		Stack []Frame `json:"stack"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.NotEmpty(t, rec.Stack)
+	assert.Equal(t, "github.com/minus5/svckit/log.TestStackLevels", rec.Stack[0].Func)
+}
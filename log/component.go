@@ -0,0 +1,109 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvLevels sets per component levels, e.g. "svckit.dcy=debug,http=info".
+	EnvLevels = "SVCKIT_LOG_LEVELS"
+)
+
+var (
+	componentMu     sync.RWMutex
+	componentLevels = map[string]Level{}
+)
+
+// Component returns logger for the named component.
+// Component level can be set with SetComponentLevel.
+func Component(name string) *Logger {
+	return With("component", name)
+}
+
+// SetComponentLevel sets minimal level for the component.
+// Component is value of the "lib" or "component" attribute.
+// Level applies to the sub components also: "svckit" matches "svckit.dcy".
+func SetComponentLevel(component string, l Level) {
+	componentMu.Lock()
+	defer componentMu.Unlock()
+	componentLevels[component] = l
+}
+
+// ResetComponentLevel removes component level override.
+func ResetComponentLevel(component string) {
+	componentMu.Lock()
+	defer componentMu.Unlock()
+	delete(componentLevels, component)
+}
+
+// ComponentLevels returns copy of the current component levels.
+func ComponentLevels() map[string]Level {
+	componentMu.RLock()
+	defer componentMu.RUnlock()
+	m := make(map[string]Level, len(componentLevels))
+	for k, v := range componentLevels {
+		m[k] = v
+	}
+	return m
+}
+
+// SetComponentLevels parses and sets component levels
+// in "component=level,component=level" format.
+// Useful for levels stored in env or Consul KV.
+func SetComponentLevels(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid component level %s", part)
+		}
+		l, ok := ParseLevel(kv[1])
+		if !ok {
+			return fmt.Errorf("invalid level %s", kv[1])
+		}
+		SetComponentLevel(strings.TrimSpace(kv[0]), l)
+	}
+	return nil
+}
+
+// componentLevel finds level for the component, longest matching prefix wins.
+func componentLevel(component string) (Level, bool) {
+	componentMu.RLock()
+	defer componentMu.RUnlock()
+	if len(componentLevels) == 0 {
+		return DebugLevel, false
+	}
+	for c := component; c != ""; {
+		if l, ok := componentLevels[c]; ok {
+			return l, true
+		}
+		i := strings.LastIndex(c, ".")
+		if i < 0 {
+			break
+		}
+		c = c[:i]
+	}
+	return DebugLevel, false
+}
+
+// component returns value of the lib or component attribute.
+func (a *Agregator) component() string {
+	for _, atr := range a.attrs {
+		if atr.key == "lib" || atr.key == "component" {
+			return unquote(atr.val)
+		}
+	}
+	return ""
+}
+
+func initComponentLevels() {
+	if e, ok := os.LookupEnv(EnvLevels); ok {
+		SetComponentLevels(e)
+	}
+}
@@ -0,0 +1,26 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentLevels(t *testing.T) {
+	defer SetLevel(GetLevel())
+	assert.Nil(t, SetComponentLevels("svckit=warn, svckit.dcy=debug"))
+	defer ResetComponentLevel("svckit")
+	defer ResetComponentLevel("svckit.dcy")
+	assert.NotNil(t, SetComponentLevels("pero"))
+
+	SetLevel(InfoLevel)
+	buf := &bytes.Buffer{}
+	NewAgregator(buf, 3).S("lib", "svckit.nsq").Info("dropped")
+	assert.Equal(t, 0, buf.Len())
+	NewAgregator(buf, 3).S("lib", "svckit.dcy.sr").Debug("logged")
+	assert.Contains(t, buf.String(), "logged")
+	buf.Reset()
+	NewAgregator(buf, 3).S("lib", "other").Debug("dropped")
+	assert.Equal(t, 0, buf.Len())
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/minus5/svckit/metric"
+)
+
+// RequestIDHeader is http header carrying request id.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+type traceKey struct{}
+
+type traceIDs struct {
+	traceID string
+	spanID  string
+}
+
+var (
+	traceExtractorMu sync.RWMutex
+	traceExtractor   func(context.Context) (traceID, spanID string)
+)
+
+func init() {
+	metric.SetExemplarExtractor(func(ctx context.Context) metric.Exemplar {
+		traceID, spanID := traceFromContext(ctx)
+		return metric.Exemplar{TraceID: traceID, SpanID: spanID}
+	})
+}
+
+// WithRequestID returns context which carries request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns request id from context, empty if not set.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns random request id.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithTrace returns context which carries trace and span ids.
+func WithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceIDs{traceID: traceID, spanID: spanID})
+}
+
+// ParseTraceparent parses W3C traceparent header (version-traceid-spanid-flags).
+func ParseTraceparent(h string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// SetTraceExtractor sets function which finds trace and span ids in context.
+// Used for OpenTelemetry integration, for example:
+//
+//	log.SetTraceExtractor(func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	})
+func SetTraceExtractor(f func(context.Context) (traceID, spanID string)) {
+	traceExtractorMu.Lock()
+	defer traceExtractorMu.Unlock()
+	traceExtractor = f
+}
+
+// traceFromContext returns trace and span ids from extractor or WithTrace.
+func traceFromContext(ctx context.Context) (string, string) {
+	traceExtractorMu.RLock()
+	f := traceExtractor
+	traceExtractorMu.RUnlock()
+	if f != nil {
+		if traceID, spanID := f(ctx); traceID != "" {
+			return traceID, spanID
+		}
+	}
+	t, _ := ctx.Value(traceKey{}).(traceIDs)
+	return t.traceID, t.spanID
+}
+
+// correlation returns trace_id, span_id and request_id fields found in context.
+func correlation(ctx context.Context) []interface{} {
+	var kv []interface{}
+	if traceID, spanID := traceFromContext(ctx); traceID != "" {
+		kv = append(kv, "trace_id", traceID)
+		if spanID != "" {
+			kv = append(kv, "span_id", spanID)
+		}
+	}
+	if id := RequestID(ctx); id != "" {
+		kv = append(kv, "request_id", id)
+	}
+	return kv
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelation(t *testing.T) {
+	o := out
+	defer SetOutput(o)
+	buf := bytes.NewBuffer(nil)
+	SetOutput(buf)
+
+	ctx := WithRequestID(context.Background(), "abc")
+	traceID, spanID, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	ctx = WithTrace(ctx, traceID, spanID)
+	ctx = NewContext(ctx, With("user", 12))
+	FromContext(ctx).Info("msg")
+	assert.Contains(t, buf.String(), `"user":12, "trace_id":"4bf92f3577b34da6a3ce929d0e0e4736", "span_id":"00f067aa0ba902b7", "request_id":"abc", "msg":"msg"`)
+
+	defer SetTraceExtractor(nil)
+	SetTraceExtractor(func(context.Context) (string, string) { return "t1", "" })
+	buf.Reset()
+	FromContext(context.Background()).Info("msg")
+	assert.Contains(t, buf.String(), `"trace_id":"t1", "msg":"msg"`)
+
+	_, _, ok = ParseTraceparent("pero")
+	assert.False(t, ok)
+}
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvDedup sets window for duplicate error suppression (Go duration, e.g. 1m).
+	EnvDedup = "SVCKIT_LOG_DEDUP"
+)
+
+// deduper suppresses identical error records in time window.
+type deduper struct {
+	window time.Duration
+	stop   chan struct{}
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	first      time.Time
+	suppressed int
+	file       string
+	line       int
+	msg        string
+	attrs      []*attr
+}
+
+var (
+	dedupMu sync.RWMutex
+	dedup   *deduper
+)
+
+// SetDedup enables suppression of identical error messages (same message and location).
+// First error is logged, duplicates in window are counted
+// and at the end of the window summary with occurrences field is logged.
+// Set window to 0 to disable.
+func SetDedup(window time.Duration) {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+	if dedup != nil {
+		close(dedup.stop)
+		dedup.flush(time.Time{})
+		dedup = nil
+	}
+	if window <= 0 {
+		return
+	}
+	dedup = &deduper{
+		window:  window,
+		stop:    make(chan struct{}),
+		entries: make(map[string]*dedupEntry),
+	}
+	go dedup.loop()
+}
+
+// duplicate returns true if record is suppressed as duplicate.
+func (a *Agregator) duplicate() bool {
+	if a.summary || levelOf(a.level) != ErrorLevel {
+		return false
+	}
+	dedupMu.RLock()
+	d := dedup
+	dedupMu.RUnlock()
+	if d == nil {
+		return false
+	}
+	key := a.file + ":" + strconv.Itoa(a.line) + " " + a.msg
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.entries[key]; ok {
+		e.suppressed++
+		return true
+	}
+	d.entries[key] = &dedupEntry{first: a.t, file: a.file, line: a.line, msg: a.msg, attrs: a.attrs}
+	return false
+}
+
+func (d *deduper) loop() {
+	ticker := time.NewTicker(d.window / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case t := <-ticker.C:
+			d.flush(t)
+		}
+	}
+}
+
+// flush logs summary for entries with expired window, all entries if t is zero.
+func (d *deduper) flush(t time.Time) {
+	d.mu.Lock()
+	var expired []*dedupEntry
+	for k, e := range d.entries {
+		if t.IsZero() || t.Sub(e.first) >= d.window {
+			delete(d.entries, k)
+			if e.suppressed > 0 {
+				expired = append(expired, e)
+			}
+		}
+	}
+	d.mu.Unlock()
+	for _, e := range expired {
+		a := newAgregator(0)
+		a.summary = true
+		a.file, a.line = e.file, e.line
+		a.attrs = append(a.attrs, e.attrs...)
+		a.I("occurrences", e.suppressed+1)
+		a.ErrorS(e.msg)
+	}
+}
+
+func initDedup() {
+	if d, err := time.ParseDuration(os.Getenv(EnvDedup)); err == nil {
+		SetDedup(d)
+	}
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedup(t *testing.T) {
+	o := out
+	defer SetOutput(o)
+	buf := bytes.NewBuffer(nil)
+	SetOutput(buf)
+	SetDedup(time.Hour)
+	defer SetDedup(0)
+
+	for i := 0; i < 5; i++ {
+		S("key", "val").Error(errors.New("failed"))
+	}
+	Error(errors.New("other"))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	buf.Reset()
+	SetDedup(0)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"key":"val", "occurrences":5, "msg":"failed"`)
+}
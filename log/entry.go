@@ -102,3 +102,8 @@ func (e *Entry) S(key string) (string, bool) {
 		return valStr, ok
 	}
 }
+
+// Attrs returns all attributes except the standard ones (time, host, app, file, level, msg).
+func (e *Entry) Attrs() map[string]interface{} {
+	return e.attr
+}
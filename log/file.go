@@ -0,0 +1,369 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables for file output.
+const (
+	EnvFile           = "SVCKIT_LOG_FILE"             // file path, enables file output
+	EnvFileMaxSize    = "SVCKIT_LOG_FILE_MAX_SIZE"    // in megabytes
+	EnvFileRotate     = "SVCKIT_LOG_FILE_ROTATE"      // time based rotation, duration (e.g. 24h)
+	EnvFileMaxAge     = "SVCKIT_LOG_FILE_MAX_AGE"     // duration (e.g. 168h)
+	EnvFileMaxBackups = "SVCKIT_LOG_FILE_MAX_BACKUPS" // number of rotated files to keep
+	EnvFileCompress   = "SVCKIT_LOG_FILE_COMPRESS"    // gzip rotated files
+	EnvFileShip       = "SVCKIT_LOG_FILE_SHIP"        // http(s) upload url or command for rotated files
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// File is log output to the file with size/time based rotation.
+// Rotated files are renamed to path.<time> and optionally gzipped.
+type File struct {
+	path       string
+	maxSize    int64
+	rotate     time.Duration
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	ship       func(string) error
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	rotateAt time.Time
+	closed   bool
+	cleanup  sync.WaitGroup
+}
+
+// MaxSize rotates file when it grows over size bytes.
+func MaxSize(size int64) func(*File) {
+	return func(f *File) {
+		f.maxSize = size
+	}
+}
+
+// RotateEvery rotates file every interval.
+func RotateEvery(interval time.Duration) func(*File) {
+	return func(f *File) {
+		f.rotate = interval
+	}
+}
+
+// MaxAge removes rotated files older than age.
+func MaxAge(age time.Duration) func(*File) {
+	return func(f *File) {
+		f.maxAge = age
+	}
+}
+
+// MaxBackups keeps at most n rotated files.
+func MaxBackups(n int) func(*File) {
+	return func(f *File) {
+		f.maxBackups = n
+	}
+}
+
+// Compress gzips rotated files.
+func Compress() func(*File) {
+	return func(f *File) {
+		f.compress = true
+	}
+}
+
+// Ship calls fn with path of each rotated (and compressed) file,
+// before retention is applied. Used to push archives to object storage.
+func Ship(fn func(path string) error) func(*File) {
+	return func(f *File) {
+		f.ship = fn
+	}
+}
+
+// ShipCommand runs command with rotated file path as last argument.
+func ShipCommand(name string, args ...string) func(string) error {
+	return func(path string) error {
+		out, err := exec.Command(name, append(args, path)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+}
+
+// ShipHTTP uploads rotated file with PUT to url/<file name>.
+func ShipHTTP(url string) func(string) error {
+	return func(path string) error {
+		fh, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		req, err := http.NewRequest(http.MethodPut, strings.TrimSuffix(url, "/")+"/"+filepath.Base(path), fh)
+		if err != nil {
+			return err
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, rsp.Body)
+		rsp.Body.Close()
+		if rsp.StatusCode/100 != 2 {
+			return fmt.Errorf("upload status %s", rsp.Status)
+		}
+		return nil
+	}
+}
+
+// NewFile opens (appends to) log file at path.
+func NewFile(path string, opts ...func(*File)) (*File, error) {
+	f := &File{path: path}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	fh, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		return err
+	}
+	f.f = fh
+	f.size = fi.Size()
+	if f.rotate > 0 {
+		f.rotateAt = time.Now().Add(f.rotate)
+	}
+	return nil
+}
+
+// Write implements io.Writer.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(len(p)) {
+		if err := f.rotateFile(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.f.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *File) shouldRotate(n int) bool {
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(n) > f.maxSize {
+		return true
+	}
+	return !f.rotateAt.IsZero() && time.Now().After(f.rotateAt)
+}
+
+// Rotate forces file rotation.
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotateFile()
+}
+
+func (f *File) rotateFile() error {
+	if err := f.f.Close(); err != nil {
+		return err
+	}
+	backup := backupName(f.path + "." + time.Now().Format(backupTimeFormat))
+	if err := os.Rename(f.path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+		f.postRotate(backup)
+	}()
+	return nil
+}
+
+// backupName adds sequence suffix to base if backup with that name already exists
+// (more than one rotation in the same millisecond).
+func backupName(base string) string {
+	name := base
+	for seq := 1; exists(name) || exists(name+".gz"); seq++ {
+		name = fmt.Sprintf("%s.%03d", base, seq)
+	}
+	return name
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// postRotate compresses and ships rotated file and applies retention.
+func (f *File) postRotate(backup string) {
+	if f.compress {
+		if err := gzipFile(backup); err != nil {
+			logger().S("file", backup).Error(err)
+		} else {
+			backup += ".gz"
+		}
+	}
+	if f.ship != nil {
+		if err := f.ship(backup); err != nil {
+			logger().S("file", backup).Error(err)
+		}
+	}
+	for _, name := range f.expiredBackups() {
+		os.Remove(name)
+	}
+}
+
+// backups returns rotated files, newest first.
+func (f *File) backups() []string {
+	names, _ := filepath.Glob(f.path + ".*")
+	var bs []string
+	for _, name := range names {
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, f.path+"."), ".gz")
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			bs = append(bs, name)
+			continue
+		}
+		// with sequence suffix
+		if i := strings.LastIndex(ts, "."); i > 0 {
+			if _, err := strconv.Atoi(ts[i+1:]); err == nil {
+				if _, err := time.Parse(backupTimeFormat, ts[:i]); err == nil {
+					bs = append(bs, name)
+				}
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(bs)))
+	return bs
+}
+
+func (f *File) expiredBackups() []string {
+	var expired []string
+	for i, name := range f.backups() {
+		if f.maxBackups > 0 && i >= f.maxBackups {
+			expired = append(expired, name)
+			continue
+		}
+		if f.maxAge > 0 {
+			if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > f.maxAge {
+				expired = append(expired, name)
+			}
+		}
+	}
+	return expired
+}
+
+// Close closes the file, later writes return os.ErrClosed.
+// Waits for compression of rotated files to finish.
+func (f *File) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	err := f.f.Close()
+	f.mu.Unlock()
+	// without lock, cleanup goroutines may log through this file
+	f.cleanup.Wait()
+	return err
+}
+
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// initFile sets file output if EnvFile is defined.
+// fileOutput returns file output if EnvFile is defined.
+func fileOutput() io.Writer {
+	path, ok := os.LookupEnv(EnvFile)
+	if !ok || path == "" {
+		return nil
+	}
+	var opts []func(*File)
+	if e, err := strconv.Atoi(os.Getenv(EnvFileMaxSize)); err == nil && e > 0 {
+		opts = append(opts, MaxSize(int64(e)*1024*1024))
+	}
+	if e, err := time.ParseDuration(os.Getenv(EnvFileRotate)); err == nil && e > 0 {
+		opts = append(opts, RotateEvery(e))
+	}
+	if e, err := time.ParseDuration(os.Getenv(EnvFileMaxAge)); err == nil && e > 0 {
+		opts = append(opts, MaxAge(e))
+	}
+	if e, err := strconv.Atoi(os.Getenv(EnvFileMaxBackups)); err == nil && e > 0 {
+		opts = append(opts, MaxBackups(e))
+	}
+	if e := os.Getenv(EnvFileCompress); e != "" && e != "0" && e != "false" {
+		opts = append(opts, Compress())
+	}
+	if e := os.Getenv(EnvFileShip); e != "" {
+		if strings.HasPrefix(e, "http://") || strings.HasPrefix(e, "https://") {
+			opts = append(opts, Ship(ShipHTTP(e)))
+		} else if args := strings.Fields(e); len(args) > 0 {
+			opts = append(opts, Ship(ShipCommand(args[0], args[1:]...)))
+		}
+	}
+	f, err := NewFile(path, opts...)
+	if err != nil {
+		Error(err)
+		return nil
+	}
+	return f
+}
+
+func logger() *Agregator {
+	return S("lib", "svckit.log")
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewFile(path, MaxSize(10), MaxBackups(1), Compress())
+	assert.Nil(t, err)
+
+	line := []byte("012345678\n")
+	f.Write(line)
+	f.Write(line) // rotates
+	f.cleanup.Wait()
+	f.Write(line) // rotates, removes oldest backup
+	assert.Nil(t, f.Close())
+
+	buf, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, line, buf)
+	bs := f.backups()
+	assert.Equal(t, 1, len(bs))
+	assert.True(t, strings.HasSuffix(bs[0], ".gz"))
+}
+
+func TestFileShip(t *testing.T) {
+	uploads := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		uploads <- r.URL.Path
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := NewFile(path, Compress(), Ship(ShipHTTP(srv.URL+"/logs")))
+	assert.Nil(t, err)
+	f.Write([]byte("line\n"))
+	assert.Nil(t, f.Rotate())
+	assert.Nil(t, f.Close())
+	u := <-uploads
+	assert.True(t, strings.HasPrefix(u, "/logs/app.log."), u)
+	assert.True(t, strings.HasSuffix(u, ".gz"), u)
+
+	assert.Nil(t, ShipCommand("true")(path))
+	assert.Error(t, ShipCommand("false")(path))
+}
+
+func TestFileFastRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := NewFile(path)
+	assert.Nil(t, err)
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("line\n"))
+		assert.Nil(t, f.Rotate())
+	}
+	assert.Nil(t, f.Close())
+	assert.Equal(t, 5, len(f.backups()))
+}
+
+func TestFileCloseWhileCleanupLogs(t *testing.T) {
+	dir := t.TempDir()
+	var f *File
+	release := make(chan struct{})
+	f, err := NewFile(filepath.Join(dir, "app.log"), Ship(func(string) error {
+		<-release
+		// cleanup logging through the same file
+		f.Write([]byte("shipped\n"))
+		return nil
+	}))
+	assert.Nil(t, err)
+	assert.Nil(t, f.Rotate())
+	done := make(chan struct{})
+	go func() {
+		f.Close()
+		close(done)
+	}()
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close deadlock")
+	}
+	_, err = f.Write([]byte("late\n"))
+	assert.Equal(t, os.ErrClosed, err)
+}
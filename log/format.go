@@ -0,0 +1,209 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/minus5/svckit/env"
+)
+
+// Format of the log output.
+type Format int32
+
+// Supported output formats.
+const (
+	FormatJSON Format = iota
+	FormatLogfmt
+	FormatConsole
+)
+
+const (
+	logfmtLayout = "2006-01-02T15:04:05.000000-07:00"
+	// EnvFormat sets output format (json, logfmt, console).
+	EnvFormat = "SVCKIT_LOG_FORMAT"
+)
+
+var format int32 = int32(FormatJSON)
+
+// SetFormat sets output format.
+func SetFormat(f Format) {
+	atomic.StoreInt32(&format, int32(f))
+}
+
+func getFormat() Format {
+	return Format(atomic.LoadInt32(&format))
+}
+
+// ParseFormat converts format name to Format.
+func ParseFormat(s string) (Format, bool) {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON, true
+	case "logfmt":
+		return FormatLogfmt, true
+	case "console":
+		return FormatConsole, true
+	}
+	return FormatJSON, false
+}
+
+// initFormat sets format from env, or console format on developer machine when output is terminal.
+func initFormat() {
+	if e, ok := os.LookupEnv(EnvFormat); ok {
+		if f, ok := ParseFormat(e); ok {
+			SetFormat(f)
+		}
+		return
+	}
+	if env.InDev() && isTerminal(out) {
+		SetFormat(FormatConsole)
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// unquote returns plain value of the json encoded attribute value.
+func unquote(val string) string {
+	if strings.HasPrefix(val, `"`) {
+		if s, err := strconv.Unquote(val); err == nil {
+			return s
+		}
+		return strings.Trim(val, `"`)
+	}
+	return val
+}
+
+// logfmtValue quotes value if it contains spaces, quotes or equal signs.
+func logfmtValue(val string) string {
+	if val == "" || strings.ContainsAny(val, " \"=\t\n") {
+		return strconv.Quote(val)
+	}
+	return val
+}
+
+func (a *Agregator) logfmtKV(key, val string) {
+	if len(*a.buf) > 0 {
+		*a.buf = append(*a.buf, ' ')
+	}
+	*a.buf = append(*a.buf, key...)
+	*a.buf = append(*a.buf, '=')
+	*a.buf = append(*a.buf, logfmtValue(val)...)
+}
+
+// logfmt encodes record in logfmt format (https://brandur.org/logfmt).
+func (a *Agregator) logfmt() {
+	a.logfmtKV("time", a.t.Format(logfmtLayout))
+	a.logfmtKV("level", unquote(a.level))
+	if a.file != "" {
+		a.logfmtKV("file", a.file+":"+strconv.Itoa(a.line))
+	}
+	if len(prefix) > 0 {
+		a.logfmtKV("host", hostname)
+		a.logfmtKV("app", appName)
+	}
+	for _, atr := range a.attrs {
+		a.logfmtKV(atr.key, unquote(atr.val))
+	}
+	a.logfmtKV("msg", unquote(a.msg))
+	*a.buf = append(*a.buf, '\n')
+}
+
+// ansi color codes
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorGray   = "\033[90m"
+)
+
+func levelColor(l Level) string {
+	switch l {
+	case DebugLevel:
+		return colorGray
+	case InfoLevel:
+		return colorGreen
+	case NoticeLevel:
+		return colorBlue
+	case WarnLevel:
+		return colorYellow
+	}
+	return colorRed
+}
+
+const (
+	// message column width in console format, attributes after it are aligned
+	consoleMsgWidth = 40
+	// longer attribute values are truncated in console format
+	consoleMaxValue = 120
+)
+
+// console encodes record in human readable colored format.
+// Multi-line messages and values, and stack traces are rendered in indented lines after the record.
+func (a *Agregator) console() {
+	level := strings.ToUpper(unquote(a.level))
+	lines := strings.Split(unquote(a.msg), "\n")
+	*a.buf = append(*a.buf, a.t.Format("15:04:05.000")...)
+	*a.buf = append(*a.buf, ' ')
+	*a.buf = append(*a.buf, levelColor(levelOf(a.level))...)
+	*a.buf = append(*a.buf, level...)
+	*a.buf = append(*a.buf, colorReset...)
+	*a.buf = append(*a.buf, strings.Repeat(" ", 6-len(level))...)
+	*a.buf = append(*a.buf, lines[0]...)
+	var more []string
+	var stack []Frame
+	for i, atr := range a.attrs {
+		if i == 0 && len(lines[0]) < consoleMsgWidth {
+			*a.buf = append(*a.buf, strings.Repeat(" ", consoleMsgWidth-len(lines[0]))...)
+		}
+		val := unquote(atr.val)
+		if atr.key == "stack" && json.Unmarshal([]byte(atr.val), &stack) == nil {
+			continue
+		}
+		if strings.Contains(val, "\n") {
+			more = append(more, atr.key+":")
+			for _, l := range strings.Split(val, "\n") {
+				more = append(more, "  "+l)
+			}
+			continue
+		}
+		if len(val) > consoleMaxValue {
+			val = val[:consoleMaxValue] + "..."
+		}
+		*a.buf = append(*a.buf, ' ')
+		*a.buf = append(*a.buf, colorGray...)
+		*a.buf = append(*a.buf, atr.key...)
+		*a.buf = append(*a.buf, '=')
+		*a.buf = append(*a.buf, colorReset...)
+		*a.buf = append(*a.buf, val...)
+	}
+	if a.file != "" {
+		*a.buf = append(*a.buf, ' ')
+		*a.buf = append(*a.buf, colorGray...)
+		*a.buf = append(*a.buf, a.file...)
+		*a.buf = append(*a.buf, ':')
+		*a.buf = append(*a.buf, strconv.Itoa(a.line)...)
+		*a.buf = append(*a.buf, colorReset...)
+	}
+	more = append(lines[1:], more...)
+	for _, f := range stack {
+		more = append(more, f.Func, "  "+f.File+":"+strconv.Itoa(f.Line))
+	}
+	for _, l := range more {
+		*a.buf = append(*a.buf, "\n    "...)
+		*a.buf = append(*a.buf, l...)
+	}
+	*a.buf = append(*a.buf, '\n')
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmt(t *testing.T) {
+	defer SetFormat(FormatJSON)
+	SetFormat(FormatLogfmt)
+	a := newTestAgregator()
+	a.I("keyi", 123).S("key", "val with space").Info("msg")
+	assert.Equal(t, `time=2009-11-10T23:05:06.000000+00:00 level=info file=main.go:123 keyi=123 key="val with space" msg=msg`, currentBuffer(a))
+}
+
+func TestConsole(t *testing.T) {
+	defer SetFormat(FormatJSON)
+	SetFormat(FormatConsole)
+	a := newTestAgregator()
+	a.S("key", "val").Warn("msg")
+	assert.Equal(t, "23:05:06.000 "+colorYellow+"WARN"+colorReset+"  msg"+strings.Repeat(" ", 37)+" "+colorGray+"key="+colorReset+"val "+colorGray+"main.go:123"+colorReset, currentBuffer(a))
+
+	a = newTestAgregator()
+	a.S("long", strings.Repeat("x", 200)).S("body", "line1\nline2").J("stack", []byte(`[{"func":"main.main","file":"/app/main.go","line":12}]`)).ErrorS("failed\ndetails")
+	lines := strings.Split(currentBuffer(a), "\n")
+	assert.Len(t, lines, 7)
+	assert.Contains(t, lines[0], strings.Repeat("x", 120)+"...")
+	assert.NotContains(t, lines[0], "line1")
+	assert.Equal(t, []string{"    details", "    body:", "      line1", "      line2", "    main.main", "      /app/main.go:12"}, lines[1:])
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// EnvAdminToken is token required by HttpHandler.
+	EnvAdminToken = "SVCKIT_LOG_ADMIN_TOKEN"
+)
+
+// httpConfig is runtime log configuration exposed by HttpHandler.
+type httpConfig struct {
+	Level      string            `json:"level,omitempty"`
+	Components map[string]string `json:"components,omitempty"`
+	Sampling   *struct {
+		First      int `json:"first"`
+		Thereafter int `json:"thereafter"`
+	} `json:"sampling,omitempty"`
+}
+
+// HttpHandler returns handler for querying (GET) and changing (PUT, POST)
+// log level, component levels and sampling at runtime.
+// Requests must have "Authorization: Bearer <token>" header.
+// If token is empty all requests are forbidden.
+//
+// Example of change request body:
+//
+//	{"level":"debug", "components":{"svckit.dcy":"info"}, "sampling":{"first":5,"thereafter":100}}
+//
+// Component with empty level removes override.
+func HttpHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var c httpConfig
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := c.apply(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger().J("config", mustJSON(c)).Notice("log config changed")
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(mustJSON(currentConfig()))
+	}
+}
+
+func (c httpConfig) apply() error {
+	var level Level
+	if c.Level != "" {
+		var ok bool
+		if level, ok = ParseLevel(c.Level); !ok {
+			return errInvalidLevel(c.Level)
+		}
+	}
+	cls := map[string]*Level{}
+	for comp, ls := range c.Components {
+		if ls == "" {
+			cls[comp] = nil
+			continue
+		}
+		l, ok := ParseLevel(ls)
+		if !ok {
+			return errInvalidLevel(ls)
+		}
+		cls[comp] = &l
+	}
+	// validated, apply
+	if c.Level != "" {
+		SetLevel(level)
+	}
+	for comp, l := range cls {
+		if l == nil {
+			ResetComponentLevel(comp)
+			continue
+		}
+		SetComponentLevel(comp, *l)
+	}
+	if c.Sampling != nil {
+		SetSampling(c.Sampling.First, c.Sampling.Thereafter)
+	}
+	return nil
+}
+
+func currentConfig() httpConfig {
+	c := httpConfig{
+		Level:      GetLevel().String(),
+		Components: map[string]string{},
+	}
+	for comp, l := range ComponentLevels() {
+		c.Components[comp] = l.String()
+	}
+	first, thereafter := Sampling()
+	c.Sampling = &struct {
+		First      int `json:"first"`
+		Thereafter int `json:"thereafter"`
+	}{first, thereafter}
+	return c
+}
+
+type errInvalidLevel string
+
+func (e errInvalidLevel) Error() string {
+	return "invalid level " + string(e)
+}
+
+func mustJSON(v interface{}) []byte {
+	buf, _ := json.Marshal(v)
+	return buf
+}
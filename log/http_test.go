@@ -0,0 +1,35 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpHandler(t *testing.T) {
+	defer SetLevel(GetLevel())
+	defer SetSampling(0, 0)
+	defer ResetComponentLevel("svckit.dcy")
+	h := HttpHandler("secret")
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/debug/log", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	req := httptest.NewRequest("PUT", "/debug/log", strings.NewReader(`{"level":"warn","components":{"svckit.dcy":"debug"},"sampling":{"first":5,"thereafter":100}}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"level":"warn","components":{"svckit.dcy":"debug"},"sampling":{"first":5,"thereafter":100}}`, rec.Body.String())
+	assert.Equal(t, WarnLevel, GetLevel())
+
+	req = httptest.NewRequest("PUT", "/debug/log", strings.NewReader(`{"level":"pero"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
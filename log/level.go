@@ -0,0 +1,131 @@
+package log
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/minus5/svckit/env"
+)
+
+// Level is minimal level of messages which are logged.
+type Level int32
+
+// Log levels in increasing order of importance.
+// Event messages are logged at NoticeLevel.
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	NoticeLevel
+	WarnLevel
+	ErrorLevel
+	FatalLevel
+)
+
+const (
+	// EnvLevel sets minimal log level (debug, info, notice, warn, error).
+	EnvLevel = "SVCKIT_LOG_LEVEL"
+)
+
+var minLevel int32 = int32(DebugLevel)
+
+// SetLevel sets global minimal log level.
+// Can be changed at runtime.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&minLevel, int32(l))
+}
+
+// GetLevel returns global minimal log level.
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&minLevel))
+}
+
+// String returns unquoted level name.
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return LevelDebugUnquoted
+	case InfoLevel:
+		return LevelInfoUnquoted
+	case NoticeLevel:
+		return LevelNoticeUnquoted
+	case WarnLevel:
+		return LevelWarnUnquoted
+	case ErrorLevel:
+		return LevelErrorUnquoted
+	}
+	return LevelFatalUnquoted
+}
+
+// ParseLevel converts level name to Level.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(strings.Trim(s, `"`)) {
+	case LevelDebugUnquoted:
+		return DebugLevel, true
+	case LevelInfoUnquoted:
+		return InfoLevel, true
+	case LevelNoticeUnquoted, LevelEventUnquoted:
+		return NoticeLevel, true
+	case LevelWarnUnquoted, "warning":
+		return WarnLevel, true
+	case LevelErrorUnquoted:
+		return ErrorLevel, true
+	case LevelFatalUnquoted:
+		return FatalLevel, true
+	}
+	return DebugLevel, false
+}
+
+// levelOf returns Level for quoted level string used in Agregator.
+func levelOf(quoted string) Level {
+	l, _ := ParseLevel(quoted)
+	return l
+}
+
+func enabled(l Level) bool {
+	return l >= GetLevel()
+}
+
+func initLogLevel() {
+	environmentDefaults(env.Environment())
+	env.OnEnvironment(environmentDefaults)
+	if e, ok := os.LookupEnv(EnvLevel); ok {
+		if l, ok := ParseLevel(e); ok {
+			SetLevel(l)
+		}
+	}
+	e, ok := os.LookupEnv(EnvDisableDebug)
+	if !ok || (e == "0") || (e == "false") || (e == "") {
+		return
+	}
+	DisableDebug()
+}
+
+// DisableDebug do not log Debug messages
+func DisableDebug() {
+	if GetLevel() < InfoLevel {
+		SetLevel(InfoLevel)
+	}
+}
+
+// environmentDefaults adjusts level and sampling to environment class,
+// unless they are set by environment variables or sampling is already enabled:
+// prod is info level with sampling of repeated messages, staging is info level, dev is debug.
+func environmentDefaults(e string) {
+	_, level := os.LookupEnv(EnvLevel)
+	_, disableDebug := os.LookupEnv(EnvDisableDebug)
+	if !level && !disableDebug {
+		switch e {
+		case env.Prod, env.Staging:
+			SetLevel(InfoLevel)
+		case env.Dev:
+			SetLevel(DebugLevel)
+		}
+	}
+	if first, _ := Sampling(); first == 0 && e == env.Prod {
+		SetSampling(defaultProdSampling, defaultProdSampling)
+	}
+}
+
+// defaultProdSampling is number of same messages per second logged in prod, and rate after that.
+const defaultProdSampling = 100
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevel(t *testing.T) {
+	defer SetLevel(GetLevel())
+	buf := &bytes.Buffer{}
+	a := func() *Agregator { return NewAgregator(buf, 3) }
+
+	SetLevel(WarnLevel)
+	a().Info("info")
+	a().Notice("notice")
+	assert.Equal(t, 0, buf.Len())
+	a().Warn("warn")
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+
+	buf.Reset()
+	a().Level(DebugLevel).Debug("debug")
+	assert.Contains(t, buf.String(), `"level":"debug"`)
+
+	buf.Reset()
+	SetLevel(DebugLevel)
+	a().Level(ErrorLevel).Info("info")
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestParseLevel(t *testing.T) {
+	l, ok := ParseLevel("warning")
+	assert.True(t, ok)
+	assert.Equal(t, WarnLevel, l)
+	l, ok = ParseLevel(LevelEvent)
+	assert.True(t, ok)
+	assert.Equal(t, NoticeLevel, l)
+	_, ok = ParseLevel("pero")
+	assert.False(t, ok)
+	assert.Equal(t, "error", ErrorLevel.String())
+}
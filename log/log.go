@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"log/syslog"
 	"os"
+	"strings"
+	"sync"
 	"github.com/minus5/svckit/env"
 
 	golog "log"
@@ -13,22 +15,20 @@ import (
 
 const (
 	SyslogServiceName = "syslog"
-	EnvSyslog         = "SVCKIT_LOG_SYSLOG"
+	EnvSyslog         = "SVCKIT_LOG_SYSLOG" // udp address, or tcp://addr, tls://addr
 	EnvDisableDebug   = "SVCKIT_LOG_DISABLE_DEBUG"
 )
 
 var (
-	out                  io.Writer
-	prefix               []byte
-	debugLogLevelEnabled = true
+	out      io.Writer
+	prefix   []byte
+	hostname string
+	appName  string
 )
 
 type stdLibOutput struct{}
 
 func (o *stdLibOutput) Write(p []byte) (int, error) {
-	if !debugLogLevelEnabled {
-		return len(p), nil
-	}
 	if len(p) > 0 {
 		//izbaci zadnji znak (\n)
 		p = p[0 : len(p)-1]
@@ -43,7 +43,6 @@ func (o *stdLibOutput) Write(p []byte) (int, error) {
 }
 
 /* TODO
-- opcija da iskljuci logiranje file-a
 - preusmjeri na syslog
 - pazi na keys koji su vec zauzeti kada netko pokusava u njih upisati nesto, dodaj im neki prefix (reserved keys)
 - sync.Pool iskoristi da ima vise buffera pa da ne mora nikada raditi lock
@@ -52,49 +51,85 @@ func init() {
 	out = os.Stderr
 
 	//prefix za sve logove
-	p := fmt.Sprintf(`"host":"%s", "app":"%s"`, env.Hostname(), env.AppName())
+	hostname, appName = env.Hostname(), env.AppName()
+	p := fmt.Sprintf(`"host":"%s", "app":"%s"`, hostname, appName)
 	prefix = []byte(p)
 
 	// preusmjeri go standard lib logger kroz mene
 	golog.SetFlags(0)
 	golog.SetOutput(&stdLibOutput{})
-	initSyslog()
+	initOutput()
+	initAsync()
 	initLogLevel()
+	initComponentLevels()
+	initFormat()
+	initCaller()
+	initDedup()
+	initAudit()
+}
+
+var appStart sync.Once
+
+// AppStart logs application start with build metadata, only on the first call.
+// It is called by httpi.Start and svckit.Start, after the application has configured logging.
+func AppStart() {
+	appStart.Do(func() {
+		if env.InTest() {
+			return
+		}
+		b := env.Build()
+		S("lib", "svckit.log").S("version", b.Version).S("commit", b.Commit).
+			S("build_time", b.BuildTime).S("go_version", b.GoVersion).Info("start")
+	})
+}
+
+// initOutput sets output from all configured outputs (syslog, file, sinks and loki).
+// Syslog, file and sinks replace default stderr, loki is added to them.
+func initOutput() {
+	var sinks []TeeSink
+	for _, w := range []io.Writer{syslogOutput(), fileOutput()} {
+		if w != nil {
+			sinks = append(sinks, TeeSink{W: w})
+		}
+	}
+	sinks = append(sinks, sinksOutput()...)
+	if len(sinks) == 0 {
+		sinks = append(sinks, TeeSink{W: os.Stderr})
+	}
+	if w := lokiOutput(); w != nil {
+		sinks = append(sinks, TeeSink{W: w})
+	}
+	if len(sinks) == 1 && sinks[0].Level == DebugLevel {
+		SetOutput(sinks[0].W)
+		return
+	}
+	SetOutput(NewTee(sinks...))
 }
 
-func initSyslog() {
+// syslogOutput returns syslog output if EnvSyslog is defined.
+func syslogOutput() io.Writer {
 	env, ok := os.LookupEnv(EnvSyslog)
 	if !ok || (env == "0") || (env == "false") {
-		return
+		return nil
 	}
-	if len(env) > 5 {
-		setSyslogOutput(env)
-		return
+	w, err := newSyslogWriter(env)
+	if err != nil {
+		return nil
 	}
-
-	setSyslogOutput("127.0.0.1:514")
+	return w
 }
 
-func initLogLevel() {
-	env, ok := os.LookupEnv(EnvDisableDebug)
-	if !ok || (env == "0") || (env == "false") || (env == "") {
-		return
+// newSyslogWriter creates syslog output for udp address, or tcp://addr, tls://addr.
+// Default is udp 127.0.0.1:514.
+func newSyslogWriter(addr string) (io.Writer, error) {
+	if strings.HasPrefix(addr, "tcp://") || strings.HasPrefix(addr, "tls://") {
+		return NewSyslog(addr[:3], addr[6:], nil)
 	}
-	DisableDebug()
-}
-
-// DisableDebug do not log Debug messages
-func DisableDebug() {
-	debugLogLevelEnabled = false
-}
-
-func setSyslogOutput(addr string) {
-	sys, err := syslog.Dial("udp", addr, syslog.LOG_LOCAL5, env.AppName())
-	if err != nil {
-		//For udp err is not raised if server don't exists.
-		return
+	if len(addr) <= 5 {
+		addr = "127.0.0.1:514"
 	}
-	SetOutput(sys)
+	//For udp err is not raised if server don't exists.
+	return syslog.Dial("udp", addr, syslog.LOG_LOCAL5, env.AppName())
 }
 
 //SetOutput sets output for logs.
@@ -103,16 +138,28 @@ func SetOutput(o io.Writer) {
 	out = o
 }
 
+// AddOutput adds output alongside existing ones.
+// Outputs are combined in Tee, so Flush and Close reach all of them.
+func AddOutput(o io.Writer) {
+	var sinks []TeeSink
+	if t, ok := out.(*Tee); ok {
+		sinks = append(sinks, t.sinks...)
+	} else {
+		sinks = append(sinks, TeeSink{W: out})
+	}
+	SetOutput(NewTee(append(sinks, TeeSink{W: o})...))
+}
+
 func Discard() {
 	SetOutput(ioutil.Discard)
 	golog.SetOutput(ioutil.Discard)
 }
 
 func Printf(format string, v ...interface{}) {
-	if !debugLogLevelEnabled {
+	level, msg := splitLevelMessage(format)
+	if !enabled(levelOf(level)) {
 		return
 	}
-	level, msg := splitLevelMessage(format)
 	a := newAgregator(3)
 	a.level = level
 	a.msg = sprintf(msg, v...)
@@ -127,6 +174,10 @@ func Info(msg string, v ...interface{}) {
 	newAgregator(4).Info(sprintf(msg, v...))
 }
 
+func Warn(msg string, v ...interface{}) {
+	newAgregator(4).Warn(sprintf(msg, v...))
+}
+
 func Error(err error) {
 	newAgregator(4).Error(err)
 }
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"context"
+	"fmt"
+)
+
+// Logger is logger with inherited fields.
+// Each log call creates new Agregator with logger fields.
+//
+// Example:
+//
+//	l := log.With("request_id", id, "user", user)
+//	ctx = log.NewContext(ctx, l)
+//	...
+//	log.FromContext(ctx).S("key", "val").Info("msg")
+type Logger struct {
+	attrs    []*attr
+	minLevel *Level
+}
+
+type ctxKey struct{}
+
+// With returns logger with fields given as key value pairs.
+func With(kv ...interface{}) *Logger {
+	return (&Logger{}).With(kv...)
+}
+
+// With returns child logger with additional fields given as key value pairs.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	c := &Logger{minLevel: l.minLevel}
+	c.attrs = append(c.attrs, l.attrs...)
+	a := &Agregator{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		a.field(key, kv[i+1])
+	}
+	c.attrs = append(c.attrs, a.attrs...)
+	return c
+}
+
+// Level returns child logger with minimal level, overrides global level.
+func (l *Logger) Level(lvl Level) *Logger {
+	c := &Logger{attrs: l.attrs, minLevel: &lvl}
+	return c
+}
+
+// NewContext returns context which carries logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns logger from context,
+// or logger without fields if context doesn't carry one.
+// Trace, span and request ids found in context are added as
+// trace_id, span_id and request_id fields.
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(ctxKey{}).(*Logger)
+	if !ok {
+		l = &Logger{}
+	}
+	if kv := correlation(ctx); len(kv) > 0 {
+		return l.With(kv...)
+	}
+	return l
+}
+
+func (l *Logger) agregator(callerDepth int) *Agregator {
+	a := newAgregator(callerDepth)
+	a.attrs = append(a.attrs, l.attrs...)
+	a.minLevel = l.minLevel
+	return a
+}
+
+// field adds attribute of any type.
+func (a *Agregator) field(key string, val interface{}) *Agregator {
+	switch v := val.(type) {
+	case string:
+		return a.S(key, v)
+	case int:
+		return a.I(key, v)
+	case int64:
+		return a.I(key, int(v))
+	case float64:
+		return a.F(key, v, -1)
+	case bool:
+		return a.B(key, v)
+	case []byte:
+		return a.Jc(key, v)
+	case error:
+		return a.S(key, v.Error())
+	case fmt.Stringer:
+		return a.S(key, v.String())
+	}
+	return a.S(key, fmt.Sprintf("%v", val))
+}
+
+func (l *Logger) I(key string, val int) *Agregator {
+	return l.agregator(3).I(key, val)
+}
+
+func (l *Logger) F(key string, val float64, prec int) *Agregator {
+	return l.agregator(3).F(key, val, prec)
+}
+
+func (l *Logger) S(key string, val string) *Agregator {
+	return l.agregator(3).S(key, val)
+}
+
+func (l *Logger) B(key string, val bool) *Agregator {
+	return l.agregator(3).B(key, val)
+}
+
+func (l *Logger) J(key string, val []byte) *Agregator {
+	return l.agregator(3).J(key, val)
+}
+
+func (l *Logger) Jc(key string, val []byte) *Agregator {
+	return l.agregator(3).Jc(key, val)
+}
+
+func (l *Logger) Debug(msg string, v ...interface{}) {
+	l.agregator(4).Debug(sprintf(msg, v...))
+}
+
+func (l *Logger) Info(msg string, v ...interface{}) {
+	l.agregator(4).Info(sprintf(msg, v...))
+}
+
+func (l *Logger) Notice(msg string, v ...interface{}) {
+	l.agregator(4).Notice(sprintf(msg, v...))
+}
+
+func (l *Logger) Event(msg string, v ...interface{}) {
+	l.agregator(4).Event(sprintf(msg, v...))
+}
+
+func (l *Logger) Warn(msg string, v ...interface{}) {
+	l.agregator(4).Warn(sprintf(msg, v...))
+}
+
+func (l *Logger) Error(err error) {
+	l.agregator(4).Error(err)
+}
+
+func (l *Logger) Errorf(msg string, v ...interface{}) {
+	l.agregator(4).Error(fmt.Errorf(msg, v...))
+}
+
+func (l *Logger) Fatal(err error) {
+	l.agregator(4).Fatal(err)
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := out
+	SetOutput(buf)
+	defer SetOutput(o)
+	prefix = []byte{}
+
+	l := With("request_id", "abc", "user", 12)
+	ctx := NewContext(context.Background(), l.With("err", errors.New("pero")))
+	FromContext(ctx).S("key", "val").Info("msg")
+	line := buf.String()
+	assert.Contains(t, line, `"level":"info", "request_id":"abc", "user":12, "err":"pero", "key":"val", "msg":"msg"}`)
+	assert.Contains(t, line, `"file":"logger_test.go:`)
+
+	buf.Reset()
+	FromContext(ctx).Warn("msg %d", 1)
+	assert.Contains(t, buf.String(), `"file":"logger_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"msg 1"`)
+
+	buf.Reset()
+	FromContext(context.Background()).Level(ErrorLevel).Info("msg")
+	assert.Equal(t, 0, buf.Len())
+}
@@ -0,0 +1,82 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/env"
+)
+
+const (
+	// EnvLoki is Loki base url (e.g. http://loki:3100), enables Loki output.
+	EnvLoki = "SVCKIT_LOG_LOKI"
+
+	lokiPushPath = "/loki/api/v1/push"
+)
+
+// Loki is output to the Grafana Loki push api.
+// Lines are batched and pushed from background goroutine,
+// labeled with app, dc and node.
+type Loki struct {
+	*BatchWriter
+	url    string
+	client *http.Client
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLoki creates Loki output pushing to Loki at url.
+func NewLoki(url string) *Loki {
+	l := &Loki{
+		url:    strings.TrimSuffix(url, "/") + lokiPushPath,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	l.BatchWriter = NewBatchWriter(l.push)
+	return l
+}
+
+func (l *Loki) push(batch []BatchLine) error {
+	stream := lokiStream{
+		Stream: map[string]string{
+			"app":  env.AppName(),
+			"dc":   env.Dc(),
+			"node": env.NodeName(),
+		},
+	}
+	for _, bl := range batch {
+		line := strings.TrimSuffix(string(bl.Line), "\n")
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(bl.Time.UnixNano(), 10), line})
+	}
+	body, err := json.Marshal(map[string][]lokiStream{"streams": {stream}})
+	if err != nil {
+		return err
+	}
+	rsp, err := l.client.Post(l.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("loki status %s", rsp.Status)
+	}
+	return nil
+}
+
+// lokiOutput returns Loki output if EnvLoki is defined.
+func lokiOutput() io.Writer {
+	url, ok := os.LookupEnv(EnvLoki)
+	if !ok || url == "" {
+		return nil
+	}
+	return NewLoki(url)
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoki(t *testing.T) {
+	var streams map[string][]lokiStream
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, lokiPushPath, r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&streams)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	l := NewLoki(srv.URL)
+	l.Write([]byte("line1\n"))
+	l.Write([]byte("line2\n"))
+	l.Flush()
+	assert.Equal(t, 1, len(streams["streams"]))
+	s := streams["streams"][0]
+	assert.Equal(t, appName, s.Stream["app"])
+	assert.Equal(t, 2, len(s.Values))
+	assert.Equal(t, "line2", s.Values[1][1])
+
+	// buffered lines are pushed on close, later writes are dropped
+	l.Write([]byte("line3\n"))
+	assert.Nil(t, l.Close())
+	assert.Equal(t, "line3", streams["streams"][0].Values[0][1])
+	l.Write([]byte("line4\n"))
+	assert.Nil(t, l.Close())
+}
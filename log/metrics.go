@@ -0,0 +1,36 @@
+package log
+
+import (
+	"time"
+
+	"github.com/minus5/svckit/metric"
+)
+
+// Logging self metrics, sent through the metric package:
+//
+//	log.written.<level>    counter of written records
+//	log.dropped.<reason>   counter of dropped records (sampling, async, syslog, batch)
+//	log.sink_error.<sink>  counter of failed writes (output, hook, batch)
+//	log.flush              flush latency
+const (
+	metricWritten   = "log.written."
+	metricDropped   = "log.dropped."
+	metricSinkError = "log.sink_error."
+	metricFlush     = "log.flush"
+)
+
+func countWritten(l Level) {
+	metric.Counter(metricWritten + l.String())
+}
+
+func countDropped(reason string) {
+	metric.Counter(metricDropped + reason)
+}
+
+func countSinkError(sink string) {
+	metric.Counter(metricSinkError + sink)
+}
+
+func timeFlush(start time.Time) {
+	metric.Time(metricFlush, int(time.Since(start).Nanoseconds()))
+}
@@ -0,0 +1,43 @@
+package log
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMetric struct {
+	metric.Noop
+	mu       sync.Mutex
+	counters map[string]int
+}
+
+func (m *testMetric) Counter(name string, values ...int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name]++
+}
+
+func TestSelfMetrics(t *testing.T) {
+	m := &testMetric{counters: map[string]int{}}
+	metric.Set(m)
+	defer metric.Set(metric.NewNoop())
+
+	newTestAgregator().Info("msg")
+	newTestAgregator().Info("msg")
+	a := newTestAgregator()
+	a.output = errWriter{}
+	a.Warn("msg")
+
+	assert.Equal(t, 2, m.counters["log.written.info"])
+	assert.Equal(t, 1, m.counters["log.sink_error.output"])
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("failed")
+}
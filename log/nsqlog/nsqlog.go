@@ -0,0 +1,145 @@
+// Package nsqlog publishes log records to the NSQ topic.
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/log/nsqlog"
+//	err := nsqlog.Dial("log")
+//
+// Each record is published as json message with time, level, file, host, app, msg and record attributes.
+// Nsqd is discovered in Consul, or address can be given explicitly:
+//
+//	err := nsqlog.Dial("log", "127.0.0.1:4150")
+package nsqlog
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/nsq"
+	"github.com/minus5/svckit/signal"
+)
+
+// Sink is log hook which publishes records to the NSQ topic.
+type Sink struct {
+	*log.BatchWriter
+	producer *nsq.Producer
+}
+
+// Dial creates sink for topic and registers it as log hook for all levels.
+//
+//	opts[0] - nsqd tcp address, if not set it is discovered in Consul
+func Dial(topic string, opts ...string) (*Sink, error) {
+	addr := ""
+	if len(opts) > 0 {
+		addr = opts[0]
+	} else {
+		err := signal.WithExponentialBackoff(func() error {
+			a, err := dcy.Service(nsq.NsqdTCPServiceName)
+			addr = a.String()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	p, err := nsq.NewProducer(topic, nsq.NsqdTCPAddr(addr))
+	if err != nil {
+		return nil, err
+	}
+	s := New(p)
+	log.AddHook(s)
+	logger().S("addr", addr).S("topic", topic).Info("connected")
+	return s, nil
+}
+
+// New creates sink which publishes with producer.
+func New(p *nsq.Producer) *Sink {
+	s := &Sink{producer: p}
+	s.BatchWriter = log.NewBatchWriter(s.push)
+	return s
+}
+
+// Fire implements log.Hook.
+// Records from nsq library are skipped to avoid feedback loop.
+func (s *Sink) Fire(r log.Record) error {
+	if lib := r.Attrs["lib"]; strings.HasPrefix(lib, "svckit.nsq") || lib == "svckit.log.nsqlog" {
+		return nil
+	}
+	buf, err := json.Marshal(message(r))
+	if err != nil {
+		return err
+	}
+	_, err = s.Write(buf)
+	return err
+}
+
+// message converts record to json object.
+// Attribute values which are json numbers, objects or arrays are kept raw.
+func message(r log.Record) map[string]interface{} {
+	m := make(map[string]interface{}, len(r.Attrs)+6)
+	for k, v := range r.Attrs {
+		m[k] = value(v)
+	}
+	m["time"] = r.Time
+	m["level"] = r.Level.String()
+	if r.File != "" {
+		m["file"] = r.File + ":" + strconv.Itoa(r.Line)
+	}
+	m["host"] = env.Hostname()
+	m["app"] = env.AppName()
+	m["msg"] = r.Msg
+	return m
+}
+
+func value(v string) interface{} {
+	if v == "" {
+		return v
+	}
+	switch v[0] {
+	case '{', '[', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		if json.Valid([]byte(v)) {
+			return json.RawMessage(v)
+		}
+	}
+	return v
+}
+
+func (s *Sink) push(batch []log.BatchLine) error {
+	msgs := make([][]byte, 0, len(batch))
+	for _, bl := range batch {
+		msgs = append(msgs, bl.Line)
+	}
+	return s.producer.MultiPublish(msgs)
+}
+
+// Close flushes buffered records and stops producer.
+func (s *Sink) Close() error {
+	s.BatchWriter.Close()
+	s.producer.Close()
+	return nil
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.log.nsqlog")
+}
+
+// AuditSink is audit output which publishes each record synchronously, returning after nsqd acknowledges it.
+//
+//	log.SetAuditSink(nsqlog.AuditSink{Producer: nsq.Pub("audit")})
+type AuditSink struct {
+	Producer *nsq.Producer
+}
+
+// Write publishes p.
+func (s AuditSink) Write(p []byte) (int, error) {
+	msg := make([]byte, len(p))
+	copy(msg, p)
+	if err := s.Producer.Publish(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
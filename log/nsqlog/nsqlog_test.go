@@ -0,0 +1,32 @@
+package nsqlog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage(t *testing.T) {
+	r := log.Record{
+		Time:  time.Date(2009, 11, 10, 23, 5, 6, 0, time.UTC),
+		Level: log.WarnLevel,
+		Msg:   "msg",
+		File:  "main.go",
+		Line:  12,
+		Attrs: map[string]string{"i": "12", "s": "val", "j": `{"a":1}`, "msg": "x"},
+	}
+	buf, err := json.Marshal(message(r))
+	assert.NoError(t, err)
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf, &m))
+	assert.Equal(t, "warn", m["level"])
+	assert.Equal(t, "main.go:12", m["file"])
+	assert.Equal(t, "msg", m["msg"])
+	assert.Equal(t, float64(12), m["i"])
+	assert.Equal(t, "val", m["s"])
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, m["j"])
+	assert.Equal(t, "2009-11-10T23:05:06Z", m["time"])
+}
@@ -0,0 +1,220 @@
+// Package otlp exports logs to the OpenTelemetry collector over OTLP/HTTP (json encoding).
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/log/otlp"
+//	err := otlp.Dial()
+//
+// Collector is discovered in Consul, or address can be given explicitly:
+//
+//	err := otlp.Dial("127.0.0.1:4318")
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/signal"
+)
+
+const (
+	// CollectorServiceName is collector OTLP/HTTP service name in service discovery.
+	CollectorServiceName = "otel-collector-http"
+
+	logsPath = "/v1/logs"
+)
+
+// Exporter is log hook which sends records to the collector.
+type Exporter struct {
+	*log.BatchWriter
+	url    string
+	client *http.Client
+}
+
+// Dial creates exporter and adds it to the log hooks (see log.AddHook).
+//
+//	opts[0] - collector address, if not set it is discovered in Consul
+func Dial(opts ...string) error {
+	addr := ""
+	if len(opts) > 0 {
+		addr = opts[0]
+	} else {
+		err := signal.WithExponentialBackoff(func() error {
+			a, err := dcy.Service(CollectorServiceName)
+			addr = a.String()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.AddHook(New(addr))
+	logger().S("addr", addr).Info("connected")
+	return nil
+}
+
+// New creates exporter to the collector at addr (host:port).
+func New(addr string) *Exporter {
+	e := &Exporter{
+		url:    fmt.Sprintf("http://%s%s", addr, logsPath),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	e.BatchWriter = log.NewBatchWriter(e.push)
+	return e
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type logRecord struct {
+	TimeUnixNano   string     `json:"timeUnixNano"`
+	SeverityNumber int        `json:"severityNumber"`
+	SeverityText   string     `json:"severityText"`
+	Body           anyValue   `json:"body"`
+	Attributes     []keyValue `json:"attributes,omitempty"`
+	TraceID        string     `json:"traceId,omitempty"`
+	SpanID         string     `json:"spanId,omitempty"`
+}
+
+type scopeLogs struct {
+	Scope      map[string]string `json:"scope"`
+	LogRecords []logRecord       `json:"logRecords"`
+}
+
+type resourceLogs struct {
+	Resource struct {
+		Attributes []keyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+// Fire buffers record for sending, implements log.Hook.
+func (e *Exporter) Fire(r log.Record) error {
+	e.WriteRecord(r)
+	return nil
+}
+
+// severity numbers from OpenTelemetry logs data model
+func severity(l log.Level) int {
+	switch l {
+	case log.DebugLevel:
+		return 5
+	case log.InfoLevel:
+		return 9
+	case log.NoticeLevel:
+		return 10
+	case log.WarnLevel:
+		return 13
+	case log.ErrorLevel:
+		return 17
+	case log.FatalLevel:
+		return 21
+	}
+	return 0
+}
+
+func str(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+// value converts record attribute to number or bool when it is written as one.
+func value(v string) anyValue {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return anyValue{DoubleValue: &f}
+	}
+	if b, err := strconv.ParseBool(v); err == nil && (v == "true" || v == "false") {
+		return anyValue{BoolValue: &b}
+	}
+	return str(v)
+}
+
+// record converts log record to OTLP log record.
+func record(lr log.Record) logRecord {
+	r := logRecord{
+		TimeUnixNano:   strconv.FormatInt(lr.Time.UnixNano(), 10),
+		SeverityNumber: severity(lr.Level),
+		SeverityText:   lr.Level.String(),
+		Body:           str(lr.Msg),
+	}
+	if lr.File != "" {
+		r.Attributes = append(r.Attributes,
+			keyValue{Key: "code.filepath", Value: str(lr.File)},
+			keyValue{Key: "code.lineno", Value: value(strconv.Itoa(lr.Line))})
+	}
+	if lr.Err != nil {
+		r.Attributes = append(r.Attributes, keyValue{Key: "exception.message", Value: str(lr.Err.Error())})
+	}
+	for _, k := range sortedKeys(lr.Attrs) {
+		v := lr.Attrs[k]
+		switch k {
+		case "trace_id":
+			r.TraceID = v
+		case "span_id":
+			r.SpanID = v
+		default:
+			r.Attributes = append(r.Attributes, keyValue{Key: k, Value: value(v)})
+		}
+	}
+	return r
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (e *Exporter) push(batch []log.BatchLine) error {
+	rl := resourceLogs{}
+	rl.Resource.Attributes = []keyValue{
+		{Key: "service.name", Value: str(env.AppName())},
+		{Key: "host.name", Value: str(env.Hostname())},
+		{Key: "deployment.environment", Value: str(env.Environment())},
+		{Key: "dc", Value: str(env.Dc())},
+	}
+	sl := scopeLogs{Scope: map[string]string{"name": "svckit"}}
+	for _, bl := range batch {
+		if bl.Record != nil {
+			sl.LogRecords = append(sl.LogRecords, record(*bl.Record))
+		}
+	}
+	rl.ScopeLogs = []scopeLogs{sl}
+	body, err := json.Marshal(map[string][]resourceLogs{"resourceLogs": {rl}})
+	if err != nil {
+		return err
+	}
+	rsp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, rsp.Body)
+	rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp collector status %s", rsp.Status)
+	}
+	return nil
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.log.otlp")
+}
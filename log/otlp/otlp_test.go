@@ -0,0 +1,57 @@
+package otlp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	r := record(log.Record{
+		Time:  time.Now(),
+		Level: log.WarnLevel,
+		Msg:   "msg",
+		File:  "main.go",
+		Line:  123,
+		Attrs: map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7", "key": "val", "n": "3"},
+		Err:   errors.New("boom"),
+	})
+	assert.Equal(t, 13, r.SeverityNumber)
+	assert.Equal(t, "warn", r.SeverityText)
+	assert.Equal(t, "msg", *r.Body.StringValue)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", r.SpanID)
+	assert.Equal(t, 5, len(r.Attributes))
+	assert.Equal(t, "exception.message", r.Attributes[2].Key)
+	assert.Equal(t, "key", r.Attributes[3].Key)
+	assert.Equal(t, 3.0, *r.Attributes[4].Value.DoubleValue)
+}
+
+func TestFlush(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		body = string(buf)
+	}))
+	defer srv.Close()
+
+	e := New(strings.TrimPrefix(srv.URL, "http://"))
+	log.AddHook(e)
+	log.S("key", "val").Info("exported")
+	log.Flush()
+
+	var rsp map[string][]resourceLogs
+	assert.NoError(t, json.Unmarshal([]byte(body), &rsp))
+	lrs := rsp["resourceLogs"][0].ScopeLogs[0].LogRecords
+	assert.Equal(t, 1, len(lrs))
+	assert.Equal(t, "exported", *lrs[0].Body.StringValue)
+	assert.Equal(t, 9, lrs[0].SeverityNumber)
+}
@@ -0,0 +1,154 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record is structured log record passed to hooks.
+type Record struct {
+	Time  time.Time
+	Level Level
+	Msg   string
+	File  string
+	Line  int
+	// Attrs are attribute values, strings unquoted, numbers and json as written in output
+	Attrs map[string]string
+	// Err is error given to Error or Fatal, nil otherwise
+	Err error
+}
+
+// Hook receives every record logged at its levels.
+// Hooks are called synchronously, slow sinks should buffer records.
+type Hook interface {
+	Fire(Record) error
+}
+
+// HookFunc is function adapter for Hook.
+type HookFunc func(Record) error
+
+// Fire calls f(r).
+func (f HookFunc) Fire(r Record) error {
+	return f(r)
+}
+
+type hook struct {
+	Hook
+	levels int32 // bit mask of levels
+}
+
+var (
+	hooksMu    sync.RWMutex
+	hooks      []hook
+	hookLevels int32 // union of all hooks levels
+	hookErrors int64
+)
+
+// AddHook registers hook for levels, without levels hook receives all records.
+// Errors and panics in hook are isolated from logging and other hooks,
+// they are only counted in HookErrors.
+func AddHook(h Hook, levels ...Level) {
+	var mask int32
+	for _, l := range levels {
+		mask |= 1 << uint(l)
+	}
+	if len(levels) == 0 {
+		mask = 1<<uint(FatalLevel+1) - 1
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, hook{Hook: h, levels: mask})
+	atomic.StoreInt32(&hookLevels, atomic.LoadInt32(&hookLevels)|mask)
+}
+
+// AddErrorHook registers function called for each error and fatal record.
+// Used for error reporting integrations.
+func AddErrorHook(h func(Record)) {
+	AddHook(HookFunc(func(r Record) error {
+		h(r)
+		return nil
+	}), ErrorLevel, FatalLevel)
+}
+
+// registered returns copy of registered hooks.
+func registered() []hook {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return append([]hook(nil), hooks...)
+}
+
+// flushHooks flushes hooks which buffer records.
+func flushHooks() {
+	for _, h := range registered() {
+		if f, ok := h.Hook.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+}
+
+// closeHooks closes hooks which are io.Closer, first error is returned.
+func closeHooks() error {
+	var err error
+	for _, h := range registered() {
+		if c, ok := h.Hook.(io.Closer); ok {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// HookErrors returns number of failed hook calls.
+func HookErrors() int64 {
+	return atomic.LoadInt64(&hookErrors)
+}
+
+func (a *Agregator) record() Record {
+	r := Record{
+		Time:  a.t,
+		Level: levelOf(a.level),
+		Msg:   unquote(a.msg),
+		File:  a.file,
+		Line:  a.line,
+		Attrs: make(map[string]string, len(a.attrs)),
+		Err:   a.err,
+	}
+	for _, atr := range a.attrs {
+		r.Attrs[atr.key] = unquote(atr.val)
+	}
+	return r
+}
+
+func (a *Agregator) fireHooks() {
+	level := levelOf(a.level)
+	bit := int32(1) << uint(level)
+	if atomic.LoadInt32(&hookLevels)&bit == 0 {
+		return
+	}
+	r := a.record()
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, h := range hooks {
+		if h.levels&bit == 0 {
+			continue
+		}
+		if err := fire(h, r); err != nil {
+			atomic.AddInt64(&hookErrors, 1)
+			countSinkError("hook")
+		}
+	}
+}
+
+// fire calls hook, recovering from panic.
+func fire(h Hook, r Record) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("hook panic: %v", p)
+		}
+	}()
+	return h.Fire(r)
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	defer func() { hooks, hookLevels = nil, 0 }()
+	var all, errs []Record
+	AddHook(HookFunc(func(r Record) error {
+		all = append(all, r)
+		return nil
+	}))
+	AddHook(HookFunc(func(r Record) error {
+		panic("failed")
+	}), ErrorLevel)
+	AddHook(HookFunc(func(r Record) error {
+		errs = append(errs, r)
+		return errors.New("failed")
+	}), ErrorLevel)
+
+	a := newTestAgregator()
+	a.S("key", "val").I("i", 1).Info("msg")
+	a = newTestAgregator()
+	a.Error(errors.New("err"))
+
+	assert.Len(t, all, 2)
+	assert.Equal(t, InfoLevel, all[0].Level)
+	assert.Equal(t, "msg", all[0].Msg)
+	assert.Equal(t, map[string]string{"key": "val", "i": "1"}, all[0].Attrs)
+	assert.Equal(t, "main.go", all[0].File)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "err", errs[0].Err.Error())
+	assert.Equal(t, int64(2), HookErrors())
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const redacted = "***"
+
+var (
+	redactMu       sync.RWMutex
+	redactKeys     = map[string]bool{}
+	redactPatterns []*regexp.Regexp
+)
+
+func init() {
+	RedactKeys("password", "passwd", "secret", "token", "authorization", "cookie")
+}
+
+// RedactKeys registers attribute names whose values are masked in output.
+// Names are case insensitive. Keys inside json attributes are also masked.
+// Password, secret, token, authorization and cookie are masked by default.
+func RedactKeys(keys ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, k := range keys {
+		redactKeys[strings.ToLower(k)] = true
+	}
+}
+
+// RedactPattern registers pattern which is masked in all values and messages.
+func RedactPattern(re *regexp.Regexp) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	redactPatterns = append(redactPatterns, re)
+}
+
+func isRedactKey(key string) bool {
+	return redactKeys[strings.ToLower(strings.TrimPrefix(key, "_"))]
+}
+
+// redact masks sensitive values in attributes and message.
+func (a *Agregator) redact() {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	for _, atr := range a.attrs {
+		if isRedactKey(atr.key) {
+			atr.val = `"` + redacted + `"`
+			continue
+		}
+		if strings.HasPrefix(atr.val, "{") || strings.HasPrefix(atr.val, "[") {
+			atr.val = redactJSON(atr.val)
+		}
+		atr.val = redactPattern(atr.val)
+	}
+	a.msg = redactPattern(a.msg)
+}
+
+func redactPattern(s string) string {
+	for _, re := range redactPatterns {
+		s = re.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+// redactJSON masks values of sensitive keys in json document.
+func redactJSON(val string) string {
+	lower := strings.ToLower(val)
+	found := false
+	for k := range redactKeys {
+		if strings.Contains(lower, k) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return val
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(val), &v); err != nil {
+		return val
+	}
+	buf, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return val
+	}
+	return string(buf)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range t {
+			if isRedactKey(k) {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactValue(vv)
+		}
+	case []interface{}:
+		for i, vv := range t {
+			t[i] = redactValue(vv)
+		}
+	}
+	return v
+}
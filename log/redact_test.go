@@ -0,0 +1,20 @@
+package log
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	RedactPattern(regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`))
+	defer func() { redactPatterns = nil }()
+
+	a := newTestAgregator()
+	a.S("Password", "pero").
+		J("req", []byte(`{"user":"pero","auth":{"token":"abc"},"list":[{"secret":1}]}`)).
+		S("card", "1234-5678-1234-5678").
+		Info("paid with 1234-5678-1234-5678")
+	assert.Equal(t, `{"time":"2009-11-10T23:05:06.000000+00:00", "file":"main.go:123", "level":"info", "Password":"***", "req":{"auth":{"token":"***"},"list":[{"secret":"***"}],"user":"pero"}, "card":"***", "msg":"paid with ***"}`, currentBuffer(a))
+}
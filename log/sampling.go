@@ -0,0 +1,88 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const samplingTick = time.Second
+
+// sampler limits number of same messages logged in each second.
+type sampler struct {
+	first      int
+	thereafter int
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+var (
+	samplingMu sync.RWMutex
+	sampling   *sampler
+	sampledOut int64
+)
+
+// SetSampling enables sampling of debug, info and notice messages.
+// In each second first messages with the same level and text are logged,
+// and after that every thereafter message.
+// Set first to 0 to disable sampling.
+func SetSampling(first, thereafter int) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	if first <= 0 {
+		sampling = nil
+		return
+	}
+	if thereafter <= 0 {
+		thereafter = 1 << 31
+	}
+	sampling = &sampler{first: first, thereafter: thereafter, counts: map[string]int{}}
+}
+
+// Sampling returns current sampling parameters, first is 0 when sampling is disabled.
+func Sampling() (first, thereafter int) {
+	samplingMu.RLock()
+	defer samplingMu.RUnlock()
+	if sampling == nil {
+		return 0, 0
+	}
+	return sampling.first, sampling.thereafter
+}
+
+// SampledOut returns number of messages dropped by sampling.
+func SampledOut() int64 {
+	return atomic.LoadInt64(&sampledOut)
+}
+
+// sample returns true if message should be logged.
+func sample(level, msg string, t time.Time) bool {
+	samplingMu.RLock()
+	s := sampling
+	samplingMu.RUnlock()
+	if s == nil || levelOf(level) >= WarnLevel {
+		return true
+	}
+	if s.check(level+msg, t) {
+		return true
+	}
+	atomic.AddInt64(&sampledOut, 1)
+	countDropped("sampling")
+	return false
+}
+
+func (s *sampler) check(key string, t time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.Sub(s.start) >= samplingTick {
+		s.start = t
+		s.counts = map[string]int{}
+	}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= s.first {
+		return true
+	}
+	return (n-s.first)%s.thereafter == 0
+}
@@ -0,0 +1,23 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampling(t *testing.T) {
+	SetSampling(2, 3)
+	defer SetSampling(0, 0)
+	buf := &bytes.Buffer{}
+	before := SampledOut()
+	for i := 0; i < 8; i++ {
+		NewAgregator(buf, 3).Info("hot path")
+	}
+	NewAgregator(buf, 3).Error(nil)
+	// first 2, then 5th and 8th, and error
+	assert.Equal(t, 5, strings.Count(buf.String(), "\n"))
+	assert.Equal(t, int64(4), SampledOut()-before)
+}
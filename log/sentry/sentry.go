@@ -0,0 +1,381 @@
+// Package sentry forwards error log records to Sentry compatible endpoint.
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/log/sentry"
+//	err := sentry.Dial("http://public_key@sentry/42", sentry.Release("v1.2.3"))
+//
+// When DSN host has no port it is discovered in Consul.
+// Each log.Error, log.Errorf, ErrorS and Fatal is sent as Sentry event with stack trace and log attributes.
+// Events are grouped by message and location, same group is sent at most once in group window.
+// Total number of events is limited per minute.
+// On Fatal queued events are sent before the process exits, waiting at most FatalFlushTimeout.
+package sentry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/signal"
+)
+
+const (
+	// EnvDSN is environment variable with Sentry DSN, if set reporting is started on import.
+	EnvDSN = "SVCKIT_SENTRY_DSN"
+
+	queueSize = 64
+)
+
+// FatalFlushTimeout is maximum time Fatal waits for queued events to be sent before the process exits.
+var FatalFlushTimeout = 2 * time.Second
+
+// Reporter sends error events to Sentry.
+type Reporter struct {
+	url         string
+	auth        string
+	release     string
+	environment string
+	perMinute   int
+	groupWindow time.Duration
+
+	client *http.Client
+	queue  chan *event
+
+	mu      sync.Mutex
+	window  time.Time
+	sent    int
+	groups  map[string]time.Time
+	dropped int
+}
+
+// Option is Reporter option.
+type Option func(*Reporter)
+
+// Release sets release reported with each event (default is env.Version).
+func Release(r string) Option {
+	return func(s *Reporter) {
+		s.release = r
+	}
+}
+
+// Environment sets environment reported with each event (default is env.Environment).
+func Environment(e string) Option {
+	return func(s *Reporter) {
+		s.environment = e
+	}
+}
+
+// RateLimit sets maximum number of events sent per minute (default 60).
+func RateLimit(perMinute int) Option {
+	return func(s *Reporter) {
+		s.perMinute = perMinute
+	}
+}
+
+// GroupWindow sets time in which same error group is sent only once (default 1 minute).
+func GroupWindow(d time.Duration) Option {
+	return func(s *Reporter) {
+		s.groupWindow = d
+	}
+}
+
+// Dial creates reporter and registers it as log error hook.
+func Dial(dsn string, opts ...Option) (*Reporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.Port() == "" {
+		err := signal.WithExponentialBackoff(func() error {
+			a, err := dcy.Service(u.Hostname())
+			if err == nil {
+				u.Host = a.String()
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := New(u.String(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	log.AddErrorHook(r.Report)
+	logger().S("addr", u.Host).Info("connected")
+	return r, nil
+}
+
+// New creates reporter for the dsn (scheme://public_key@host:port/project_id).
+func New(dsn string, opts ...Option) (*Reporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || project == "" {
+		return nil, fmt.Errorf("invalid sentry dsn %s", dsn)
+	}
+	r := &Reporter{
+		url:         fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, project),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=svckit/1.0, sentry_key=%s", u.User.Username()),
+		release:     env.Version(),
+		environment: env.Environment(),
+		perMinute:   60,
+		groupWindow: time.Minute,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *event, queueSize),
+		groups:      make(map[string]time.Time),
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	go r.loop()
+	return r, nil
+}
+
+// Report queues log record as Sentry event.
+// Record is skipped if rate limit is reached or same group was recently sent.
+func (r *Reporter) Report(rec log.Record) {
+	if rec.Level == log.FatalLevel {
+		// process exits after hooks, send also events queued before
+		defer r.Flush(FatalFlushTimeout)
+	}
+	fingerprint := fmt.Sprintf("%s:%d %s", rec.File, rec.Line, rec.Msg)
+	if !r.allow(fingerprint, rec.Time) {
+		return
+	}
+	e := r.event(rec, fingerprint, stacktrace())
+	select {
+	case r.queue <- e:
+	default:
+		r.mu.Lock()
+		r.dropped++
+		r.mu.Unlock()
+	}
+}
+
+// Flush waits until queued events are sent, at most timeout.
+// Returns false on timeout.
+func (r *Reporter) Flush(timeout time.Duration) bool {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	done := make(chan struct{})
+	select {
+	case r.queue <- &event{flushed: done}:
+	case <-t.C:
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// Dropped returns number of events not sent because the queue was full.
+func (r *Reporter) Dropped() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+func (r *Reporter) allow(fingerprint string, t time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, ok := r.groups[fingerprint]; ok && t.Sub(last) < r.groupWindow {
+		return false
+	}
+	if t.Sub(r.window) >= time.Minute {
+		r.window = t
+		r.sent = 0
+		for k, last := range r.groups {
+			if t.Sub(last) >= r.groupWindow {
+				delete(r.groups, k)
+			}
+		}
+	}
+	if r.sent >= r.perMinute {
+		return false
+	}
+	r.sent++
+	r.groups[fingerprint] = t
+	return true
+}
+
+type frame struct {
+	Filename string `json:"filename"`
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type exception struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []frame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Platform    string            `json:"platform"`
+	Message     string            `json:"message"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	Fingerprint []string          `json:"fingerprint"`
+	Exception   struct {
+		Values []exception `json:"values"`
+	} `json:"exception"`
+
+	flushed chan struct{} // flush marker, closed when events queued before it are sent
+}
+
+func (r *Reporter) event(rec log.Record, fingerprint string, frames []frame) *event {
+	id := make([]byte, 16)
+	rand.Read(id)
+	e := &event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   rec.Time.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		Level:       "error",
+		Logger:      env.AppName(),
+		Platform:    "go",
+		Message:     rec.Msg,
+		Release:     r.release,
+		Environment: r.environment,
+		ServerName:  env.Hostname(),
+		Tags:        map[string]string{"app": env.AppName(), "dc": env.Dc(), "file": fmt.Sprintf("%s:%d", rec.File, rec.Line)},
+		Extra:       rec.Attrs,
+		Fingerprint: []string{fingerprint},
+	}
+	if rec.Level >= log.FatalLevel {
+		e.Level = "fatal"
+	}
+	if lib, ok := rec.Attrs["lib"]; ok {
+		e.Tags["lib"] = lib
+	}
+	ex := exception{Type: "error", Value: rec.Msg}
+	if rec.Err != nil {
+		ex.Type = reflect.TypeOf(rec.Err).String()
+	}
+	ex.Stacktrace.Frames = frames
+	e.Exception.Values = []exception{ex}
+	return e
+}
+
+// stacktrace returns frames of the log caller, oldest first as Sentry expects.
+// Frames inside log package and this package are skipped.
+func stacktrace() []frame {
+	pc := make([]uintptr, 64)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	var fs []frame
+	for {
+		f, more := frames.Next()
+		module, fn := splitFunction(f.Function)
+		if !(module == "github.com/minus5/svckit/log" || module == "github.com/minus5/svckit/log/sentry") {
+			fs = append(fs, frame{
+				Filename: f.File,
+				Function: fn,
+				Module:   module,
+				Lineno:   f.Line,
+				InApp:    !strings.HasPrefix(module, "runtime") && !strings.Contains(module, "/vendor/"),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(fs)-1; i < j; i, j = i+1, j-1 {
+		fs[i], fs[j] = fs[j], fs[i]
+	}
+	return fs
+}
+
+// splitFunction splits runtime function name (github.com/a/b.(*T).M) into package and function.
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+func (r *Reporter) loop() {
+	for e := range r.queue {
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+		if err := r.send(e); err != nil {
+			// ne preko loga da ne napravim petlju
+			r.mu.Lock()
+			r.dropped++
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *Reporter) send(e *event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, rsp.Body)
+	rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("sentry status %s", rsp.Status)
+	}
+	return nil
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.log.sentry")
+}
+
+func init() {
+	dsn := os.Getenv(EnvDSN)
+	if dsn == "" {
+		return
+	}
+	go func() {
+		if _, err := Dial(dsn); err != nil {
+			logger().Error(err)
+		}
+	}()
+}
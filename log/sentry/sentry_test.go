@@ -0,0 +1,101 @@
+package sentry
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New("http://sentry:9000/42")
+	assert.Error(t, err)
+	r, err := New("http://key@sentry:9000/42", Release("v1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://sentry:9000/api/42/store/", r.url)
+	assert.Contains(t, r.auth, "sentry_key=key")
+	assert.Equal(t, "v1", r.release)
+}
+
+func TestAllow(t *testing.T) {
+	r := &Reporter{perMinute: 2, groupWindow: time.Minute, groups: make(map[string]time.Time)}
+	now := time.Now()
+	assert.True(t, r.allow("a", now))
+	assert.False(t, r.allow("a", now.Add(time.Second)))
+	assert.True(t, r.allow("b", now))
+	assert.False(t, r.allow("c", now))
+	assert.True(t, r.allow("a", now.Add(time.Minute)))
+}
+
+func TestSplitFunction(t *testing.T) {
+	m, f := splitFunction("github.com/minus5/svckit/log.(*Agregator).Error")
+	assert.Equal(t, "github.com/minus5/svckit/log", m)
+	assert.Equal(t, "(*Agregator).Error", f)
+	m, f = splitFunction("main.main")
+	assert.Equal(t, "main", m)
+	assert.Equal(t, "main", f)
+}
+
+func TestReport(t *testing.T) {
+	events := make(chan event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/api/42/store/", req.URL.Path)
+		assert.True(t, strings.HasPrefix(req.Header.Get("X-Sentry-Auth"), "Sentry "))
+		var e event
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&e))
+		events <- e
+	}))
+	defer srv.Close()
+	r, err := New(strings.Replace(srv.URL, "://", "://key@", 1)+"/42", Release("v1"))
+	assert.NoError(t, err)
+
+	r.Report(log.Record{Time: time.Now(), Level: log.ErrorLevel, Msg: "failed", File: "main.go", Line: 12,
+		Attrs: map[string]string{"lib": "pero"}, Err: errors.New("failed")})
+	e := <-events
+	assert.Equal(t, "failed", e.Message)
+	assert.Equal(t, "v1", e.Release)
+	assert.Equal(t, "pero", e.Tags["lib"])
+	assert.Equal(t, "*errors.errorString", e.Exception.Values[0].Type)
+	frames := e.Exception.Values[0].Stacktrace.Frames
+	assert.NotEmpty(t, frames)
+	assert.Equal(t, "tRunner", frames[len(frames)-1].Function)
+}
+
+func TestFatalFlush(t *testing.T) {
+	var sent int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&sent, 1)
+	}))
+	defer srv.Close()
+	r, err := New(strings.Replace(srv.URL, "://", "://key@", 1) + "/42")
+	assert.NoError(t, err)
+
+	r.Report(log.Record{Time: time.Now(), Level: log.ErrorLevel, Msg: "failed", File: "main.go", Line: 12})
+	r.Report(log.Record{Time: time.Now(), Level: log.FatalLevel, Msg: "fatal", File: "main.go", Line: 13})
+	// both sent when Report of the fatal record returns
+	assert.Equal(t, int32(2), atomic.LoadInt32(&sent))
+
+	// rate limited fatal record still waits for queued events
+	r, err = New(strings.Replace(srv.URL, "://", "://key@", 1)+"/42", RateLimit(1))
+	assert.NoError(t, err)
+	r.Report(log.Record{Time: time.Now(), Level: log.ErrorLevel, Msg: "failed", File: "main.go", Line: 12})
+	r.Report(log.Record{Time: time.Now(), Level: log.FatalLevel, Msg: "fatal", File: "main.go", Line: 13})
+	assert.Equal(t, int32(3), atomic.LoadInt32(&sent))
+
+	block := make(chan struct{})
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) { <-block }))
+	defer stuck.Close()
+	defer close(block)
+	r, err = New(strings.Replace(stuck.URL, "://", "://key@", 1) + "/42")
+	assert.NoError(t, err)
+	r.Report(log.Record{Time: time.Now(), Level: log.ErrorLevel, Msg: "stuck", File: "main.go", Line: 14})
+	assert.False(t, r.Flush(20*time.Millisecond))
+}
@@ -0,0 +1,252 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Slog returns *slog.Logger which writes through svckit log.
+// For libraries which accept only slog logger.
+func Slog() *slog.Logger {
+	return slog.New(NewHandler(nil))
+}
+
+// HandlerOptions are options for Handler.
+type HandlerOptions struct {
+	// Level is minimal level of the handler, if nil global level is used.
+	Level slog.Leveler
+}
+
+// Handler is slog.Handler which writes records through svckit log,
+// to the same outputs, with the same env fields and format.
+// Groups are flattened into dot separated keys.
+// Trace, span and request ids from the context are added as in FromContext.
+type Handler struct {
+	opts  HandlerOptions
+	attrs []*attr
+	group string
+}
+
+// NewHandler creates slog handler, opts can be nil.
+func NewHandler(opts *HandlerOptions) *Handler {
+	h := &Handler{}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func toSlogLevel(l Level) slog.Level {
+	switch l {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case NoticeLevel:
+		return slog.LevelInfo + 2
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	}
+	return slog.LevelError + 4
+}
+
+func fromSlogLevel(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return DebugLevel
+	case l < slog.LevelInfo+2:
+		return InfoLevel
+	case l < slog.LevelWarn:
+		return NoticeLevel
+	case l < slog.LevelError:
+		return WarnLevel
+	case l < slog.LevelError+4:
+		return ErrorLevel
+	}
+	return FatalLevel
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
+	if h.opts.Level != nil {
+		return l >= h.opts.Level.Level()
+	}
+	return enabled(fromSlogLevel(l))
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	a := newAgregator(0)
+	a.t = r.Time
+	if a.t.IsZero() {
+		a.t = time.Now()
+	}
+	a.file, a.line = "???", 0
+	if r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		a.file, a.line = shortFile(f.File), f.Line
+	}
+	if h.opts.Level != nil {
+		// already checked in Enabled
+		lvl := DebugLevel
+		a.minLevel = &lvl
+	}
+	a.attrs = append(a.attrs, h.attrs...)
+	r.Attrs(func(attr slog.Attr) bool {
+		a.slogAttr(h.group, attr)
+		return true
+	})
+	if ctx != nil {
+		kv := correlation(ctx)
+		for i := 0; i+1 < len(kv); i += 2 {
+			a.field(kv[i].(string), kv[i+1])
+		}
+	}
+	a.msg = r.Message
+	switch fromSlogLevel(r.Level) {
+	case DebugLevel:
+		a.level = LevelDebug
+	case InfoLevel:
+		a.level = LevelInfo
+	case NoticeLevel:
+		a.level = LevelNotice
+	case WarnLevel:
+		a.level = LevelWarn
+	default:
+		// fatal level in slog doesn't exit
+		a.level = LevelError
+	}
+	return a.write()
+}
+
+// WithAttrs implements slog.Handler.
+// Attributes are encoded once, not on each record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	a := &Agregator{}
+	for _, attr := range attrs {
+		a.slogAttr(h.group, attr)
+	}
+	h2 := *h
+	h2.attrs = append(append([]*attr{}, h.attrs...), a.attrs...)
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = name
+	if h.group != "" {
+		h2.group = h.group + "." + name
+	}
+	return &h2
+}
+
+// slogAttr adds slog attribute to the agregator.
+// Groups are flattened into dot separated keys, empty attributes are ignored.
+func (a *Agregator) slogAttr(group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	key := attr.Key
+	if group != "" && key != "" {
+		key = group + "." + key
+	} else if key == "" {
+		key = group
+	}
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		for _, ga := range attr.Value.Group() {
+			a.slogAttr(key, ga)
+		}
+	case slog.KindString:
+		a.S(key, attr.Value.String())
+	case slog.KindInt64:
+		a.I(key, int(attr.Value.Int64()))
+	case slog.KindUint64:
+		a.I(key, int(attr.Value.Uint64()))
+	case slog.KindFloat64:
+		a.F(key, attr.Value.Float64(), -1)
+	case slog.KindBool:
+		a.B(key, attr.Value.Bool())
+	case slog.KindDuration:
+		a.I(key, int(attr.Value.Duration()))
+	case slog.KindTime:
+		a.S(key, attr.Value.Time().Format(time.RFC3339Nano))
+	default:
+		a.anyValue(key, attr.Value.Any())
+	}
+}
+
+// anyValue adds value of any type, structs, maps and slices are json encoded.
+func (a *Agregator) anyValue(key string, v interface{}) {
+	switch t := v.(type) {
+	case error:
+		a.S(key, t.Error())
+		return
+	case []byte:
+		a.S(key, string(t))
+		return
+	case fmt.Stringer:
+		a.S(key, t.String())
+		return
+	}
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if buf, err := json.Marshal(v); err == nil {
+			a.J(key, buf)
+			return
+		}
+	}
+	a.S(key, fmt.Sprint(v))
+}
+
+// SlogWriter returns output which writes svckit log lines to the slog handler.
+// Use it with SetOutput to route svckit logs through application slog pipeline.
+// Lines must be in json format.
+func SlogWriter(h slog.Handler) io.Writer {
+	return &slogWriter{h: h}
+}
+
+type slogWriter struct {
+	h slog.Handler
+}
+
+func (w *slogWriter) Write(p []byte) (int, error) {
+	e, err := NewEntry(p)
+	if err != nil {
+		return 0, err
+	}
+	l, _ := ParseLevel(e.Level)
+	r := slog.NewRecord(e.Time, toSlogLevel(l), e.Msg, 0)
+	r.AddAttrs(slog.String("file", e.File))
+	for k, v := range e.attr {
+		r.AddAttrs(slog.Any(k, v))
+	}
+	if err := w.h.Handle(context.Background(), r); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func shortFile(longFile string) string {
+	if i := strings.LastIndex(longFile, "/"); i >= 0 {
+		return longFile[i+1:]
+	}
+	return longFile
+}
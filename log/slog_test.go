@@ -0,0 +1,53 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := out
+	SetOutput(buf)
+	defer SetOutput(o)
+	prefix = []byte{}
+
+	Slog().With("key", "val").WithGroup("req").Warn("msg", "id", 12, slog.Group("user", "name", "pero"))
+	line := buf.String()
+	assert.Contains(t, line, `"level":"warn", "key":"val", "req.id":12, "req.user.name":"pero", "msg":"msg"}`)
+	assert.Contains(t, line, `"file":"slog_test.go:`)
+}
+
+func TestSlogWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := SlogWriter(slog.NewTextHandler(buf, nil))
+	_, err := w.Write([]byte(`{"time":"2009-11-10T23:05:06.000000+00:00", "file":"main.go:123", "level":"warn", "key":"val", "msg":"msg"}`))
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), `level=WARN msg=msg file=main.go:123 key=val`)
+}
+
+func TestHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	o := out
+	SetOutput(buf)
+	defer SetOutput(o)
+	prefix = []byte{}
+
+	l := slog.New(NewHandler(&HandlerOptions{Level: slog.LevelInfo}))
+	l.Debug("skipped")
+	assert.Empty(t, buf.String())
+
+	ctx := WithRequestID(context.Background(), "abc")
+	l.WithGroup("req").With("id", 12).InfoContext(ctx, "msg",
+		slog.Group("", "inline", true),
+		slog.Any("err", errors.New("failed")),
+		slog.Any("m", map[string]int{"a": 1}),
+		slog.Attr{},
+	)
+	assert.Contains(t, buf.String(), `"level":"info", "req.id":12, "req.inline":true, "req.err":"failed", "req.m":{"a":1}, "request_id":"abc", "msg":"msg"}`)
+}
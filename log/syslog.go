@@ -0,0 +1,176 @@
+package log
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	syslogBufferSize   = 8192
+	syslogDialTimeout  = 5 * time.Second
+	syslogMaxReconnect = 30 * time.Second
+)
+
+// Syslog is tcp or tls syslog output.
+// Writes are buffered and sent from background goroutine, so they don't block
+// when collector is not available. Connection is reestablished on error.
+// When buffer is full new lines are dropped.
+type Syslog struct {
+	network   string
+	addr      string
+	tlsConfig *tls.Config
+	tag       string
+	hostname  string
+
+	lines   chan []byte
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	dropped int
+}
+
+// NewSyslog creates syslog output for network "tcp" or "tls".
+// TlsConfig is used only for tls network, may be nil.
+func NewSyslog(network, addr string, tlsConfig *tls.Config) (*Syslog, error) {
+	if network != "tcp" && network != "tls" {
+		return nil, fmt.Errorf("unsupported syslog network %s", network)
+	}
+	s := &Syslog{
+		network:   network,
+		addr:      addr,
+		tlsConfig: tlsConfig,
+		tag:       appName,
+		hostname:  hostname,
+		lines:     make(chan []byte, syslogBufferSize),
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.loop()
+	return s, nil
+}
+
+// Write implements io.Writer.
+func (s *Syslog) Write(p []byte) (int, error) {
+	select {
+	case <-s.closing:
+		// closed, lines written during shutdown are dropped
+		s.drop()
+		return len(p), nil
+	default:
+	}
+	line := make([]byte, len(p))
+	copy(line, p)
+	select {
+	case s.lines <- line:
+	default:
+		s.drop()
+	}
+	return len(p), nil
+}
+
+func (s *Syslog) drop() {
+	s.mu.Lock()
+	s.dropped++
+	s.mu.Unlock()
+	countDropped("syslog")
+}
+
+// Dropped returns number of lines dropped because buffer was full or output was closed.
+func (s *Syslog) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops sending, buffered lines are flushed if connection is available.
+// Lines written after Close are dropped.
+func (s *Syslog) Close() error {
+	s.once.Do(func() {
+		close(s.closing)
+	})
+	<-s.done
+	return nil
+}
+
+func (s *Syslog) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: syslogDialTimeout}
+	if s.network == "tls" {
+		return tls.DialWithDialer(d, "tcp", s.addr, s.tlsConfig)
+	}
+	return d.Dial("tcp", s.addr)
+}
+
+// frame formats line as syslog message with octet counting framing (RFC 6587).
+func (s *Syslog) frame(line []byte) []byte {
+	msg := fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+		syslog.LOG_LOCAL5|syslog.LOG_INFO, time.Now().Format(time.RFC3339),
+		s.hostname, s.tag, os.Getpid(), strings.TrimSuffix(string(line), "\n"))
+	return []byte(fmt.Sprintf("%d %s", len(msg), msg))
+}
+
+func (s *Syslog) loop() {
+	defer close(s.done)
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	wait := time.Second
+	// send writes line, reconnecting on error.
+	// Returns false if output is closed while waiting for reconnect.
+	send := func(line []byte) bool {
+		buf := s.frame(line)
+		for {
+			if conn == nil {
+				var err error
+				if conn, err = s.dial(); err != nil {
+					conn = nil
+					select {
+					case <-time.After(wait):
+					case <-s.closing:
+						return false
+					}
+					if wait *= 2; wait > syslogMaxReconnect {
+						wait = syslogMaxReconnect
+					}
+					continue
+				}
+				wait = time.Second
+			}
+			if _, err := conn.Write(buf); err != nil {
+				conn.Close()
+				conn = nil
+				continue
+			}
+			return true
+		}
+	}
+	for {
+		select {
+		case line := <-s.lines:
+			if !send(line) {
+				return
+			}
+		case <-s.closing:
+			// flush buffered lines
+			for {
+				select {
+				case line := <-s.lines:
+					if !send(line) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
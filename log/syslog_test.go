@@ -0,0 +1,43 @@
+package log
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('}')
+		received <- line
+	}()
+
+	s, err := NewSyslog("tcp", ln.Addr().String(), nil)
+	assert.Nil(t, err)
+	s.Write([]byte(`{"msg":"pero"}` + "\n"))
+	line := <-received
+	assert.Regexp(t, `^\d+ <174>`, line)
+	assert.True(t, strings.HasSuffix(line, `: {"msg":"pero"}`))
+	assert.Nil(t, s.Close())
+	// write after close is dropped
+	n, err := s.Write([]byte("late\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 1, s.Dropped())
+	assert.Nil(t, s.Close())
+
+	_, err = NewSyslog("udp", "127.0.0.1:514", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// EnvSinks configures several outputs with level filters, json list of SinkConfig, e.g.
+	//
+	//	[{"type":"stderr","level":"info"}, {"type":"file","path":"/var/log/app.log"}, {"type":"syslog","addr":"tcp://127.0.0.1:514","level":"warn"}]
+	EnvSinks = "SVCKIT_LOG_SINKS"
+)
+
+// Tee writes each record to several outputs, each with its own minimal level.
+type Tee struct {
+	sinks []TeeSink
+}
+
+// TeeSink is Tee output with minimal level.
+type TeeSink struct {
+	W     io.Writer
+	Level Level
+}
+
+// levelWriter is output which filters records by level.
+type levelWriter interface {
+	WriteLevel(l Level, p []byte) (int, error)
+}
+
+// NewTee creates Tee output.
+func NewTee(sinks ...TeeSink) *Tee {
+	return &Tee{sinks: sinks}
+}
+
+// Write writes p to all outputs.
+func (t *Tee) Write(p []byte) (int, error) {
+	return t.WriteLevel(FatalLevel, p)
+}
+
+// WriteLevel writes p to outputs with minimal level up to l.
+// Failure of one output doesn't stop writing to others, first error is returned.
+func (t *Tee) WriteLevel(l Level, p []byte) (int, error) {
+	var err error
+	for _, s := range t.sinks {
+		if l < s.Level {
+			continue
+		}
+		var e error
+		if lw, ok := s.W.(levelWriter); ok {
+			_, e = lw.WriteLevel(l, p)
+		} else {
+			_, e = s.W.Write(p)
+		}
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return len(p), err
+}
+
+// Flush flushes outputs which support it.
+func (t *Tee) Flush() {
+	for _, s := range t.sinks {
+		if f, ok := s.W.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+}
+
+// Close closes outputs, except stdout and stderr.
+func (t *Tee) Close() error {
+	var err error
+	for _, s := range t.sinks {
+		if s.W == os.Stdout || s.W == os.Stderr {
+			continue
+		}
+		if c, ok := s.W.(io.Closer); ok {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// SinkConfig is configuration of one Tee output.
+type SinkConfig struct {
+	// Type is one of stdout, stderr, file, syslog, loki.
+	Type string `json:"type"`
+	// Level is minimal level, default debug.
+	Level string `json:"level,omitempty"`
+	// Path of the file output.
+	Path string `json:"path,omitempty"`
+	// Addr of the syslog (udp address, tcp://addr or tls://addr) or loki (url) output.
+	Addr string `json:"addr,omitempty"`
+}
+
+// ParseSinks creates Tee from json list of SinkConfig.
+func ParseSinks(buf []byte) (*Tee, error) {
+	var cfgs []SinkConfig
+	if err := json.Unmarshal(buf, &cfgs); err != nil {
+		return nil, err
+	}
+	t := &Tee{}
+	for _, c := range cfgs {
+		s, err := c.sink()
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		t.sinks = append(t.sinks, s)
+	}
+	return t, nil
+}
+
+func (c SinkConfig) sink() (TeeSink, error) {
+	s := TeeSink{Level: DebugLevel}
+	if c.Level != "" {
+		l, ok := ParseLevel(c.Level)
+		if !ok {
+			return s, fmt.Errorf("unknown level %s", c.Level)
+		}
+		s.Level = l
+	}
+	var err error
+	switch c.Type {
+	case "stdout":
+		s.W = os.Stdout
+	case "stderr":
+		s.W = os.Stderr
+	case "file":
+		s.W, err = NewFile(c.Path)
+	case "syslog":
+		s.W, err = newSyslogWriter(c.Addr)
+	case "loki":
+		s.W = NewLoki(c.Addr)
+	default:
+		err = fmt.Errorf("unknown sink type %s", c.Type)
+	}
+	return s, err
+}
+
+// sinksOutput returns outputs configured in EnvSinks.
+func sinksOutput() []TeeSink {
+	e := os.Getenv(EnvSinks)
+	if e == "" {
+		return nil
+	}
+	t, err := ParseSinks([]byte(e))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log sinks config failed: %s\n", err)
+		return nil
+	}
+	return t.sinks
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTee(t *testing.T) {
+	all, warn := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	a := newTestAgregator()
+	a.output = NewTee(TeeSink{W: all}, TeeSink{W: warn, Level: WarnLevel})
+	a.Info("info")
+	a = newTestAgregator()
+	a.output = NewTee(TeeSink{W: all}, TeeSink{W: warn, Level: WarnLevel})
+	a.Error(nil)
+	assert.Contains(t, all.String(), `"level":"info"`)
+	assert.Contains(t, all.String(), `"level":"error"`)
+	assert.NotContains(t, warn.String(), `"level":"info"`)
+	assert.Contains(t, warn.String(), `"level":"error"`)
+}
+
+func TestParseSinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	tee, err := ParseSinks([]byte(`[{"type":"stderr","level":"warn"}, {"type":"file","path":"` + path + `"}]`))
+	assert.NoError(t, err)
+	assert.Len(t, tee.sinks, 2)
+	assert.Equal(t, WarnLevel, tee.sinks[0].Level)
+	assert.Equal(t, DebugLevel, tee.sinks[1].Level)
+	assert.NoError(t, tee.Close())
+
+	_, err = ParseSinks([]byte(`[{"type":"kafka"}]`))
+	assert.Error(t, err)
+	_, err = ParseSinks([]byte(`[{"type":"stdout","level":"pero"}]`))
+	assert.Error(t, err)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("down") }
+
+type flushingWriter struct {
+	bytes.Buffer
+	flushed, closed bool
+}
+
+func (w *flushingWriter) Flush()       { w.flushed = true }
+func (w *flushingWriter) Close() error { w.closed = true; return nil }
+
+func TestAddOutput(t *testing.T) {
+	defer SetOutput(out)
+	first, second := &flushingWriter{}, &flushingWriter{}
+	SetOutput(failingWriter{})
+	AddOutput(NewTee(TeeSink{W: first, Level: WarnLevel}))
+	AddOutput(second)
+	tee, ok := out.(*Tee)
+	assert.True(t, ok)
+	assert.Len(t, tee.sinks, 3)
+
+	Info("info")
+	Warn("warn")
+	assert.NotContains(t, first.String(), "info")
+	assert.Contains(t, first.String(), "warn")
+	assert.Contains(t, second.String(), "info")
+
+	Flush()
+	assert.True(t, first.flushed)
+	assert.True(t, second.flushed)
+	assert.NoError(t, Close())
+	assert.True(t, first.closed)
+	assert.True(t, second.closed)
+}
+
+func TestInitOutput(t *testing.T) {
+	defer SetOutput(out)
+	path := filepath.Join(t.TempDir(), "app.log")
+	t.Setenv(EnvFile, path)
+	t.Setenv(EnvSinks, `[{"type":"stdout","level":"warn"}]`)
+	t.Setenv(EnvLoki, "http://127.0.0.1:3100")
+	initOutput()
+	tee, ok := out.(*Tee)
+	assert.True(t, ok)
+	assert.Len(t, tee.sinks, 3)
+	_, ok = tee.sinks[0].W.(*File)
+	assert.True(t, ok)
+	assert.Equal(t, WarnLevel, tee.sinks[1].Level)
+	_, ok = tee.sinks[2].W.(*Loki)
+	assert.True(t, ok)
+	assert.NoError(t, tee.Close())
+}
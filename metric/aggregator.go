@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kind is kind of aggregated metric.
+type Kind int
+
+const (
+	KindCounter Kind = iota
+	KindGauge
+	KindTiming
+	KindHistogram
+)
+
+// Point is metric aggregated since last Flush.
+type Point struct {
+	Name  string
+	Tags  []Tag
+	Kind  Kind
+	Value int64 // counter sum or last gauge value
+	Stats Stats // timings (in milliseconds) and histograms
+}
+
+// Aggregator is driver which aggregates metrics in memory until Flush.
+// It is base for drivers which push metrics periodically (see metric/graphite, metric/cloudwatch).
+// Views created by WithPrefix share points with the parent.
+type Aggregator struct {
+	prefix string
+	*points
+}
+
+type points struct {
+	mu     sync.Mutex
+	points map[string]*Point
+}
+
+// NewAggregator creates empty aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{points: &points{points: make(map[string]*Point)}}
+}
+
+func (a *Aggregator) point(name string, tags []Tag, kind Kind) *Point {
+	name = prefixed(a.prefix, name)
+	key := keyWithTags(name, tags)
+	p, ok := a.points.points[key]
+	if !ok {
+		p = &Point{Name: name, Tags: tags, Kind: kind}
+		a.points.points[key] = p
+	}
+	return p
+}
+
+func (a *Aggregator) Counter(name string, values ...int) {
+	a.CounterTags(name, sum(values), nil)
+}
+
+func (a *Aggregator) Gauge(name string, value int) {
+	a.GaugeTags(name, value, nil)
+}
+
+func (a *Aggregator) Timing(name string, f func()) {
+	sw := NewStopwatch()
+	f()
+	a.Time(name, sw.GetNs())
+}
+
+func (a *Aggregator) Time(name string, duration int) {
+	a.TimeTags(name, duration, nil)
+}
+
+func (a *Aggregator) CounterTags(name string, value int, tags []Tag) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.point(name, tags, KindCounter).Value += int64(value)
+}
+
+func (a *Aggregator) GaugeTags(name string, value int, tags []Tag) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.point(name, tags, KindGauge).Value = int64(value)
+}
+
+// TimeTags observes duration (in nanoseconds) in milliseconds.
+func (a *Aggregator) TimeTags(name string, duration int, tags []Tag) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.point(name, tags, KindTiming).Stats.observe(float64(duration) / float64(time.Millisecond))
+}
+
+func (a *Aggregator) Histogram(name string, value float64, tags []Tag) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.point(name, tags, KindHistogram).Stats.observe(value)
+}
+
+func (a *Aggregator) WithPrefix(prefix string) Metric {
+	return &Aggregator{prefix: prefix, points: a.points}
+}
+
+func (a *Aggregator) AppendSuffix(suffix string) Metric {
+	return a.WithPrefix(prefixed(a.prefix, suffix))
+}
+
+// Flush returns points aggregated since last Flush, sorted by name and tags.
+// Gauges keep last value and are returned on every Flush, until set again.
+func (a *Aggregator) Flush() []Point {
+	a.mu.Lock()
+	points := a.points.points
+	a.points.points = make(map[string]*Point)
+	for k, p := range points {
+		if p.Kind == KindGauge {
+			g := *p
+			a.points.points[k] = &g
+		}
+	}
+	a.mu.Unlock()
+	keys := make([]string, 0, len(points))
+	for k := range points {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ps := make([]Point, len(keys))
+	for i, k := range keys {
+		ps[i] = *points[k]
+	}
+	return ps
+}
@@ -0,0 +1,29 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregatorFlush(t *testing.T) {
+	a := NewAggregator()
+	a.Counter("req", 2)
+	a.WithPrefix("app").Gauge("queue", 7)
+	a.Time("rsp", int(10*time.Millisecond))
+
+	ps := a.Flush()
+	assert.Len(t, ps, 3)
+	assert.Equal(t, "app.queue", ps[0].Name)
+	assert.Equal(t, int64(7), ps[0].Value)
+	assert.Equal(t, "req", ps[1].Name)
+	assert.Equal(t, int64(2), ps[1].Value)
+	assert.Equal(t, 10.0, ps[2].Stats.Sum)
+
+	// only gauges are kept
+	ps = a.Flush()
+	assert.Len(t, ps, 1)
+	assert.Equal(t, "app.queue", ps[0].Name)
+	assert.Equal(t, int64(7), ps[0].Value)
+}
@@ -0,0 +1,94 @@
+package metric
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	// EnvMaxTagValues sets maximum number of distinct values of one tag per metric.
+	EnvMaxTagValues = "SVCKIT_METRIC_MAX_TAG_VALUES"
+	// DefaultMaxTagValues is default maximum number of distinct values of one tag per metric.
+	DefaultMaxTagValues = 100
+	// OtherTagValue replaces tag values over the limit.
+	OtherTagValue = "other"
+
+	metricTruncated = "metric.cardinality.truncated"
+)
+
+var cardinality = newCardinalityGuard(DefaultMaxTagValues)
+
+func init() {
+	if n, err := strconv.Atoi(os.Getenv(EnvMaxTagValues)); err == nil {
+		SetMaxTagValues(n)
+	}
+}
+
+// SetMaxTagValues sets maximum number of distinct values of one tag per metric, 0 disables the limit.
+// Values over the limit are replaced with OtherTagValue and metric.cardinality.truncated counter is incremented.
+// Protects backends from unbounded cardinality caused by user controlled values (user ids, urls...).
+func SetMaxTagValues(n int) {
+	cardinality.mu.Lock()
+	defer cardinality.mu.Unlock()
+	cardinality.max = n
+}
+
+type cardinalityKey struct {
+	metric string
+	tag    string
+}
+
+type cardinalityGuard struct {
+	mu     sync.Mutex
+	max    int
+	values map[cardinalityKey]map[string]struct{}
+}
+
+func newCardinalityGuard(max int) *cardinalityGuard {
+	return &cardinalityGuard{max: max, values: make(map[cardinalityKey]map[string]struct{})}
+}
+
+// guard returns tags with values over the limit replaced with OtherTagValue.
+// Tags slice is copied only if some value is replaced.
+func (g *cardinalityGuard) guard(name string, tags []Tag) ([]Tag, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.max <= 0 {
+		return tags, false
+	}
+	truncated := false
+	for i, t := range tags {
+		k := cardinalityKey{name, t.Key}
+		vs, ok := g.values[k]
+		if !ok {
+			vs = make(map[string]struct{})
+			g.values[k] = vs
+		}
+		if _, ok := vs[t.Value]; ok {
+			continue
+		}
+		if len(vs) < g.max {
+			vs[t.Value] = struct{}{}
+			continue
+		}
+		if !truncated {
+			tags = append([]Tag(nil), tags...)
+			truncated = true
+		}
+		tags[i].Value = OtherTagValue
+	}
+	return tags, truncated
+}
+
+// guardTags applies cardinality limit to tags of metric name.
+func guardTags(name string, tags []Tag) []Tag {
+	if len(tags) == 0 {
+		return tags
+	}
+	tags, truncated := cardinality.guard(name, tags)
+	if truncated {
+		driver.Counter(metricTruncated)
+	}
+	return tags
+}
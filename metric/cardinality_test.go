@@ -0,0 +1,39 @@
+package metric
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCardinalityGuard(t *testing.T) {
+	g := newCardinalityGuard(2)
+	for i := 0; i < 2; i++ {
+		tags, truncated := g.guard("req", []Tag{T("user", fmt.Sprint(i)), T("code", "200")})
+		assert.False(t, truncated)
+		assert.Equal(t, fmt.Sprint(i), tags[0].Value)
+	}
+	in := []Tag{T("user", "2"), T("code", "200")}
+	tags, truncated := g.guard("req", in)
+	assert.True(t, truncated)
+	assert.Equal(t, []Tag{T("user", OtherTagValue), T("code", "200")}, tags)
+	assert.Equal(t, "2", in[0].Value)
+	// known values and other metrics are not affected
+	_, truncated = g.guard("req", []Tag{T("user", "1")})
+	assert.False(t, truncated)
+	_, truncated = g.guard("rsp", []Tag{T("user", "2")})
+	assert.False(t, truncated)
+}
+
+func TestCardinalityTruncatedCounter(t *testing.T) {
+	defer Set(NewNoop())
+	defer func(g *cardinalityGuard) { cardinality = g }(cardinality)
+	cardinality = newCardinalityGuard(1)
+	b := &tagged{counting: counting{counters: map[string]int{}}}
+	Set(b)
+	CounterT("req", 1, T("user", "1"))
+	CounterT("req", 1, T("user", "2"))
+	assert.Equal(t, []Tag{T("user", OtherTagValue)}, b.tags)
+	assert.Equal(t, 1, b.counters[metricTruncated])
+}
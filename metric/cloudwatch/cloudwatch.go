@@ -0,0 +1,271 @@
+// Package cloudwatch is metric backend which sends metrics to AWS CloudWatch (PutMetricData).
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/metric/cloudwatch"
+//	err := cloudwatch.Dial(cloudwatch.Namespace("my_app"))
+//
+// Credentials and region are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN (optional) and AWS_REGION environment variables.
+//
+// Metrics are aggregated and sent every FlushInterval.
+// Counters are sent as Count, gauges as None unit and timings as Milliseconds statistic sets.
+// Tags are sent as dimensions.
+package cloudwatch
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+)
+
+const (
+	service    = "monitoring"
+	apiVersion = "2010-08-01"
+	// batchSize is maximum number of metrics in one PutMetricData request.
+	batchSize = 20
+)
+
+// FlushInterval is interval of sending metrics to CloudWatch.
+var FlushInterval = time.Minute
+
+// Credentials are AWS credentials.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Backend aggregates metrics and sends them to CloudWatch.
+type Backend struct {
+	*metric.Aggregator
+	namespace string
+	region    string
+	endpoint  string
+	creds     Credentials
+	client    *http.Client
+}
+
+// Option is Backend option.
+type Option func(*Backend)
+
+// Namespace sets CloudWatch namespace (default is application name).
+func Namespace(ns string) Option {
+	return func(b *Backend) {
+		b.namespace = ns
+	}
+}
+
+// Region sets AWS region (default is AWS_REGION).
+func Region(r string) Option {
+	return func(b *Backend) {
+		b.region = r
+	}
+}
+
+// Endpoint sets CloudWatch endpoint url (default is https://monitoring.<region>.amazonaws.com).
+func Endpoint(u string) Option {
+	return func(b *Backend) {
+		b.endpoint = u
+	}
+}
+
+// WithCredentials sets AWS credentials (default are from environment).
+func WithCredentials(c Credentials) Option {
+	return func(b *Backend) {
+		b.creds = c
+	}
+}
+
+// Dial creates backend and adds it as metric driver.
+func Dial(opts ...Option) error {
+	b, err := New(opts...)
+	if err != nil {
+		return err
+	}
+	go b.loop()
+	metric.Add(b)
+	logger().S("namespace", b.namespace).S("region", b.region).Info("started")
+	return nil
+}
+
+// New creates backend.
+func New(opts ...Option) (*Backend, error) {
+	b := &Backend{
+		Aggregator: metric.NewAggregator(),
+		namespace:  env.AppName(),
+		region:     os.Getenv("AWS_REGION"),
+		creds: Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	if b.region == "" {
+		return nil, errors.New("cloudwatch region not set")
+	}
+	if b.creds.AccessKeyID == "" || b.creds.SecretAccessKey == "" {
+		return nil, errors.New("cloudwatch credentials not set")
+	}
+	if b.endpoint == "" {
+		b.endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, b.region)
+	}
+	return b, nil
+}
+
+func (b *Backend) loop() {
+	for range time.Tick(FlushInterval) {
+		if err := b.Flush(); err != nil {
+			logger().Error(err)
+		}
+	}
+}
+
+// Flush sends aggregated metrics to CloudWatch.
+// Failed batch doesn't stop sending of the remaining ones, errors of all failed batches are returned.
+func (b *Backend) Flush() error {
+	ps := b.Aggregator.Flush()
+	now := time.Now().UTC()
+	var errs []error
+	for len(ps) > 0 {
+		n := batchSize
+		if n > len(ps) {
+			n = len(ps)
+		}
+		if err := b.put(b.form(ps[:n], now), now); err != nil {
+			errs = append(errs, err)
+		}
+		ps = ps[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// form returns PutMetricData request parameters.
+func (b *Backend) form(ps []metric.Point, now time.Time) url.Values {
+	v := url.Values{
+		"Action":    {"PutMetricData"},
+		"Version":   {apiVersion},
+		"Namespace": {b.namespace},
+	}
+	ts := now.Format(time.RFC3339)
+	for i, p := range ps {
+		m := fmt.Sprintf("MetricData.member.%d.", i+1)
+		v.Set(m+"MetricName", p.Name)
+		v.Set(m+"Timestamp", ts)
+		for j, t := range p.Tags {
+			d := fmt.Sprintf("%sDimensions.member.%d.", m, j+1)
+			v.Set(d+"Name", t.Key)
+			v.Set(d+"Value", t.Value)
+		}
+		switch p.Kind {
+		case metric.KindCounter:
+			v.Set(m+"Value", strconv.FormatInt(p.Value, 10))
+			v.Set(m+"Unit", "Count")
+		case metric.KindGauge:
+			v.Set(m+"Value", strconv.FormatInt(p.Value, 10))
+			v.Set(m+"Unit", "None")
+		default:
+			unit := "None"
+			if p.Kind == metric.KindTiming {
+				unit = "Milliseconds"
+			}
+			v.Set(m+"Unit", unit)
+			v.Set(m+"StatisticValues.SampleCount", strconv.FormatInt(p.Stats.Count, 10))
+			v.Set(m+"StatisticValues.Sum", formatFloat(p.Stats.Sum))
+			v.Set(m+"StatisticValues.Minimum", formatFloat(p.Stats.Min))
+			v.Set(m+"StatisticValues.Maximum", formatFloat(p.Stats.Max))
+		}
+	}
+	return v
+}
+
+func (b *Backend) put(form url.Values, now time.Time) error {
+	body := form.Encode()
+	req, err := http.NewRequest(http.MethodPost, b.endpoint, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	b.sign(req, body, now)
+	rsp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return fmt.Errorf("cloudwatch status %s: %s", rsp.Status, msg)
+	}
+	io.Copy(io.Discard, rsp.Body)
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to the request.
+func (b *Backend) sign(req *http.Request, body string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if b.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.creds.SessionToken)
+	}
+	headers := []string{"content-type", "host", "x-amz-date"}
+	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-date:%s\n",
+		req.Header.Get("Content-Type"), req.URL.Host, amzDate)
+	if b.creds.SessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+		canonicalHeaders += "x-amz-security-token:" + b.creds.SessionToken + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders, signedHeaders, hashHex(body),
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, b.region, service)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex(canonicalRequest)}, "\n")
+	key := hmacSHA256([]byte("AWS4"+b.creds.SecretAccessKey), date)
+	for _, s := range []string{b.region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.metric.cloudwatch")
+}
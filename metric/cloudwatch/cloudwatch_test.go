@@ -0,0 +1,70 @@
+package cloudwatch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlush(t *testing.T) {
+	var forms []map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/monitoring/aws4_request")
+		r.ParseForm()
+		forms = append(forms, r.PostForm)
+	}))
+	defer srv.Close()
+
+	b, err := New(Namespace("app"), Region("eu-west-1"), Endpoint(srv.URL),
+		WithCredentials(Credentials{AccessKeyID: "key", SecretAccessKey: "secret"}))
+	assert.NoError(t, err)
+	for i := 0; i < batchSize; i++ {
+		b.GaugeTags("queue", i, []metric.Tag{metric.T("id", string(rune('a'+i)))})
+	}
+	b.Counter("req", 3)
+	b.Time("rsp", int(10*time.Millisecond))
+	assert.NoError(t, b.Flush())
+
+	assert.Len(t, forms, 2)
+	assert.Equal(t, "PutMetricData", forms[0]["Action"][0])
+	assert.Equal(t, "app", forms[0]["Namespace"][0])
+	assert.Equal(t, "id", forms[0]["MetricData.member.1.Dimensions.member.1.Name"][0])
+	f := forms[1]
+	assert.Equal(t, "req", f["MetricData.member.1.MetricName"][0])
+	assert.Equal(t, "3", f["MetricData.member.1.Value"][0])
+	assert.Equal(t, "Count", f["MetricData.member.1.Unit"][0])
+	assert.Equal(t, "Milliseconds", f["MetricData.member.2.Unit"][0])
+	assert.Equal(t, "10", f["MetricData.member.2.StatisticValues.Maximum"][0])
+}
+
+func TestFlushContinuesAfterFailedBatch(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	b, err := New(Namespace("app"), Region("eu-west-1"), Endpoint(srv.URL),
+		WithCredentials(Credentials{AccessKeyID: "key", SecretAccessKey: "secret"}))
+	assert.NoError(t, err)
+	for i := 0; i < batchSize+1; i++ {
+		b.Counter(fmt.Sprintf("req%03d", i))
+	}
+	assert.Error(t, b.Flush())
+	assert.Equal(t, 2, calls)
+}
+
+func TestNewRequiresCredentials(t *testing.T) {
+	_, err := New(Region("eu-west-1"), WithCredentials(Credentials{}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package metric
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Exemplar links observed value to the trace in which it was observed.
+type Exemplar struct {
+	TraceID string
+	SpanID  string
+}
+
+// Exemplars is implemented by drivers which support exemplars (Prometheus, OTLP).
+// Other drivers get plain timings and histograms.
+type Exemplars interface {
+	TimeExemplar(name string, duration int, tags []Tag, e Exemplar)
+	HistogramExemplar(name string, value float64, tags []Tag, e Exemplar)
+}
+
+var (
+	exemplarExtractorMu sync.RWMutex
+	exemplarExtractor   func(context.Context) Exemplar
+)
+
+// SetExemplarExtractor sets function which finds trace in context.
+// Log package sets it to use the same trace ids as log records (see log.WithTrace and log.SetTraceExtractor).
+func SetExemplarExtractor(f func(context.Context) Exemplar) {
+	exemplarExtractorMu.Lock()
+	defer exemplarExtractorMu.Unlock()
+	exemplarExtractor = f
+}
+
+func exemplarFromContext(ctx context.Context) Exemplar {
+	exemplarExtractorMu.RLock()
+	f := exemplarExtractor
+	exemplarExtractorMu.RUnlock()
+	if f == nil || ctx == nil {
+		return Exemplar{}
+	}
+	return f(ctx)
+}
+
+// TimeContext submits timing with exemplar of the trace in ctx.
+func TimeContext(ctx context.Context, name string, duration int, tags ...Tag) {
+	e := exemplarFromContext(ctx)
+	if x, ok := driver.(Exemplars); ok && e.TraceID != "" {
+		x.TimeExemplar(name, duration, guardTags(name, tags), e)
+		return
+	}
+	Time(name, duration, tags...)
+}
+
+// ObserveContext observes value in histogram with exemplar of the trace in ctx.
+func ObserveContext(ctx context.Context, name string, value float64, tags ...Tag) {
+	e := exemplarFromContext(ctx)
+	if x, ok := driver.(Exemplars); ok && e.TraceID != "" {
+		x.HistogramExemplar(name, value, guardTags(name, tags), e)
+		return
+	}
+	Observe(name, value, tags...)
+}
+
+// TimedContext is Timed which submits timing with exemplar of the trace in ctx.
+//
+//	defer metric.TimedContext(ctx, "handle")()
+func TimedContext(ctx context.Context, name string, tags ...Tag) func() {
+	start := time.Now()
+	return func() {
+		TimeContext(ctx, name, int(time.Since(start)), tags...)
+	}
+}
+
+func (m Multi) TimeExemplar(name string, duration int, tags []Tag, e Exemplar) {
+	for _, d := range m {
+		if x, ok := d.(Exemplars); ok {
+			x.TimeExemplar(name, duration, tags, e)
+			continue
+		}
+		timing(d, name, duration, tags)
+	}
+}
+
+func (m Multi) HistogramExemplar(name string, value float64, tags []Tag, e Exemplar) {
+	for _, d := range m {
+		if x, ok := d.(Exemplars); ok {
+			x.HistogramExemplar(name, value, tags, e)
+			continue
+		}
+		observe(d, name, value, tags)
+	}
+}
@@ -0,0 +1,40 @@
+package metric
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exemplars struct {
+	timings
+	exemplars []Exemplar
+}
+
+func (e *exemplars) TimeExemplar(name string, duration int, tags []Tag, ex Exemplar) {
+	e.exemplars = append(e.exemplars, ex)
+}
+
+func (e *exemplars) HistogramExemplar(name string, value float64, tags []Tag, ex Exemplar) {}
+
+type traceKey struct{}
+
+func TestTimeContext(t *testing.T) {
+	defer Set(NewNoop())
+	defer SetExemplarExtractor(nil)
+	SetExemplarExtractor(func(ctx context.Context) Exemplar {
+		id, _ := ctx.Value(traceKey{}).(string)
+		return Exemplar{TraceID: id}
+	})
+	a := &timings{times: map[string]int{}}
+	b := &exemplars{timings: timings{times: map[string]int{}}}
+	Set(Multi{a, b})
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "abc")
+	TimeContext(ctx, "rsp", 10)
+	TimedContext(context.Background(), "rsp")()
+	assert.Equal(t, 2, a.times["rsp"])
+	assert.Equal(t, 1, b.times["rsp"])
+	assert.Equal(t, []Exemplar{{TraceID: "abc"}}, b.exemplars)
+}
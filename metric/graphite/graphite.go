@@ -0,0 +1,145 @@
+// Package graphite is metric backend which sends metrics to Graphite using plaintext protocol.
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/metric/graphite"
+//	err := graphite.Dial()
+//
+// Graphite is discovered in Consul, or address can be given explicitly:
+//
+//	err := graphite.Dial("127.0.0.1:2003")
+//
+// Metrics are aggregated and sent every FlushInterval, prefixed with app.node.
+// Counters are sent as sum in interval, gauges as last value and timings (in milliseconds) as
+// name.count, name.sum, name.mean, name.min and name.max.
+// Tags are sent as Graphite tags (name;key=value).
+package graphite
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/signal"
+)
+
+// ServiceName is Graphite plaintext service name in service discovery.
+const ServiceName = "graphite"
+
+// FlushInterval is interval of sending metrics to Graphite.
+var FlushInterval = 10 * time.Second
+
+// Dial creates backend and adds it as metric driver.
+//
+//	opts[0] - graphite address, if not set it is discovered in Consul
+func Dial(opts ...string) error {
+	addr := ""
+	if len(opts) > 0 {
+		addr = opts[0]
+	} else {
+		err := signal.WithExponentialBackoff(func() error {
+			a, err := dcy.Service(ServiceName)
+			addr = a.String()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	b := New(addr, fmt.Sprintf("%s.%s", env.AppName(), env.NodeName()))
+	go b.loop()
+	metric.Add(b)
+	logger().S("addr", addr).Info("connected")
+	return nil
+}
+
+// Backend aggregates metrics and sends them to Graphite.
+type Backend struct {
+	*metric.Aggregator
+	addr   string
+	prefix string
+}
+
+// New creates backend for Graphite at addr (host:port), with prefix added to all metric names.
+func New(addr, prefix string) *Backend {
+	return &Backend{Aggregator: metric.NewAggregator(), addr: addr, prefix: prefix}
+}
+
+func (b *Backend) loop() {
+	for range time.Tick(FlushInterval) {
+		if err := b.Flush(); err != nil {
+			logger().Error(err)
+		}
+	}
+}
+
+// Flush sends aggregated metrics to Graphite.
+func (b *Backend) Flush() error {
+	ps := b.Aggregator.Flush()
+	if len(ps) == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", b.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	w := bufio.NewWriter(conn)
+	b.write(w, ps, time.Now())
+	return w.Flush()
+}
+
+func (b *Backend) write(w io.Writer, ps []metric.Point, now time.Time) {
+	ts := now.Unix()
+	for _, p := range ps {
+		line := func(suffix string, value interface{}) {
+			fmt.Fprintf(w, "%s %v %d\n", b.path(p.Name+suffix, p.Tags), value, ts)
+		}
+		switch p.Kind {
+		case metric.KindCounter, metric.KindGauge:
+			line("", p.Value)
+		default:
+			line(".count", p.Stats.Count)
+			line(".sum", p.Stats.Sum)
+			line(".mean", p.Stats.Sum/float64(p.Stats.Count))
+			line(".min", p.Stats.Min)
+			line(".max", p.Stats.Max)
+		}
+	}
+}
+
+// path returns Graphite metric path with tags.
+func (b *Backend) path(name string, tags []metric.Tag) string {
+	if b.prefix != "" {
+		name = b.prefix + "." + name
+	}
+	var sb strings.Builder
+	sb.WriteString(clean(name))
+	for _, t := range tags {
+		fmt.Fprintf(&sb, ";%s=%s", clean(t.Key), clean(t.Value))
+	}
+	return sb.String()
+}
+
+// clean replaces characters not allowed in path and tags.
+func clean(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', ';', '=', '~', '!', '^', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.metric.graphite")
+}
@@ -0,0 +1,54 @@
+package graphite
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlush(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		close(lines)
+	}()
+
+	b := New(ln.Addr().String(), "app.node")
+	b.Counter("req", 2)
+	b.Counter("req", 3)
+	b.GaugeTags("queue", 7, []metric.Tag{metric.T("topic", "a b")})
+	b.Time("rsp", int(10*time.Millisecond))
+	b.Time("rsp", int(30*time.Millisecond))
+	assert.NoError(t, b.Flush())
+
+	var got []string
+	for l := range lines {
+		// strip timestamp
+		got = append(got, l[:len(l)-11])
+	}
+	assert.Equal(t, []string{
+		"app.node.queue;topic=a_b 7",
+		"app.node.req 5",
+		"app.node.rsp.count 2",
+		"app.node.rsp.sum 40",
+		"app.node.rsp.mean 20",
+		"app.node.rsp.min 10",
+		"app.node.rsp.max 30",
+	}, got)
+}
@@ -0,0 +1,108 @@
+// Package grpcmetric records gRPC call metrics, consistent with httpi request metrics:
+//
+//	grpc.server.requests, grpc.client.requests   counter
+//	grpc.server.duration, grpc.client.duration   timing
+//
+// tagged with service, method, type (unary, stream) and code (OK, NotFound...).
+//
+// svckit doesn't depend on grpc, so interceptors are thin adapters around Server and Client:
+//
+//	grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (rsp any, err error) {
+//		err = grpcmetric.Server(info.FullMethod, grpcmetric.Unary, func() error {
+//			rsp, err = h(ctx, req)
+//			return err
+//		})
+//		return
+//	})
+//
+//	grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+//		return grpcmetric.Server(info.FullMethod, grpcmetric.Stream, func() error { return h(srv, ss) })
+//	})
+//
+//	grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, rsp any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+//		return grpcmetric.Client(method, grpcmetric.Unary, func() error { return invoker(ctx, method, req, rsp, cc, opts...) })
+//	})
+package grpcmetric
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+)
+
+// Call types.
+const (
+	Unary  = "unary"
+	Stream = "stream"
+)
+
+// Server records server side call of fullMethod (/package.Service/Method).
+func Server(fullMethod, typ string, call func() error) error {
+	return record("grpc.server.", fullMethod, typ, call)
+}
+
+// Client records client side call of fullMethod (/package.Service/Method).
+func Client(fullMethod, typ string, call func() error) error {
+	return record("grpc.client.", fullMethod, typ, call)
+}
+
+func record(prefix, fullMethod, typ string, call func() error) error {
+	start := time.Now()
+	err := call()
+	duration := time.Since(start)
+	service, method := splitMethod(fullMethod)
+	tags := []metric.Tag{
+		metric.T("service", service),
+		metric.T("method", method),
+		metric.T("type", typ),
+		metric.T("code", Code(err)),
+	}
+	metric.CounterT(prefix+"requests", 1, tags...)
+	metric.Time(prefix+"duration", int(duration.Nanoseconds()), tags...)
+	return err
+}
+
+// splitMethod splits /package.Service/Method into service and method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+// Code returns name of the gRPC status code of the error.
+// Errors which are not gRPC status errors (without GRPCStatus method) are Unknown,
+// context errors are Canceled and DeadlineExceeded.
+func Code(err error) string {
+	if err == nil {
+		return "OK"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "Canceled"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "DeadlineExceeded"
+	}
+	// status.Status.Code() returns codes.Code which implements Stringer
+	m := reflect.ValueOf(err).MethodByName("GRPCStatus")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return "Unknown"
+	}
+	st := m.Call(nil)[0]
+	if st.Kind() == reflect.Ptr && st.IsNil() {
+		return "Unknown"
+	}
+	code := st.MethodByName("Code")
+	if !code.IsValid() || code.Type().NumIn() != 0 || code.Type().NumOut() != 1 {
+		return "Unknown"
+	}
+	if s, ok := code.Call(nil)[0].Interface().(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "Unknown"
+}
@@ -0,0 +1,53 @@
+package grpcmetric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+type code uint32
+
+func (c code) String() string { return "NotFound" }
+
+type status struct{}
+
+func (*status) Code() code { return 5 }
+
+type statusError struct{}
+
+func (statusError) Error() string       { return "not found" }
+func (statusError) GRPCStatus() *status { return &status{} }
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, "OK", Code(nil))
+	assert.Equal(t, "Unknown", Code(errors.New("failed")))
+	assert.Equal(t, "DeadlineExceeded", Code(fmt.Errorf("call: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "NotFound", Code(statusError{}))
+}
+
+type recorder struct {
+	metric.Noop
+	tags map[string][]metric.Tag
+}
+
+func (r *recorder) CounterTags(name string, value int, tags []metric.Tag)   { r.tags[name] = tags }
+func (r *recorder) GaugeTags(name string, value int, tags []metric.Tag)     {}
+func (r *recorder) TimeTags(name string, duration int, tags []metric.Tag)   { r.tags[name] = tags }
+func (r *recorder) Histogram(name string, value float64, tags []metric.Tag) {}
+
+func TestServer(t *testing.T) {
+	rec := &recorder{tags: map[string][]metric.Tag{}}
+	metric.Set(rec)
+	defer metric.Set(metric.NewNoop())
+
+	err := Server("/pkg.Users/Get", Unary, func() error { return statusError{} })
+	assert.Equal(t, statusError{}, err)
+	expected := []metric.Tag{metric.T("service", "pkg.Users"), metric.T("method", "Get"), metric.T("type", "unary"), metric.T("code", "NotFound")}
+	assert.Equal(t, expected, rec.tags["grpc.server.requests"])
+	assert.Equal(t, expected, rec.tags["grpc.server.duration"])
+}
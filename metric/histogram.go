@@ -0,0 +1,166 @@
+package metric
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HistogramInterval is interval of reporting histogram percentiles.
+var HistogramInterval = 10 * time.Second
+
+// Percentiles reported for each histogram.
+var Percentiles = []float64{50, 95, 99}
+
+const (
+	// values below are counted exactly, above are in log-linear buckets with ~3% precision
+	histogramSubBuckets = 32
+	histogramExact      = 2 * histogramSubBuckets
+)
+
+// Histogram aggregates values client-side and reports percentiles every HistogramInterval
+// as gauges name.p50, name.p95, name.p99 with name.count and name.max.
+// After each report histogram is reset.
+//
+// Example:
+//
+//	h := metric.NewHistogram("db.query")
+//	h.ObserveDuration(time.Since(start))
+type Histogram struct {
+	name string
+
+	mu      sync.Mutex
+	buckets map[int]uint64
+	count   uint64
+	max     int
+}
+
+var (
+	histogramsMu sync.Mutex
+	histograms   = map[string]*Histogram{}
+	reporterOnce sync.Once
+)
+
+// NewHistogram returns histogram registered under name.
+// Same histogram is returned for the same name.
+func NewHistogram(name string) *Histogram {
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+	if h, ok := histograms[name]; ok {
+		return h
+	}
+	h := &Histogram{name: name, buckets: make(map[int]uint64)}
+	histograms[name] = h
+	reporterOnce.Do(func() { go reportHistograms() })
+	return h
+}
+
+// Observe adds non negative value.
+func (h *Histogram) Observe(v int) {
+	if v < 0 {
+		v = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buckets[bucketIndex(v)]++
+	h.count++
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// ObserveDuration adds duration in nanoseconds, as Time does.
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(int(d))
+}
+
+// Percentile returns approximate value below which are p percent of observed values.
+func (h *Histogram) Percentile(p float64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.percentile(p)
+}
+
+func (h *Histogram) percentile(p float64) int {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(p/100*float64(h.count) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	idxs := make([]int, 0, len(h.buckets))
+	for i := range h.buckets {
+		idxs = append(idxs, i)
+	}
+	sort.Ints(idxs)
+	var seen uint64
+	for _, i := range idxs {
+		seen += h.buckets[i]
+		if seen >= rank {
+			if v := bucketValue(i); v < h.max {
+				return v
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// report sends percentiles and resets histogram.
+func (h *Histogram) report() {
+	h.mu.Lock()
+	if h.count == 0 {
+		h.mu.Unlock()
+		return
+	}
+	values := make([]int, len(Percentiles))
+	for i, p := range Percentiles {
+		values[i] = h.percentile(p)
+	}
+	count, max := h.count, h.max
+	h.buckets = make(map[int]uint64)
+	h.count, h.max = 0, 0
+	h.mu.Unlock()
+
+	for i, p := range Percentiles {
+		Gauge(fmt.Sprintf("%s.p%g", h.name, p), values[i])
+	}
+	Gauge(h.name+".count", int(count))
+	Gauge(h.name+".max", max)
+}
+
+func reportHistograms() {
+	for range time.Tick(HistogramInterval) {
+		histogramsMu.Lock()
+		hs := make([]*Histogram, 0, len(histograms))
+		for _, h := range histograms {
+			hs = append(hs, h)
+		}
+		histogramsMu.Unlock()
+		for _, h := range hs {
+			h.report()
+		}
+	}
+}
+
+// bucketIndex returns log-linear bucket of the value.
+func bucketIndex(v int) int {
+	if v < histogramExact {
+		return v
+	}
+	shift := bits.Len64(uint64(v)) - 6
+	return histogramSubBuckets*shift + v>>uint(shift)
+}
+
+// bucketValue returns middle value of the bucket.
+func bucketValue(i int) int {
+	if i < histogramExact {
+		return i
+	}
+	shift := uint(i/histogramSubBuckets - 1)
+	m := i%histogramSubBuckets + histogramSubBuckets
+	return m<<shift + (1<<shift)/2
+}
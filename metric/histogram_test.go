@@ -0,0 +1,43 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gauges struct {
+	Noop
+	values map[string]int
+}
+
+func (g *gauges) Gauge(name string, value int) {
+	g.values[name] = value
+}
+
+func TestBucketIndex(t *testing.T) {
+	for _, v := range []int{0, 1, 63, 64, 65, 100, 1000, 123456, 1 << 40} {
+		b := bucketValue(bucketIndex(v))
+		assert.InEpsilon(t, float64(v+1), float64(b+1), 0.03, "%d -> %d", v, b)
+	}
+	assert.Equal(t, bucketIndex(95)+1, bucketIndex(96))
+}
+
+func TestHistogram(t *testing.T) {
+	h := &Histogram{name: "rsp", buckets: map[int]uint64{}}
+	for i := 1; i <= 1000; i++ {
+		h.Observe(i)
+	}
+	assert.InEpsilon(t, 500, h.Percentile(50), 0.03)
+	assert.InEpsilon(t, 990, h.Percentile(99), 0.03)
+	assert.Equal(t, 1000, h.Percentile(100))
+
+	defer Set(NewNoop())
+	g := &gauges{values: map[string]int{}}
+	Set(g)
+	h.report()
+	assert.InEpsilon(t, 950, g.values["rsp.p95"], 0.03)
+	assert.Equal(t, 1000, g.values["rsp.count"])
+	assert.Equal(t, 1000, g.values["rsp.max"])
+	assert.Equal(t, 0, h.Percentile(50))
+}
@@ -0,0 +1,214 @@
+package metric
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/env"
+)
+
+// EnvMemory enables in-memory registry when set to 1 or true.
+// In dev environment (see env.InDev) it is enabled by default.
+const EnvMemory = "SVCKIT_METRIC_MEMORY"
+
+var memory = NewMemory()
+
+func init() {
+	expvar.Publish("svckit.metrics", expvar.Func(func() interface{} {
+		return memory.Snapshot()
+	}))
+}
+
+func initMemory() {
+	switch os.Getenv(EnvMemory) {
+	case "1", "true":
+		EnableMemory()
+	case "":
+		if env.InDev() {
+			EnableMemory()
+		}
+	}
+}
+
+// EnableMemory adds in-memory registry as metric driver.
+// Current values are available through MemoryHandler (/debug/metrics of httpi) and expvar (svckit.metrics).
+func EnableMemory() {
+	Add(memory)
+}
+
+// MemoryHandler serves current values of the in-memory registry as json.
+func MemoryHandler() http.Handler {
+	return memory
+}
+
+// Memory is driver which keeps current metric values in memory.
+// Tagged metrics are kept under name{key=value,...}.
+// Views created by WithPrefix share values with the parent.
+type Memory struct {
+	prefix string
+	*values
+}
+
+type values struct {
+	mu         sync.Mutex
+	counters   map[string]int64
+	gauges     map[string]int64
+	timings    map[string]*Stats
+	histograms map[string]*Stats
+}
+
+// Stats is summary of observed values.
+// Timings are in milliseconds.
+type Stats struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Last  float64 `json:"last"`
+}
+
+func (s *Stats) observe(v float64) {
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	s.Sum += v
+	s.Last = v
+}
+
+// Snapshot is copy of the in-memory registry values.
+type Snapshot struct {
+	Counters   map[string]int64 `json:"counters"`
+	Gauges     map[string]int64 `json:"gauges"`
+	Timings    map[string]Stats `json:"timings"`
+	Histograms map[string]Stats `json:"histograms"`
+}
+
+// NewMemory creates empty in-memory registry.
+func NewMemory() *Memory {
+	return &Memory{values: &values{
+		counters:   make(map[string]int64),
+		gauges:     make(map[string]int64),
+		timings:    make(map[string]*Stats),
+		histograms: make(map[string]*Stats),
+	}}
+}
+
+func (m *Memory) key(name string, tags []Tag) string {
+	return keyWithTags(prefixed(m.prefix, name), tags)
+}
+
+func (m *Memory) Counter(name string, values ...int) {
+	m.CounterTags(name, sum(values), nil)
+}
+
+func (m *Memory) Gauge(name string, value int) {
+	m.GaugeTags(name, value, nil)
+}
+
+func (m *Memory) Timing(name string, f func()) {
+	sw := NewStopwatch()
+	f()
+	m.Time(name, sw.GetNs())
+}
+
+func (m *Memory) Time(name string, duration int) {
+	m.TimeTags(name, duration, nil)
+}
+
+func (m *Memory) CounterTags(name string, value int, tags []Tag) {
+	name = m.key(name, tags)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name] += int64(value)
+}
+
+func (m *Memory) GaugeTags(name string, value int, tags []Tag) {
+	name = m.key(name, tags)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = int64(value)
+}
+
+// TimeTags observes duration (in nanoseconds) in milliseconds.
+func (m *Memory) TimeTags(name string, duration int, tags []Tag) {
+	m.observe(m.timings, m.key(name, tags), float64(duration)/float64(time.Millisecond))
+}
+
+func (m *Memory) Histogram(name string, value float64, tags []Tag) {
+	m.observe(m.histograms, m.key(name, tags), value)
+}
+
+func (m *Memory) WithPrefix(prefix string) Metric {
+	return &Memory{prefix: prefix, values: m.values}
+}
+
+func (m *Memory) AppendSuffix(suffix string) Metric {
+	return m.WithPrefix(prefixed(m.prefix, suffix))
+}
+
+func (m *Memory) observe(stats map[string]*Stats, name string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := stats[name]
+	if !ok {
+		s = &Stats{}
+		stats[name] = s
+	}
+	s.observe(v)
+}
+
+// Snapshot returns copy of current values.
+func (m *Memory) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := Snapshot{
+		Counters:   make(map[string]int64, len(m.counters)),
+		Gauges:     make(map[string]int64, len(m.gauges)),
+		Timings:    make(map[string]Stats, len(m.timings)),
+		Histograms: make(map[string]Stats, len(m.histograms)),
+	}
+	for k, v := range m.counters {
+		s.Counters[k] = v
+	}
+	for k, v := range m.gauges {
+		s.Gauges[k] = v
+	}
+	for k, v := range m.timings {
+		s.Timings[k] = *v
+	}
+	for k, v := range m.histograms {
+		s.Histograms[k] = *v
+	}
+	return s
+}
+
+// ServeHTTP writes snapshot as json.
+func (m *Memory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(m.Snapshot())
+}
+
+// keyWithTags returns name{k1=v1,k2=v2} with tags sorted by key.
+func keyWithTags(name string, tags []Tag) string {
+	if len(tags) == 0 {
+		return name
+	}
+	kv := make([]string, len(tags))
+	for i, t := range tags {
+		kv[i] = t.Key + "=" + t.Value
+	}
+	sort.Strings(kv)
+	return name + "{" + strings.Join(kv, ",") + "}"
+}
@@ -0,0 +1,35 @@
+package metric
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	defer Set(NewNoop())
+	m := NewMemory()
+	Set(m)
+	Counter("req", 2)
+	CounterT("req", 1, T("code", "200"), T("route", "/"))
+	Gauge("queue", 5)
+	Time("rsp", int(10*time.Millisecond))
+	Time("rsp", int(20*time.Millisecond))
+	WithPrefix("pero").Counter("req")
+
+	s := m.Snapshot()
+	assert.Equal(t, int64(2), s.Counters["req"])
+	assert.Equal(t, int64(1), s.Counters["req{code=200,route=/}"])
+	assert.Equal(t, int64(1), s.Counters["pero.req"])
+	assert.Equal(t, int64(5), s.Gauges["queue"])
+	assert.Equal(t, Stats{Count: 2, Sum: 30, Min: 10, Max: 20, Last: 20}, s.Timings["rsp"])
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/debug/metrics", nil))
+	var s2 Snapshot
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &s2))
+	assert.Equal(t, s, s2)
+}
@@ -17,13 +17,34 @@
 //    metric.Gauge("my_gauge", 123)
 //    metric.Timing("my_gauge", func() { do_something } )
 // Depending on the currently set driver metric will be sent ...somewhere.
+//
+// Several drivers can be active together (see Add and Multi),
+// e.g. statsd and Prometheus during migration.
+// Drivers which implement Tagged get tags and histograms.
 package metric
 
+import (
+	"os"
+	"time"
+
+	"github.com/minus5/svckit/env"
+)
+
+// EnvRuntime enables Go runtime metrics collection, value is interval (e.g. 10s).
+const EnvRuntime = "SVCKIT_METRIC_RUNTIME"
+
 var driver Metric
 
 // Set the default driver.
 func init() {
 	driver = NewNoop()
+	initMemory()
+	if d, err := time.ParseDuration(os.Getenv(EnvRuntime)); err == nil && d > 0 {
+		CollectRuntime(d)
+	}
+	if d, err := time.ParseDuration(os.Getenv(EnvProcess)); err == nil && d > 0 {
+		CollectProcess(d)
+	}
 }
 
 // Interface definition for all metric implementations.
@@ -41,25 +62,101 @@ func Set(d Metric) {
 	driver = d
 }
 
+// Get returns current driver.
+func Get() Metric {
+	return driver
+}
+
+// Add driver alongside the current one, metrics are sent to both.
+// Driver gets build.info gauge with version, commit and go_version tags.
+func Add(d Metric) {
+	defer buildInfo(d)
+	if _, ok := driver.(*Noop); ok {
+		driver = d
+		return
+	}
+	if m, ok := driver.(Multi); ok {
+		driver = append(m, d)
+		return
+	}
+	driver = Multi{driver, d}
+}
+
+// Replace replaces driver old, set directly or added with Add, with d.
+// If old is not found d is added. Old must be comparable (e.g. pointer).
+func Replace(old, d Metric) {
+	if old != nil {
+		if driver == old {
+			defer buildInfo(d)
+			driver = d
+			return
+		}
+		if m, ok := driver.(Multi); ok {
+			for i, o := range m {
+				if o == old {
+					defer buildInfo(d)
+					r := make(Multi, len(m))
+					copy(r, m)
+					r[i] = d
+					driver = r
+					return
+				}
+			}
+		}
+	}
+	Add(d)
+}
+
+// buildInfo sends build.info gauge (always 1) with build metadata as tags.
+func buildInfo(d Metric) {
+	b := env.Build()
+	var tags []Tag
+	for _, t := range []Tag{T("version", b.Version), T("commit", b.Commit), T("go_version", b.GoVersion)} {
+		if t.Value != "" {
+			tags = append(tags, t)
+		}
+	}
+	gauge(d, "build.info", 1, tags)
+}
+
 // Increments counter name for sum(values)
 // If called witohout values will increment for 1
 func Counter(name string, values ...int) {
 	driver.Counter(name, values...)
 }
 
+// CounterT increments tagged counter name for value.
+//
+//	metric.CounterT("req", 1, metric.T("route", route), metric.T("code", code))
+func CounterT(name string, value int, tags ...Tag) {
+	count(driver, name, value, guardTags(name, tags))
+}
+
 // Submits/Updates a gauge type.
-func Gauge(name string, value int) {
-	driver.Gauge(name, value)
+func Gauge(name string, value int, tags ...Tag) {
+	gauge(driver, name, value, guardTags(name, tags))
 }
 
 // Measures execution time for f and submits it as timing type.
-func Timing(name string, f func()) {
-	driver.Timing(name, f)
+func Timing(name string, f func(), tags ...Tag) {
+	if len(tags) == 0 {
+		driver.Timing(name, f)
+		return
+	}
+	sw := NewStopwatch()
+	f()
+	Time(name, sw.GetNs(), tags...)
 }
 
 // Submits a statsd type.
-func Time(name string, duration int) {
-	driver.Time(name, duration)
+func Time(name string, duration int, tags ...Tag) {
+	timing(driver, name, duration, guardTags(name, tags))
+}
+
+// Observes value in driver histogram (for client side histogram see NewHistogram).
+// Drivers without histogram support get value as gauge.
+func Observe(name string, value float64, tags ...Tag) {
+	observe(driver, name, value, guardTags(name, tags))
 }
 
 // Returns a Metric with a different prefix
@@ -0,0 +1,244 @@
+// Package metrictest provides recorder backend for asserting metrics in unit tests.
+//
+// Usage:
+//
+//	func TestHandler(t *testing.T) {
+//		rec := metrictest.Start(t)
+//		handle()
+//		rec.AssertCount(t, "req", 1, metric.T("code", "200"))
+//		rec.AssertTimingBetween(t, "rsp", 0, time.Second)
+//	}
+package metrictest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+)
+
+// Recorder records all metrics submitted while it is set as metric driver (see Start).
+type Recorder struct {
+	mu         sync.Mutex
+	counts     []record
+	gauges     []record
+	timings    []record
+	histograms []record
+}
+
+type record struct {
+	name  string
+	tags  []metric.Tag
+	value float64
+}
+
+// New creates empty recorder.
+func New() *Recorder {
+	return &Recorder{}
+}
+
+// Start sets new recorder as metric driver.
+// Previous driver is restored when the test finishes.
+func Start(t testing.TB) *Recorder {
+	prev := metric.Get()
+	r := New()
+	metric.Set(r.driver(""))
+	t.Cleanup(func() { metric.Set(prev) })
+	return r
+}
+
+func (r *Recorder) add(rs *[]record, name string, tags []metric.Tag, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*rs = append(*rs, record{name: name, tags: tags, value: value})
+}
+
+// driver is metric driver which records to r, with prefix added to names.
+type driver struct {
+	prefix string
+	r      *Recorder
+}
+
+func (r *Recorder) driver(prefix string) *driver {
+	return &driver{prefix: prefix, r: r}
+}
+
+func (d *driver) name(name string) string {
+	if d.prefix == "" {
+		return name
+	}
+	return d.prefix + "." + name
+}
+
+func (d *driver) Counter(name string, values ...int) {
+	value := 1
+	if len(values) > 0 {
+		value = 0
+		for _, v := range values {
+			value += v
+		}
+	}
+	d.CounterTags(name, value, nil)
+}
+
+func (d *driver) Gauge(name string, value int) {
+	d.GaugeTags(name, value, nil)
+}
+
+func (d *driver) Timing(name string, f func()) {
+	sw := metric.NewStopwatch()
+	f()
+	d.Time(name, sw.GetNs())
+}
+
+func (d *driver) Time(name string, duration int) {
+	d.TimeTags(name, duration, nil)
+}
+
+func (d *driver) CounterTags(name string, value int, tags []metric.Tag) {
+	d.r.add(&d.r.counts, d.name(name), tags, float64(value))
+}
+
+func (d *driver) GaugeTags(name string, value int, tags []metric.Tag) {
+	d.r.add(&d.r.gauges, d.name(name), tags, float64(value))
+}
+
+func (d *driver) TimeTags(name string, duration int, tags []metric.Tag) {
+	d.r.add(&d.r.timings, d.name(name), tags, float64(duration))
+}
+
+func (d *driver) Histogram(name string, value float64, tags []metric.Tag) {
+	d.r.add(&d.r.histograms, d.name(name), tags, value)
+}
+
+func (d *driver) WithPrefix(prefix string) metric.Metric {
+	return d.r.driver(prefix)
+}
+
+func (d *driver) AppendSuffix(suffix string) metric.Metric {
+	return d.r.driver(d.name(suffix))
+}
+
+// find returns values of records with name and all of the tags.
+func (r *Recorder) find(rs []record, name string, tags []metric.Tag) []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var vs []float64
+	for _, rec := range rs {
+		if rec.name == name && hasTags(rec.tags, tags) {
+			vs = append(vs, rec.value)
+		}
+	}
+	return vs
+}
+
+func hasTags(tags, want []metric.Tag) bool {
+	for _, w := range want {
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Counter returns sum of counter name with tags.
+func (r *Recorder) Counter(name string, tags ...metric.Tag) int {
+	n := 0
+	for _, v := range r.find(r.counts, name, tags) {
+		n += int(v)
+	}
+	return n
+}
+
+// LastGauge returns last value of gauge name with tags and false if gauge was not set.
+func (r *Recorder) LastGauge(name string, tags ...metric.Tag) (int, bool) {
+	vs := r.find(r.gauges, name, tags)
+	if len(vs) == 0 {
+		return 0, false
+	}
+	return int(vs[len(vs)-1]), true
+}
+
+// Timings returns all timings name with tags.
+func (r *Recorder) Timings(name string, tags ...metric.Tag) []time.Duration {
+	var ds []time.Duration
+	for _, v := range r.find(r.timings, name, tags) {
+		ds = append(ds, time.Duration(v))
+	}
+	return ds
+}
+
+// Histograms returns all observed values of histogram name with tags.
+func (r *Recorder) Histograms(name string, tags ...metric.Tag) []float64 {
+	return r.find(r.histograms, name, tags)
+}
+
+// Reset removes all recorded metrics.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts, r.gauges, r.timings, r.histograms = nil, nil, nil, nil
+}
+
+// AssertCount checks that counter name with tags has value expected.
+func (r *Recorder) AssertCount(t testing.TB, name string, expected int, tags ...metric.Tag) bool {
+	t.Helper()
+	if n := r.Counter(name, tags...); n != expected {
+		t.Errorf("counter %s%v: expected %d, got %d", name, tags, expected, n)
+		return false
+	}
+	return true
+}
+
+// AssertGauge checks that last value of gauge name with tags is expected.
+func (r *Recorder) AssertGauge(t testing.TB, name string, expected int, tags ...metric.Tag) bool {
+	t.Helper()
+	v, ok := r.LastGauge(name, tags...)
+	if !ok {
+		t.Errorf("gauge %s%v not set", name, tags)
+		return false
+	}
+	if v != expected {
+		t.Errorf("gauge %s%v: expected %d, got %d", name, tags, expected, v)
+		return false
+	}
+	return true
+}
+
+// AssertTimingBetween checks that timing name with tags is recorded
+// and that all recorded durations are in [min, max].
+func (r *Recorder) AssertTimingBetween(t testing.TB, name string, min, max time.Duration, tags ...metric.Tag) bool {
+	t.Helper()
+	ds := r.Timings(name, tags...)
+	if len(ds) == 0 {
+		t.Errorf("timing %s%v not recorded", name, tags)
+		return false
+	}
+	for _, d := range ds {
+		if d < min || d > max {
+			t.Errorf("timing %s%v: %s not in [%s, %s]", name, tags, d, min, max)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertNotRecorded checks that no metric name with tags is recorded.
+func (r *Recorder) AssertNotRecorded(t testing.TB, name string, tags ...metric.Tag) bool {
+	t.Helper()
+	n := len(r.find(r.counts, name, tags)) + len(r.find(r.gauges, name, tags)) +
+		len(r.find(r.timings, name, tags)) + len(r.find(r.histograms, name, tags))
+	if n > 0 {
+		t.Errorf("metric %s%v: recorded %d times", name, tags, n)
+		return false
+	}
+	return true
+}
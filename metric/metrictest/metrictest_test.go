@@ -0,0 +1,57 @@
+package metrictest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTB records errors of failing assertions.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	rec := Start(t)
+	metric.CounterT("req", 1, metric.T("code", "200"), metric.T("route", "/"))
+	metric.CounterT("req", 2, metric.T("code", "500"))
+	metric.Gauge("queue", 3)
+	metric.Gauge("queue", 4)
+	metric.Time("rsp", int(10*time.Millisecond))
+	metric.WithPrefix("pero").Counter("req")
+
+	rec.AssertCount(t, "req", 3)
+	rec.AssertCount(t, "req", 1, metric.T("code", "200"))
+	rec.AssertCount(t, "pero.req", 1)
+	rec.AssertGauge(t, "queue", 4)
+	rec.AssertTimingBetween(t, "rsp", 5*time.Millisecond, 20*time.Millisecond)
+	rec.AssertNotRecorded(t, "other")
+
+	// failing assertions
+	ft := &fakeTB{}
+	assert.False(t, rec.AssertCount(ft, "req", 1))
+	assert.False(t, rec.AssertTimingBetween(ft, "rsp", 0, time.Millisecond))
+	assert.False(t, rec.AssertTimingBetween(ft, "missing", 0, time.Second))
+	assert.False(t, rec.AssertGauge(ft, "missing", 0))
+	assert.False(t, rec.AssertNotRecorded(ft, "queue"))
+	assert.Equal(t, []string{
+		"counter req[]: expected 1, got 3",
+		"timing rsp[]: 10ms not in [0s, 1ms]",
+		"timing missing[] not recorded",
+		"gauge missing[] not set",
+		"metric queue[]: recorded 2 times",
+	}, ft.errors)
+
+	rec.Reset()
+	rec.AssertNotRecorded(t, "req")
+}
@@ -0,0 +1,69 @@
+package metric
+
+// Multi is driver which sends metrics to several drivers.
+type Multi []Metric
+
+func (m Multi) Counter(name string, values ...int) {
+	for _, d := range m {
+		d.Counter(name, values...)
+	}
+}
+
+func (m Multi) Gauge(name string, value int) {
+	for _, d := range m {
+		d.Gauge(name, value)
+	}
+}
+
+// Timing measures f once and submits duration to all drivers.
+func (m Multi) Timing(name string, f func()) {
+	sw := NewStopwatch()
+	f()
+	m.Time(name, sw.GetNs())
+}
+
+func (m Multi) Time(name string, duration int) {
+	for _, d := range m {
+		d.Time(name, duration)
+	}
+}
+
+func (m Multi) CounterTags(name string, value int, tags []Tag) {
+	for _, d := range m {
+		count(d, name, value, tags)
+	}
+}
+
+func (m Multi) GaugeTags(name string, value int, tags []Tag) {
+	for _, d := range m {
+		gauge(d, name, value, tags)
+	}
+}
+
+func (m Multi) TimeTags(name string, duration int, tags []Tag) {
+	for _, d := range m {
+		timing(d, name, duration, tags)
+	}
+}
+
+func (m Multi) Histogram(name string, value float64, tags []Tag) {
+	for _, d := range m {
+		observe(d, name, value, tags)
+	}
+}
+
+func (m Multi) WithPrefix(prefix string) Metric {
+	c := make(Multi, len(m))
+	for i, d := range m {
+		c[i] = d.WithPrefix(prefix)
+	}
+	return c
+}
+
+func (m Multi) AppendSuffix(suffix string) Metric {
+	c := make(Multi, len(m))
+	for i, d := range m {
+		c[i] = d.AppendSuffix(suffix)
+	}
+	return c
+}
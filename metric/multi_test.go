@@ -0,0 +1,104 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type counting struct {
+	Noop
+	counters map[string]int
+}
+
+func (c *counting) Counter(name string, values ...int) {
+	c.counters[name] += sum(values)
+}
+
+func TestAdd(t *testing.T) {
+	defer Set(NewNoop())
+	a, b := &counting{counters: map[string]int{}}, &counting{counters: map[string]int{}}
+	Add(a)
+	assert.Equal(t, a, driver)
+	Add(b)
+	Counter("req")
+	assert.Equal(t, 1, a.counters["req"])
+	assert.Equal(t, 1, b.counters["req"])
+	Counter("req", 2, 3)
+	assert.Equal(t, 6, a.counters["req"])
+}
+
+func TestReplace(t *testing.T) {
+	defer Set(NewNoop())
+	a, b, c := &counting{counters: map[string]int{}}, &counting{counters: map[string]int{}}, &counting{counters: map[string]int{}}
+	Replace(nil, a)
+	assert.Equal(t, a, driver)
+	Replace(a, b)
+	assert.True(t, driver == Metric(b))
+	Add(a)
+	Replace(b, c)
+	Counter("req")
+	m := driver.(Multi)
+	assert.True(t, m[0] == Metric(c) && m[1] == Metric(a))
+	assert.Equal(t, 1, a.counters["req"])
+	assert.Equal(t, 0, b.counters["req"])
+	assert.Equal(t, 1, c.counters["req"])
+}
+
+type tagged struct {
+	counting
+	tags       []Tag
+	histograms map[string]float64
+}
+
+func (t *tagged) CounterTags(name string, value int, tags []Tag) {
+	t.counters[name] += value
+	t.tags = tags
+}
+
+func (t *tagged) GaugeTags(name string, value int, tags []Tag) {}
+
+func (t *tagged) TimeTags(name string, duration int, tags []Tag) {}
+
+func (t *tagged) Histogram(name string, value float64, tags []Tag) {
+	t.histograms[name] = value
+}
+
+func (t *tagged) WithPrefix(prefix string) Metric {
+	return &prefixedTagged{prefix: prefix, tagged: t}
+}
+
+type prefixedTagged struct {
+	prefix string
+	*tagged
+}
+
+func (p *prefixedTagged) Counter(name string, values ...int) {
+	p.tagged.Counter(prefixed(p.prefix, name), values...)
+}
+
+func TestTags(t *testing.T) {
+	defer Set(NewNoop())
+	a := &counting{counters: map[string]int{}}
+	b := &tagged{counting: counting{counters: map[string]int{}}}
+	Set(Multi{a, b})
+	CounterT("req", 5, T("route", "/"), T("code", "200"))
+	assert.Equal(t, 5, a.counters["req"])
+	assert.Equal(t, 5, b.counters["req"])
+	assert.Equal(t, []Tag{{"route", "/"}, {"code", "200"}}, b.tags)
+}
+
+func TestMultiHistogram(t *testing.T) {
+	defer Set(NewNoop())
+	a := &counting{counters: map[string]int{}}
+	b := &tagged{counting: counting{counters: map[string]int{}}, histograms: map[string]float64{}}
+	Add(a)
+	Add(b)
+	Counter("req", 2)
+	WithPrefix("pero").Counter("req")
+	Observe("size", 1.5)
+	assert.Equal(t, 2, a.counters["req"])
+	assert.Equal(t, 2, b.counters["req"])
+	assert.Equal(t, 1, b.counters["pero.req"])
+	assert.Equal(t, 1.5, b.histograms["size"])
+}
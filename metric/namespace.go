@@ -0,0 +1,33 @@
+package metric
+
+import "github.com/minus5/svckit/env"
+
+// Namespace identifies metrics source.
+// Backends add it to all metrics as prefix, labels or resource attributes.
+type Namespace struct {
+	App     string
+	Dc      string
+	Node    string
+	Version string
+}
+
+// EnvNamespace returns namespace of the application from env.
+func EnvNamespace() Namespace {
+	return Namespace{
+		App:     env.AppName(),
+		Dc:      env.Dc(),
+		Node:    env.NodeName(),
+		Version: env.Version(),
+	}
+}
+
+// Tags returns non empty namespace fields as app, dc, node and version tags.
+func (n Namespace) Tags() []Tag {
+	var tags []Tag
+	for _, t := range []Tag{T("app", n.App), T("dc", n.Dc), T("node", n.Node), T("version", n.Version)} {
+		if t.Value != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,479 @@
+// Package otlphttp is metric driver which exports metrics to the OpenTelemetry collector over OTLP/HTTP.
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/metric/otlphttp"
+//	err := otlphttp.Dial()
+//
+// Collector is discovered in Consul, or address can be given explicitly:
+//
+//	err := otlphttp.Dial("127.0.0.1:4318")
+//
+// Transport is OTLP/HTTP with json encoding, collector must have otlp receiver with http protocol enabled
+// (default port 4318). OTLP/gRPC is not supported.
+// Metrics are aggregated in memory and pushed every ExportInterval.
+// Counters are exported as cumulative sums, gauges as gauges, timings as histograms in milliseconds (DefaultBounds)
+// and other histograms (metric.Observe) with DefaultValueBounds, unless set with SetBounds.
+// Tags are exported as data point attributes.
+// Timings submitted with trace in context (metric.TimeContext) are exported with exemplars.
+// Resource attributes service.name, deployment.environment, dc, host.name and service.version are set from env.
+package otlphttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/signal"
+)
+
+const (
+	// CollectorServiceName is collector OTLP/HTTP service name in service discovery.
+	CollectorServiceName = "otel-collector-http"
+
+	metricsPath = "/v1/metrics"
+)
+
+var (
+	// ExportInterval is interval of pushing metrics to the collector.
+	ExportInterval = 10 * time.Second
+	// DefaultBounds are timing histogram bucket bounds in milliseconds.
+	DefaultBounds = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+	// DefaultValueBounds are bucket bounds of histograms of arbitrary values (sizes, counts...).
+	DefaultValueBounds = []float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+)
+
+// Dial creates exporter and adds it as metric driver.
+//
+//	opts[0] - collector address, if not set it is discovered in Consul
+func Dial(opts ...string) error {
+	addr := ""
+	if len(opts) > 0 {
+		addr = opts[0]
+	} else {
+		err := signal.WithExponentialBackoff(func() error {
+			a, err := dcy.Service(CollectorServiceName)
+			addr = a.String()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	metric.Add(New(addr))
+	logger().S("addr", addr).Info("connected")
+	return nil
+}
+
+// Exporter aggregates metrics and pushes them to the collector.
+// Implements metric.Metric interface.
+type Exporter struct {
+	prefix string
+	s      *store
+}
+
+type store struct {
+	url    string
+	client *http.Client
+	start  time.Time
+	done   chan struct{}
+	once   sync.Once
+
+	mu         sync.Mutex
+	bounds     map[string][]float64         // set with SetBounds
+	counters   map[string]map[string]*value // by name and series key
+	gauges     map[string]map[string]*value
+	histograms map[string]map[string]*histogram
+}
+
+type value struct {
+	tags []metric.Tag
+	v    int64
+}
+
+type histogram struct {
+	tags      []metric.Tag
+	unit      string
+	bounds    []float64
+	counts    []uint64 // len(bounds)+1
+	count     uint64
+	sum       float64
+	exemplars []exemplar // since last push
+}
+
+type exemplar struct {
+	TimeUnixNano string  `json:"timeUnixNano"`
+	AsDouble     float64 `json:"asDouble"`
+	TraceID      string  `json:"traceId"`
+	SpanID       string  `json:"spanId,omitempty"`
+}
+
+// maxExemplars is maximum number of exemplars per histogram in one push.
+const maxExemplars = 10
+
+// New creates exporter to the collector at addr (host:port).
+func New(addr string) *Exporter {
+	s := &store{
+		url:        fmt.Sprintf("http://%s%s", addr, metricsPath),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		start:      time.Now(),
+		done:       make(chan struct{}),
+		bounds:     make(map[string][]float64),
+		counters:   make(map[string]map[string]*value),
+		gauges:     make(map[string]map[string]*value),
+		histograms: make(map[string]map[string]*histogram),
+	}
+	go s.loop()
+	return &Exporter{s: s}
+}
+
+func (e *Exporter) name(name string) string {
+	if e.prefix != "" {
+		return e.prefix + "." + name
+	}
+	return name
+}
+
+func (e *Exporter) Counter(name string, values ...int) {
+	value := 1
+	if len(values) > 0 {
+		value = 0
+		for _, v := range values {
+			value += v
+		}
+	}
+	e.CounterTags(name, value, nil)
+}
+
+func (e *Exporter) Gauge(name string, value int) {
+	e.GaugeTags(name, value, nil)
+}
+
+func (e *Exporter) Timing(name string, f func()) {
+	sw := metric.NewStopwatch()
+	f()
+	e.Time(name, sw.GetNs())
+}
+
+// Time observes duration (in nanoseconds) in histogram.
+func (e *Exporter) Time(name string, duration int) {
+	e.TimeTags(name, duration, nil)
+}
+
+// CounterTags increments counter series with tags as attributes.
+func (e *Exporter) CounterTags(name string, v int, tags []metric.Tag) {
+	name = e.name(name)
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	series(e.s.counters, name, tags).v += int64(v)
+}
+
+// GaugeTags sets gauge series with tags as attributes.
+func (e *Exporter) GaugeTags(name string, v int, tags []metric.Tag) {
+	name = e.name(name)
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	series(e.s.gauges, name, tags).v = int64(v)
+}
+
+// TimeTags observes duration (in nanoseconds) in milliseconds histogram with DefaultBounds.
+func (e *Exporter) TimeTags(name string, duration int, tags []metric.Tag) {
+	e.observe(e.name(name), "ms", DefaultBounds, tags, float64(duration)/1e6)
+}
+
+// Histogram observes value in histogram with DefaultValueBounds.
+func (e *Exporter) Histogram(name string, v float64, tags []metric.Tag) {
+	e.observe(e.name(name), "", DefaultValueBounds, tags, v)
+}
+
+// TimeExemplar observes duration (in nanoseconds) with exemplar.
+func (e *Exporter) TimeExemplar(name string, duration int, tags []metric.Tag, ex metric.Exemplar) {
+	e.observe(e.name(name), "ms", DefaultBounds, tags, float64(duration)/1e6, ex)
+}
+
+// HistogramExemplar observes value with exemplar.
+func (e *Exporter) HistogramExemplar(name string, v float64, tags []metric.Tag, ex metric.Exemplar) {
+	e.observe(e.name(name), "", DefaultValueBounds, tags, v, ex)
+}
+
+// SetBounds sets histogram bucket bounds for metric name (with prefix, as submitted).
+// It applies to series created after the call.
+func (e *Exporter) SetBounds(name string, bounds ...float64) {
+	name = e.name(name)
+	sort.Float64s(bounds)
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	e.s.bounds[name] = bounds
+}
+
+// key returns series key, tags sorted by key.
+func key(tags []metric.Tag) string {
+	kv := make([]string, len(tags))
+	for i, t := range tags {
+		kv[i] = t.Key + "=" + t.Value
+	}
+	sort.Strings(kv)
+	return strings.Join(kv, ",")
+}
+
+func series(m map[string]map[string]*value, name string, tags []metric.Tag) *value {
+	ss, ok := m[name]
+	if !ok {
+		ss = make(map[string]*value)
+		m[name] = ss
+	}
+	k := key(tags)
+	s, ok := ss[k]
+	if !ok {
+		s = &value{tags: tags}
+		ss[k] = s
+	}
+	return s
+}
+
+// observe adds v to histogram series, bounds are used unless set with SetBounds.
+func (e *Exporter) observe(name, unit string, bounds []float64, tags []metric.Tag, v float64, exs ...metric.Exemplar) {
+	k := key(tags)
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+	if bs, ok := e.s.bounds[name]; ok {
+		bounds = bs
+	}
+	hs, ok := e.s.histograms[name]
+	if !ok {
+		hs = make(map[string]*histogram)
+		e.s.histograms[name] = hs
+	}
+	h, ok := hs[k]
+	if !ok {
+		h = &histogram{tags: tags, unit: unit, bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+		hs[k] = h
+	}
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.count++
+	h.sum += v
+	if len(exs) > 0 && len(h.exemplars) < maxExemplars {
+		h.exemplars = append(h.exemplars, exemplar{
+			TimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+			AsDouble:     v,
+			TraceID:      exs[0].TraceID,
+			SpanID:       exs[0].SpanID,
+		})
+	}
+}
+
+func (e *Exporter) WithPrefix(prefix string) metric.Metric {
+	return &Exporter{prefix: prefix, s: e.s}
+}
+
+func (e *Exporter) AppendSuffix(suffix string) metric.Metric {
+	return e.WithPrefix(e.name(suffix))
+}
+
+// Close pushes current values and stops exporting.
+func (e *Exporter) Close() error {
+	e.s.once.Do(func() { close(e.s.done) })
+	return e.s.push()
+}
+
+func (s *store) loop() {
+	ticker := time.NewTicker(ExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.push(); err != nil {
+				logger().Error(err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func attr(key, value string) keyValue {
+	kv := keyValue{Key: key}
+	kv.Value.StringValue = value
+	return kv
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             string     `json:"asInt,omitempty"`
+	Count             string     `json:"count,omitempty"`
+	Sum               *float64   `json:"sum,omitempty"`
+	BucketCounts      []string   `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64  `json:"explicitBounds,omitempty"`
+	Exemplars         []exemplar `json:"exemplars,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type histogramData struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Unit      string         `json:"unit,omitempty"`
+	Sum       *sum           `json:"sum,omitempty"`
+	Gauge     *gauge         `json:"gauge,omitempty"`
+	Histogram *histogramData `json:"histogram,omitempty"`
+}
+
+// cumulative aggregation temporality
+const cumulative = 2
+
+// metrics returns current values in OTLP format.
+func (s *store) metrics(now time.Time) []otlpMetric {
+	start := strconv.FormatInt(s.start.UnixNano(), 10)
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ms []otlpMetric
+	for name, ss := range s.counters {
+		var dps []dataPoint
+		for _, k := range sortedKeys(ss) {
+			dps = append(dps, dataPoint{Attributes: attrs(ss[k].tags), StartTimeUnixNano: start, TimeUnixNano: ts, AsInt: strconv.FormatInt(ss[k].v, 10)})
+		}
+		ms = append(ms, otlpMetric{Name: name, Sum: &sum{
+			DataPoints:             dps,
+			AggregationTemporality: cumulative,
+			IsMonotonic:            true,
+		}})
+	}
+	for name, ss := range s.gauges {
+		var dps []dataPoint
+		for _, k := range sortedKeys(ss) {
+			dps = append(dps, dataPoint{Attributes: attrs(ss[k].tags), TimeUnixNano: ts, AsInt: strconv.FormatInt(ss[k].v, 10)})
+		}
+		ms = append(ms, otlpMetric{Name: name, Gauge: &gauge{DataPoints: dps}})
+	}
+	for name, hs := range s.histograms {
+		var (
+			dps  []dataPoint
+			unit string
+		)
+		for _, k := range sortedKeys(hs) {
+			h := hs[k]
+			unit = h.unit
+			dp := dataPoint{
+				Attributes:        attrs(h.tags),
+				StartTimeUnixNano: start,
+				TimeUnixNano:      ts,
+				Count:             strconv.FormatUint(h.count, 10),
+				ExplicitBounds:    h.bounds,
+			}
+			sum := h.sum
+			dp.Sum = &sum
+			dp.Exemplars, h.exemplars = h.exemplars, nil
+			for _, c := range h.counts {
+				dp.BucketCounts = append(dp.BucketCounts, strconv.FormatUint(c, 10))
+			}
+			dps = append(dps, dp)
+		}
+		ms = append(ms, otlpMetric{Name: name, Unit: unit, Histogram: &histogramData{
+			DataPoints:             dps,
+			AggregationTemporality: cumulative,
+		}})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Name < ms[j].Name })
+	return ms
+}
+
+func attrs(tags []metric.Tag) []keyValue {
+	if len(tags) == 0 {
+		return nil
+	}
+	kvs := make([]keyValue, len(tags))
+	for i, t := range tags {
+		kvs[i] = attr(t.Key, t.Value)
+	}
+	return kvs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *store) push() error {
+	ms := s.metrics(time.Now())
+	if len(ms) == 0 {
+		return nil
+	}
+	type scopeMetrics struct {
+		Scope   map[string]string `json:"scope"`
+		Metrics []otlpMetric      `json:"metrics"`
+	}
+	type resourceMetrics struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+	}
+	rm := resourceMetrics{}
+	ns := metric.EnvNamespace()
+	rm.Resource.Attributes = []keyValue{
+		attr("service.name", ns.App),
+		attr("deployment.environment", env.Environment()),
+		attr("dc", ns.Dc),
+		attr("host.name", ns.Node),
+	}
+	if ns.Version != "" {
+		rm.Resource.Attributes = append(rm.Resource.Attributes, attr("service.version", ns.Version))
+	}
+	rm.ScopeMetrics = []scopeMetrics{{Scope: map[string]string{"name": "svckit"}, Metrics: ms}}
+	body, err := json.Marshal(map[string][]resourceMetrics{"resourceMetrics": {rm}})
+	if err != nil {
+		return err
+	}
+	rsp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, rsp.Body)
+	rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp collector status %s", rsp.Status)
+	}
+	return nil
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.metric.otlphttp")
+}
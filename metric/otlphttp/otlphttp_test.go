@@ -0,0 +1,88 @@
+package otlphttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporter(t *testing.T) {
+	bodies := make(chan map[string]interface{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, metricsPath, r.URL.Path)
+		var m map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&m))
+		bodies <- m
+	}))
+	defer srv.Close()
+
+	e := New(strings.TrimPrefix(srv.URL, "http://"))
+	e.Counter("req", 2)
+	e.Counter("req")
+	e.WithPrefix("app").Gauge("conn", 12)
+	e.Time("rsp", int(30*time.Millisecond))
+
+	ms := e.s.metrics(time.Now())
+	assert.Len(t, ms, 3)
+	assert.Equal(t, "app.conn", ms[0].Name)
+	assert.Equal(t, "12", ms[0].Gauge.DataPoints[0].AsInt)
+	assert.Equal(t, "req", ms[1].Name)
+	assert.Equal(t, "3", ms[1].Sum.DataPoints[0].AsInt)
+	assert.Equal(t, "rsp", ms[2].Name)
+	assert.Equal(t, "1", ms[2].Histogram.DataPoints[0].BucketCounts[3])
+
+	assert.NoError(t, e.Close())
+	body := <-bodies
+	assert.Contains(t, body, "resourceMetrics")
+	rm := body["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	keys := map[string]bool{}
+	for _, a := range rm["resource"].(map[string]interface{})["attributes"].([]interface{}) {
+		keys[a.(map[string]interface{})["key"].(string)] = true
+	}
+	assert.True(t, keys["dc"])
+	assert.True(t, keys["deployment.environment"])
+}
+
+func TestExemplars(t *testing.T) {
+	e := New("127.0.0.1:0")
+	defer e.s.once.Do(func() { close(e.s.done) })
+	e.TimeExemplar("rsp", int(30*time.Millisecond), nil, metric.Exemplar{TraceID: "abc", SpanID: "def"})
+	ms := e.s.metrics(time.Now())
+	ex := ms[0].Histogram.DataPoints[0].Exemplars
+	assert.Len(t, ex, 1)
+	assert.Equal(t, "abc", ex[0].TraceID)
+	assert.Equal(t, 30.0, ex[0].AsDouble)
+	// exemplars are sent once
+	ms = e.s.metrics(time.Now())
+	assert.Len(t, ms[0].Histogram.DataPoints[0].Exemplars, 0)
+}
+
+func TestTagsAndValueHistogram(t *testing.T) {
+	e := New("127.0.0.1:0")
+	defer e.s.once.Do(func() { close(e.s.done) })
+	e.CounterTags("req", 1, []metric.Tag{metric.T("code", "200")})
+	e.CounterTags("req", 2, []metric.Tag{metric.T("code", "500")})
+	e.Histogram("size", 7, []metric.Tag{metric.T("topic", "a")})
+	e.SetBounds("batch", 10, 100)
+	e.Histogram("batch", 50, nil)
+
+	ms := e.s.metrics(time.Now())
+	assert.Len(t, ms, 3)
+	assert.Equal(t, "batch", ms[0].Name)
+	assert.Equal(t, []float64{10, 100}, ms[0].Histogram.DataPoints[0].ExplicitBounds)
+	assert.Equal(t, "1", ms[0].Histogram.DataPoints[0].BucketCounts[1])
+	assert.Equal(t, "req", ms[1].Name)
+	assert.Len(t, ms[1].Sum.DataPoints, 2)
+	assert.Equal(t, "code", ms[1].Sum.DataPoints[1].Attributes[0].Key)
+	assert.Equal(t, "500", ms[1].Sum.DataPoints[1].Attributes[0].Value.StringValue)
+	assert.Equal(t, "size", ms[2].Name)
+	assert.Equal(t, "", ms[2].Unit)
+	assert.Equal(t, DefaultValueBounds, ms[2].Histogram.DataPoints[0].ExplicitBounds)
+	assert.Equal(t, "topic", ms[2].Histogram.DataPoints[0].Attributes[0].Key)
+}
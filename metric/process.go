@@ -0,0 +1,60 @@
+package metric
+
+import (
+	"errors"
+	"time"
+)
+
+// EnvProcess enables process metrics collection, value is interval (e.g. 10s).
+const EnvProcess = "SVCKIT_METRIC_PROCESS"
+
+var errProcessNotSupported = errors.New("process metrics are not supported on this platform")
+
+// processStats is process resource usage.
+type processStats struct {
+	cpu     time.Duration // user + system
+	rss     int           // bytes
+	fds     int
+	threads int
+}
+
+// CollectProcess periodically reports process resource usage through the current driver:
+//
+//	process.cpu.usage   gauge, percent of one core in interval
+//	process.cpu.time    counter, milliseconds of cpu time in interval
+//	process.rss         gauge, resident memory bytes
+//	process.fds         gauge, open file descriptors
+//	process.threads     gauge, os threads
+//
+// Works on Linux (reads /proc), on other platforms nothing is reported.
+// Returns function which stops collecting.
+func CollectProcess(interval time.Duration) func() {
+	c := &processCollector{read: readProcess}
+	c.collect()
+	return every(interval, c.collect)
+}
+
+type processCollector struct {
+	read func() (processStats, error)
+	last processStats
+	at   time.Time
+}
+
+func (c *processCollector) collect() {
+	s, err := c.read()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if !c.at.IsZero() {
+		cpu := s.cpu - c.last.cpu
+		Counter("process.cpu.time", int(cpu/time.Millisecond))
+		if elapsed := now.Sub(c.at); elapsed > 0 {
+			Gauge("process.cpu.usage", int(100*cpu/elapsed))
+		}
+	}
+	Gauge("process.rss", s.rss)
+	Gauge("process.fds", s.fds)
+	Gauge("process.threads", s.threads)
+	c.last, c.at = s, now
+}
@@ -0,0 +1,37 @@
+package metric
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, unit of cpu times in /proc/self/stat.
+const clockTicks = 100
+
+func readProcess() (processStats, error) {
+	var s processStats
+	buf, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return s, err
+	}
+	// fields after command name (which can contain spaces), first one is state (field 3 in proc(5))
+	stat := string(buf)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 22 {
+		return s, fmt.Errorf("unexpected /proc/self/stat format")
+	}
+	field := func(n int) int {
+		v, _ := strconv.Atoi(fields[n-3])
+		return v
+	}
+	s.cpu = time.Duration(field(14)+field(15)) * time.Second / clockTicks
+	s.threads = field(20)
+	s.rss = field(24) * os.Getpagesize()
+	if fds, err := os.ReadDir("/proc/self/fd"); err == nil {
+		s.fds = len(fds)
+	}
+	return s, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package metric
+
+func readProcess() (processStats, error) {
+	return processStats{}, errProcessNotSupported
+}
@@ -0,0 +1,38 @@
+package metric
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessCollector(t *testing.T) {
+	defer Set(NewNoop())
+	g := &gauges{values: map[string]int{}}
+	Set(g)
+	s := processStats{rss: 1024, fds: 5, threads: 3}
+	c := &processCollector{read: func() (processStats, error) { return s, nil }}
+	c.collect()
+	_, ok := g.values["process.cpu.usage"]
+	assert.False(t, ok)
+	c.at = c.at.Add(-time.Second)
+	s.cpu += 500 * time.Millisecond
+	c.collect()
+	assert.InDelta(t, 50, g.values["process.cpu.usage"], 1)
+	assert.Equal(t, 1024, g.values["process.rss"])
+	assert.Equal(t, 5, g.values["process.fds"])
+	assert.Equal(t, 3, g.values["process.threads"])
+}
+
+func TestReadProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip()
+	}
+	s, err := readProcess()
+	assert.NoError(t, err)
+	assert.True(t, s.rss > 0)
+	assert.True(t, s.fds > 0)
+	assert.True(t, s.threads > 0)
+}
@@ -0,0 +1,395 @@
+// Package prometheus is metric driver which keeps metrics in memory
+// and exposes them in Prometheus text format for scraping.
+//
+// Usage:
+//
+//	import "github.com/minus5/svckit/metric/prometheus"
+//	prometheus.Enable()
+//
+// Metrics are served on /metrics of the httpi router.
+// Enable adds driver alongside already set one (e.g. statsd), so both receive metrics.
+//
+// Default registry has app, dc, node and version labels from env (see metric.EnvNamespace),
+// set SVCKIT_PROMETHEUS_LABELS=0 to disable them.
+//
+// Metric names are converted to Prometheus names, invalid characters (dots) are replaced with underscores.
+// Tags are exported as labels. Counters and gauges are exported as is,
+// timings as histograms in seconds (DefaultBuckets),
+// and other histograms (metric.Observe) with DefaultValueBuckets, unless set with SetBuckets.
+//
+// Timings submitted with trace in context (metric.TimeContext) keep last trace id per bucket as exemplar.
+// Exemplars are exposed when scraper accepts OpenMetrics format.
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+)
+
+var (
+	// DefaultBuckets are timing histogram upper bounds in seconds.
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DefaultValueBuckets are upper bounds of histograms of arbitrary values (sizes, counts...).
+	DefaultValueBuckets = []float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+)
+
+var defaultRegistry = NewRegistry()
+
+// EnvLabels disables namespace labels when set to 0 or false.
+const EnvLabels = "SVCKIT_PROMETHEUS_LABELS"
+
+// Enable adds default registry as metric driver.
+func Enable() {
+	if e := os.Getenv(EnvLabels); e != "0" && e != "false" {
+		defaultRegistry.SetLabels(metric.EnvNamespace().Tags()...)
+	}
+	metric.Add(defaultRegistry)
+}
+
+// Default returns default registry.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Handler serves default registry.
+func Handler() http.Handler {
+	return defaultRegistry
+}
+
+// Registry keeps metrics values.
+// Implements metric.Metric interface.
+type Registry struct {
+	prefix string
+	s      *store
+}
+
+// store keeps values by metric name and series labels.
+type store struct {
+	mu         sync.Mutex
+	labels     string               // const labels, formatted
+	buckets    map[string][]float64 // set with SetBuckets
+	counters   map[string]map[string]float64
+	gauges     map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+type histogram struct {
+	buckets   []float64
+	counts    []uint64 // per bucket, not cumulative
+	count     uint64
+	sum       float64
+	exemplars []*exemplar // per bucket, last is +Inf
+}
+
+type exemplar struct {
+	metric.Exemplar
+	value float64
+	time  time.Time
+}
+
+// NewRegistry creates empty registry.
+func NewRegistry() *Registry {
+	return &Registry{s: &store{
+		buckets:    make(map[string][]float64),
+		counters:   make(map[string]map[string]float64),
+		gauges:     make(map[string]map[string]float64),
+		histograms: make(map[string]map[string]*histogram),
+	}}
+}
+
+func (r *Registry) name(name string) string {
+	if r.prefix != "" {
+		name = r.prefix + "." + name
+	}
+	return Name(name)
+}
+
+// Counter increments counter name for sum(values), or 1 without values.
+func (r *Registry) Counter(name string, values ...int) {
+	value := 1
+	if len(values) > 0 {
+		value = 0
+		for _, v := range values {
+			value += v
+		}
+	}
+	r.CounterTags(name, value, nil)
+}
+
+// Gauge sets gauge value.
+func (r *Registry) Gauge(name string, value int) {
+	r.GaugeTags(name, value, nil)
+}
+
+// Timing measures execution time of f.
+func (r *Registry) Timing(name string, f func()) {
+	sw := metric.NewStopwatch()
+	f()
+	r.Time(name, sw.GetNs())
+}
+
+// Time observes duration (in nanoseconds) in histogram.
+func (r *Registry) Time(name string, duration int) {
+	r.TimeTags(name, duration, nil)
+}
+
+// CounterTags increments counter series with tags as labels.
+func (r *Registry) CounterTags(name string, value int, tags []metric.Tag) {
+	name, l := r.name(name), labels(tags)
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	series(r.s.counters, name)[l] += float64(value)
+}
+
+// GaugeTags sets gauge series with tags as labels.
+func (r *Registry) GaugeTags(name string, value int, tags []metric.Tag) {
+	name, l := r.name(name), labels(tags)
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	series(r.s.gauges, name)[l] = float64(value)
+}
+
+// TimeTags observes duration (in nanoseconds) in histogram with DefaultBuckets (seconds).
+func (r *Registry) TimeTags(name string, duration int, tags []metric.Tag) {
+	r.observe(r.name(name), DefaultBuckets, tags, time.Duration(duration).Seconds())
+}
+
+// Histogram observes value in histogram with DefaultValueBuckets.
+func (r *Registry) Histogram(name string, value float64, tags []metric.Tag) {
+	r.observe(r.name(name), DefaultValueBuckets, tags, value)
+}
+
+// TimeExemplar observes duration (in nanoseconds) with exemplar.
+func (r *Registry) TimeExemplar(name string, duration int, tags []metric.Tag, e metric.Exemplar) {
+	r.observe(r.name(name), DefaultBuckets, tags, time.Duration(duration).Seconds(), e)
+}
+
+// HistogramExemplar observes value with exemplar.
+func (r *Registry) HistogramExemplar(name string, value float64, tags []metric.Tag, e metric.Exemplar) {
+	r.observe(r.name(name), DefaultValueBuckets, tags, value, e)
+}
+
+func series(m map[string]map[string]float64, name string) map[string]float64 {
+	s, ok := m[name]
+	if !ok {
+		s = make(map[string]float64)
+		m[name] = s
+	}
+	return s
+}
+
+// observe adds v to histogram series, buckets are used unless set with SetBuckets.
+func (r *Registry) observe(name string, buckets []float64, tags []metric.Tag, v float64, es ...metric.Exemplar) {
+	l := labels(tags)
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if bs, ok := r.s.buckets[name]; ok {
+		buckets = bs
+	}
+	hs, ok := r.s.histograms[name]
+	if !ok {
+		hs = make(map[string]*histogram)
+		r.s.histograms[name] = hs
+	}
+	h, ok := hs[l]
+	if !ok {
+		h = &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+		hs[l] = h
+	}
+	i := len(h.buckets)
+	for j, b := range h.buckets {
+		if v <= b {
+			h.counts[j]++
+			i = j
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+	if len(es) > 0 {
+		if h.exemplars == nil {
+			h.exemplars = make([]*exemplar, len(h.buckets)+1)
+		}
+		h.exemplars[i] = &exemplar{Exemplar: es[0], value: v, time: time.Now()}
+	}
+}
+
+// SetBuckets sets histogram upper bounds for metric name (with prefix, as submitted).
+// It applies to series created after the call.
+func (r *Registry) SetBuckets(name string, buckets ...float64) {
+	name = r.name(name)
+	sort.Float64s(buckets)
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.buckets[name] = buckets
+}
+
+// labels returns tags formatted as Prometheus labels, sorted by key.
+func labels(tags []metric.Tag) string {
+	ls := make([]string, 0, len(tags))
+	for _, t := range tags {
+		ls = append(ls, fmt.Sprintf("%s=%s", Name(t.Key), strconv.Quote(t.Value)))
+	}
+	sort.Strings(ls)
+	return strings.Join(ls, ",")
+}
+
+// SetLabels sets constant labels added to all metrics.
+func (r *Registry) SetLabels(tags ...metric.Tag) {
+	ls := make([]string, 0, len(tags))
+	for _, t := range tags {
+		ls = append(ls, fmt.Sprintf("%s=%s", Name(t.Key), strconv.Quote(t.Value)))
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.labels = strings.Join(ls, ",")
+}
+
+// WithPrefix returns registry view with different prefix.
+func (r *Registry) WithPrefix(prefix string) metric.Metric {
+	return &Registry{prefix: prefix, s: r.s}
+}
+
+// AppendSuffix returns registry view with suffix appended to prefix.
+func (r *Registry) AppendSuffix(suffix string) metric.Metric {
+	if r.prefix == "" {
+		return r.WithPrefix(suffix)
+	}
+	return r.WithPrefix(r.prefix + "." + suffix)
+}
+
+// ServeHTTP writes metrics in Prometheus text format,
+// or in OpenMetrics format (with exemplars) if client accepts it.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text") {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		r.WriteOpenMetrics(w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
+
+// Write writes metrics in Prometheus text format.
+func (r *Registry) Write(w io.Writer) error {
+	return r.write(w, false)
+}
+
+// WriteOpenMetrics writes metrics in OpenMetrics text format with exemplars.
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	return r.write(w, true)
+}
+
+func (r *Registry) write(w io.Writer, openMetrics bool) error {
+	buf := &bytes.Buffer{}
+	s := r.s
+	s.mu.Lock()
+	for _, name := range sortedKeys(s.counters) {
+		family, sample := name, name
+		if openMetrics {
+			// OpenMetrics counter samples have _total suffix
+			family = strings.TrimSuffix(name, "_total")
+			sample = family + "_total"
+		}
+		fmt.Fprintf(buf, "# TYPE %s counter\n", family)
+		for _, l := range sortedKeys(s.counters[name]) {
+			fmt.Fprintf(buf, "%s%s %s\n", sample, join(s.labels, l), formatFloat(s.counters[name][l]))
+		}
+	}
+	for _, name := range sortedKeys(s.gauges) {
+		fmt.Fprintf(buf, "# TYPE %s gauge\n", name)
+		for _, l := range sortedKeys(s.gauges[name]) {
+			fmt.Fprintf(buf, "%s%s %s\n", name, join(s.labels, l), formatFloat(s.gauges[name][l]))
+		}
+	}
+	for _, name := range sortedKeys(s.histograms) {
+		fmt.Fprintf(buf, "# TYPE %s histogram\n", name)
+		for _, l := range sortedKeys(s.histograms[name]) {
+			h := s.histograms[name][l]
+			var cum uint64
+			for i, b := range h.buckets {
+				cum += h.counts[i]
+				le := fmt.Sprintf("le=%q", formatFloat(b))
+				fmt.Fprintf(buf, "%s_bucket%s %d%s\n", name, join(s.labels, l, le), cum, h.exemplar(i, openMetrics))
+			}
+			fmt.Fprintf(buf, "%s_bucket%s %d%s\n", name, join(s.labels, l, `le="+Inf"`), h.count, h.exemplar(len(h.buckets), openMetrics))
+			labels := join(s.labels, l)
+			fmt.Fprintf(buf, "%s_sum%s %s\n%s_count%s %d\n", name, labels, formatFloat(h.sum), name, labels, h.count)
+		}
+	}
+	s.mu.Unlock()
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// join returns non empty formatted labels in braces.
+func join(labels ...string) string {
+	ls := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l != "" {
+			ls = append(ls, l)
+		}
+	}
+	if len(ls) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(ls, ",") + "}"
+}
+
+// exemplar returns OpenMetrics exemplar of the bucket i.
+func (h *histogram) exemplar(i int, openMetrics bool) string {
+	if !openMetrics || h.exemplars == nil || h.exemplars[i] == nil {
+		return ""
+	}
+	e := h.exemplars[i]
+	labels := fmt.Sprintf("trace_id=%q", e.TraceID)
+	if e.SpanID != "" {
+		labels += fmt.Sprintf(",span_id=%q", e.SpanID)
+	}
+	return fmt.Sprintf(" # {%s} %s %.3f", labels, formatFloat(e.value), float64(e.time.UnixNano())/1e9)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Name converts metric name to valid Prometheus name ([a-zA-Z_:][a-zA-Z0-9_:]*).
+func Name(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "http_GET", Name("http.GET"))
+	assert.Equal(t, "_1xx_a_b", Name("1xx.a-b"))
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("req")
+	r.Counter("req", 2, 3)
+	r.Gauge("conn", 12)
+	r.WithPrefix("app").AppendSuffix("db").Time("query", int(30*time.Millisecond))
+	r.Time("rsp", int(2*time.Second))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, r.Write(buf))
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE req counter\nreq 6\n")
+	assert.Contains(t, out, "# TYPE conn gauge\nconn 12\n")
+	assert.Contains(t, out, "# TYPE app_db_query histogram\n")
+	assert.Contains(t, out, "app_db_query_bucket{le=\"0.025\"} 0\napp_db_query_bucket{le=\"0.05\"} 1\n")
+	assert.Contains(t, out, "app_db_query_bucket{le=\"+Inf\"} 1\napp_db_query_sum 0.03\napp_db_query_count 1\n")
+	assert.Contains(t, out, "rsp_bucket{le=\"1\"} 0\nrsp_bucket{le=\"2.5\"} 1\n")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, out, rec.Body.String())
+}
+
+func TestPush(t *testing.T) {
+	bodies := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPut, req.Method)
+		assert.Equal(t, "/metrics/job/migration/instance/node1", req.URL.Path)
+		buf, _ := io.ReadAll(req.Body)
+		bodies <- string(buf)
+	}))
+	defer srv.Close()
+
+	r := NewRegistry()
+	r.Counter("rows", 12)
+	assert.NoError(t, r.Push(strings.TrimPrefix(srv.URL, "http://"), "migration", "node1"))
+	assert.Equal(t, "# TYPE rows counter\nrows 12\n", <-bodies)
+}
+
+func TestLabels(t *testing.T) {
+	r := NewRegistry()
+	r.SetLabels(metric.Namespace{App: "app", Node: "n1"}.Tags()...)
+	r.Counter("req")
+	r.Time("rsp", int(time.Millisecond))
+	buf := &bytes.Buffer{}
+	assert.NoError(t, r.Write(buf))
+	out := buf.String()
+	assert.Contains(t, out, "req{app=\"app\",node=\"n1\"} 1\n")
+	assert.Contains(t, out, "rsp_bucket{app=\"app\",node=\"n1\",le=\"0.005\"} 1\n")
+	assert.Contains(t, out, "rsp_count{app=\"app\",node=\"n1\"} 1\n")
+}
+
+func TestOpenMetricsExemplars(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("req")
+	r.Time("rsp", int(time.Millisecond))
+	r.TimeExemplar("rsp", int(70*time.Millisecond), nil, metric.Exemplar{TraceID: "abc", SpanID: "def"})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	r.ServeHTTP(w, req)
+	out := w.Body.String()
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/openmetrics-text")
+	assert.Contains(t, out, "# TYPE req counter\nreq_total 1\n")
+	assert.Contains(t, out, "rsp_bucket{le=\"0.1\"} 2 # {trace_id=\"abc\",span_id=\"def\"} 0.07 ")
+	assert.Contains(t, out, "rsp_bucket{le=\"0.005\"} 1\n")
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+
+	// plain text format has no exemplars
+	buf := &bytes.Buffer{}
+	assert.NoError(t, r.Write(buf))
+	assert.NotContains(t, buf.String(), "trace_id")
+}
+
+func TestTagsAndValueHistogram(t *testing.T) {
+	r := NewRegistry()
+	r.SetLabels(metric.T("app", "app"))
+	r.CounterTags("req", 1, []metric.Tag{metric.T("route", "/"), metric.T("code", "200")})
+	r.CounterTags("req", 2, []metric.Tag{metric.T("code", "500"), metric.T("route", "/")})
+	r.Histogram("size", 7, []metric.Tag{metric.T("topic", "a")})
+	r.SetBuckets("batch", 10, 100)
+	r.Histogram("batch", 50, nil)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, r.Write(buf))
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE req counter\nreq{app=\"app\",code=\"200\",route=\"/\"} 1\nreq{app=\"app\",code=\"500\",route=\"/\"} 2\n")
+	assert.Contains(t, out, "size_bucket{app=\"app\",topic=\"a\",le=\"5\"} 0\nsize_bucket{app=\"app\",topic=\"a\",le=\"10\"} 1\n")
+	assert.Contains(t, out, "size_sum{app=\"app\",topic=\"a\"} 7\n")
+	assert.Contains(t, out, "batch_bucket{app=\"app\",le=\"10\"} 0\nbatch_bucket{app=\"app\",le=\"100\"} 1\nbatch_bucket{app=\"app\",le=\"+Inf\"} 1\n")
+	assert.Equal(t, 1, strings.Count(out, "# TYPE req counter"))
+}
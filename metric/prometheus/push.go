@@ -0,0 +1,36 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Push sends registry values to the Pushgateway at addr (host:port),
+// replacing metrics of the job/instance group.
+func (r *Registry) Push(addr, job, instance string) error {
+	buf := &bytes.Buffer{}
+	if err := r.Write(buf); err != nil {
+		return err
+	}
+	u := fmt.Sprintf("http://%s/metrics/job/%s/instance/%s", addr, url.PathEscape(job), url.PathEscape(instance))
+	req, err := http.NewRequest(http.MethodPut, u, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := &http.Client{Timeout: 10 * time.Second}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, rsp.Body)
+	rsp.Body.Close()
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway status %s", rsp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// Package pushgateway pushes Prometheus metrics of short lived jobs to the Pushgateway.
+// Separate package so that prometheus package doesn't depend on service discovery.
+package pushgateway
+
+import (
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric/prometheus"
+	"github.com/minus5/svckit/signal"
+)
+
+// ServiceName is Pushgateway service name in service discovery.
+const ServiceName = "pushgateway"
+
+// Push sends current values of the default prometheus registry to the Pushgateway,
+// replacing metrics of the job/instance group. Instance is node name.
+// For short lived jobs which can't be scraped use PushOnExit.
+//
+//	opts[0] - Pushgateway address, if not set it is discovered in Consul
+func Push(job string, opts ...string) error {
+	addr := ""
+	if len(opts) > 0 {
+		addr = opts[0]
+	} else {
+		a, err := dcy.Service(ServiceName)
+		if err != nil {
+			return err
+		}
+		addr = a.String()
+	}
+	err := prometheus.Default().Push(addr, job, env.NodeName())
+	if err != nil {
+		logger().S("job", job).Error(err)
+	}
+	return err
+}
+
+// PushOnExit registers shutdown hook (signal.PriorityFlush) which pushes metrics with Push.
+// Short lived job which finishes without signal should run shutdown itself:
+//
+//	prometheus.Enable()
+//	pushgateway.PushOnExit("db-migration")
+//	defer signal.Shutdown()
+func PushOnExit(job string, opts ...string) {
+	signal.OnShutdown("pushgateway", signal.PriorityFlush, 0, func() {
+		Push(job, opts...)
+	})
+}
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.metric.pushgateway")
+}
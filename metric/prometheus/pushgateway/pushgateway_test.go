@@ -0,0 +1,32 @@
+package pushgateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/metric/prometheus"
+	"github.com/minus5/svckit/signal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushOnExit(t *testing.T) {
+	var path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		path, body = r.URL.Path, string(buf)
+	}))
+	defer srv.Close()
+
+	prometheus.Enable()
+	metric.Counter("migrated", 3)
+	PushOnExit("migration", strings.TrimPrefix(srv.URL, "http://"))
+	assert.Equal(t, "", path)
+	signal.Shutdown()
+	assert.Equal(t, "/metrics/job/migration/instance/"+env.NodeName(), path)
+	assert.Contains(t, body, "migrated")
+}
@@ -0,0 +1,124 @@
+package metric
+
+import (
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"time"
+)
+
+const schedLatencyMetric = "/sched/latencies:seconds"
+
+// CollectRuntime periodically reports Go runtime metrics through the current driver:
+//
+//	runtime.goroutines          gauge
+//	runtime.heap.alloc          gauge, bytes
+//	runtime.heap.sys            gauge, bytes
+//	runtime.heap.objects        gauge
+//	runtime.gc.count            counter
+//	runtime.gc.pause            timing of each gc pause
+//	runtime.sched.latency.p50   gauge, nanoseconds (also p99)
+//
+// Returns function which stops collecting.
+func CollectRuntime(interval time.Duration) func() {
+	c := &runtimeCollector{}
+	return every(interval, c.collect)
+}
+
+type runtimeCollector struct {
+	numGC uint32
+	sched *metrics.Float64Histogram
+}
+
+func (c *runtimeCollector) collect() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	Gauge("runtime.goroutines", runtime.NumGoroutine())
+	Gauge("runtime.heap.alloc", int(ms.HeapAlloc))
+	Gauge("runtime.heap.sys", int(ms.HeapSys))
+	Gauge("runtime.heap.objects", int(ms.HeapObjects))
+
+	if c.numGC > 0 && ms.NumGC > c.numGC {
+		n := ms.NumGC - c.numGC
+		Counter("runtime.gc.count", int(n))
+		if n > uint32(len(ms.PauseNs)) {
+			n = uint32(len(ms.PauseNs))
+		}
+		for i := uint32(0); i < n; i++ {
+			Time("runtime.gc.pause", int(ms.PauseNs[(ms.NumGC-i+255)%256]))
+		}
+	}
+	c.numGC = ms.NumGC
+
+	samples := []metrics.Sample{{Name: schedLatencyMetric}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return
+	}
+	h := samples[0].Value.Float64Histogram()
+	if c.sched != nil {
+		d := histogramDelta(c.sched, h)
+		Gauge("runtime.sched.latency.p50", int(histogramQuantile(d, 0.5)*1e9))
+		Gauge("runtime.sched.latency.p99", int(histogramQuantile(d, 0.99)*1e9))
+	}
+	c.sched = h
+}
+
+// histogramDelta returns counts observed since previous reading.
+func histogramDelta(prev, cur *metrics.Float64Histogram) *metrics.Float64Histogram {
+	d := &metrics.Float64Histogram{Buckets: cur.Buckets, Counts: make([]uint64, len(cur.Counts))}
+	for i := range cur.Counts {
+		d.Counts[i] = cur.Counts[i]
+		if i < len(prev.Counts) {
+			d.Counts[i] -= prev.Counts[i]
+		}
+	}
+	return d
+}
+
+// histogramQuantile returns upper bound of the bucket containing quantile q.
+func histogramQuantile(h *metrics.Float64Histogram, q float64) float64 {
+	var total uint64
+	for _, c := range h.Counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank {
+			if b := h.Buckets[i+1]; !math.IsInf(b, 1) {
+				return b
+			}
+			return h.Buckets[i]
+		}
+	}
+	return 0
+}
+
+// every calls f every interval until returned stop function is called.
+func every(interval time.Duration, f func()) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var closed bool
+	return func() {
+		if !closed {
+			closed = true
+			close(done)
+		}
+	}
+}
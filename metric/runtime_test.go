@@ -0,0 +1,29 @@
+package metric
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeCollector(t *testing.T) {
+	defer Set(NewNoop())
+	g := &gauges{values: map[string]int{}}
+	Set(g)
+	c := &runtimeCollector{}
+	c.collect()
+	runtime.GC()
+	c.collect()
+	assert.True(t, g.values["runtime.goroutines"] > 0)
+	assert.True(t, g.values["runtime.heap.alloc"] > 0)
+	_, ok := g.values["runtime.sched.latency.p99"]
+	assert.True(t, ok)
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := &metrics.Float64Histogram{Buckets: []float64{0, 1, 2, 3}, Counts: []uint64{5, 4, 1}}
+	assert.Equal(t, 1.0, histogramQuantile(h, 0.5))
+	assert.Equal(t, 3.0, histogramQuantile(h, 0.99))
+}
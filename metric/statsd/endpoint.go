@@ -0,0 +1,169 @@
+package statsd
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/metric"
+
+	api "github.com/alexcesaro/statsd"
+)
+
+// maxPending is maximum number of metrics buffered while switching statsd instance.
+const maxPending = 1024
+
+var (
+	// FlushInterval is interval of sending buffered metrics.
+	FlushInterval = 100 * time.Millisecond
+	// MaxPacketSize is maximum size of UDP packet with batched metrics
+	// (Ethernet MTU - IPv4 header - UDP header = 1500 - 20 - 8).
+	MaxPacketSize = 1472
+	// DroppedInterval is interval of reporting dropped metrics counters.
+	DroppedInterval = 10 * time.Second
+)
+
+// endpoint is connection to one statsd instance.
+// Metrics are buffered and sent in MaxPacketSize batches every FlushInterval.
+// It can be switched to another instance, metrics sent during the switch are
+// buffered and sent to the new instance.
+type endpoint struct {
+	opts []api.Option
+	dial func(opts ...api.Option) (client, error)
+
+	writeErrors int64 // packets not sent, atomic
+
+	mu        sync.Mutex
+	addr      string
+	main      client
+	clients   map[string]client // by prefix
+	switching bool
+	pending   []pending
+	dropped   int // metrics not buffered during switch
+}
+
+type pending struct {
+	prefix string
+	tags   []metric.Tag
+	f      func(client)
+}
+
+func newEndpoint(opts []api.Option) *endpoint {
+	e := &endpoint{
+		dial: func(opts ...api.Option) (client, error) {
+			return api.New(opts...)
+		},
+	}
+	e.opts = append([]api.Option{
+		api.FlushPeriod(FlushInterval),
+		api.MaxPacketSize(MaxPacketSize),
+		api.ErrorHandler(e.onWriteError),
+	}, opts...)
+	return e
+}
+
+func (e *endpoint) onWriteError(err error) {
+	atomic.AddInt64(&e.writeErrors, 1)
+}
+
+// reportDropped sends counters of dropped metrics since last call.
+func (e *endpoint) reportDropped(prefix string) {
+	e.mu.Lock()
+	buffer := e.dropped
+	e.dropped = 0
+	e.mu.Unlock()
+	write := atomic.SwapInt64(&e.writeErrors, 0)
+	if buffer > 0 {
+		e.send(prefix, nil, func(c client) { c.Count("statsd.dropped.buffer", buffer) })
+	}
+	if write > 0 {
+		e.send(prefix, nil, func(c client) { c.Count("statsd.dropped.write", write) })
+	}
+}
+
+func (e *endpoint) send(prefix string, tags []metric.Tag, f func(client)) {
+	e.mu.Lock()
+	if e.switching || e.main == nil {
+		if len(e.pending) < maxPending {
+			e.pending = append(e.pending, pending{prefix: prefix, tags: tags, f: f})
+		} else {
+			e.dropped++
+		}
+		e.mu.Unlock()
+		return
+	}
+	c := e.client(prefix, tags)
+	e.mu.Unlock()
+	// only appends to the client buffer, sent in batches
+	f(c)
+}
+
+// client returns client for prefix and tags, must be called under lock.
+func (e *endpoint) client(prefix string, tags []metric.Tag) client {
+	c, ok := e.clients[prefix]
+	if !ok {
+		c = e.main.Clone(api.Prefix(prefix))
+		e.clients[prefix] = c
+	}
+	if len(tags) > 0 {
+		return c.Clone(tagsOption(tags))
+	}
+	return c
+}
+
+// switchTo connects to statsd at addr and sends buffered metrics to it.
+func (e *endpoint) switchTo(addr string) error {
+	e.mu.Lock()
+	if e.addr == addr || e.switching {
+		e.mu.Unlock()
+		return nil
+	}
+	e.switching = true
+	e.mu.Unlock()
+
+	c, err := e.dial(append(e.opts, api.Address(addr))...)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.switching = false
+	if err != nil {
+		if e.main == nil {
+			e.pending = nil
+		}
+		return err
+	}
+	old := e.main
+	e.main, e.addr, e.clients = c, addr, make(map[string]client)
+	for _, p := range e.pending {
+		p.f(e.client(p.prefix, p.tags))
+	}
+	e.pending = nil
+	if old != nil {
+		// flushes old client buffer
+		go old.Close()
+	}
+	return nil
+}
+
+// failover switches to another instance if current one is not in addrs.
+func (e *endpoint) failover(addrs dcy.Addresses) {
+	if len(addrs) == 0 {
+		return
+	}
+	e.mu.Lock()
+	current := e.addr
+	e.mu.Unlock()
+	for _, a := range addrs {
+		if a.String() == current {
+			return
+		}
+	}
+	addr := addrs[rand.Intn(len(addrs))].String()
+	if err := e.switchTo(addr); err != nil {
+		logger().S("addr", addr).Error(err)
+		return
+	}
+	logger().S("from", current).S("addr", addr).Info("statsd switched")
+}
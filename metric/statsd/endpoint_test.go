@@ -0,0 +1,99 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/metric"
+	"github.com/stretchr/testify/assert"
+
+	api "github.com/alexcesaro/statsd"
+)
+
+func listen(t *testing.T) (*net.UDPConn, dcy.Address) {
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := c.LocalAddr().(*net.UDPAddr)
+	return c, dcy.Address{Address: "127.0.0.1", Port: a.Port}
+}
+
+func read(c *net.UDPConn) string {
+	buf := make([]byte, 1024)
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		// skip empty packets sent by client on connect
+		n, err := c.Read(buf)
+		if err != nil || n > 0 {
+			return string(buf[:n])
+		}
+	}
+}
+
+func TestEndpointFailover(t *testing.T) {
+	c1, a1 := listen(t)
+	defer c1.Close()
+	c2, a2 := listen(t)
+	defer c2.Close()
+
+	ep := newEndpoint([]api.Option{api.FlushPeriod(10 * time.Millisecond)})
+	assert.NoError(t, ep.switchTo(a1.String()))
+	ep.send("app", nil, func(c client) { c.Count("req", 1) })
+	assert.Equal(t, "app.req:1|c", read(c1))
+
+	// current instance still registered
+	ep.failover(dcy.Addresses{a2, a1})
+	assert.Equal(t, a1.String(), ep.addr)
+
+	ep.failover(dcy.Addresses{a2})
+	assert.Equal(t, a2.String(), ep.addr)
+	ep.send("app", nil, func(c client) { c.Count("req", 2) })
+	assert.Equal(t, "app.req:2|c", read(c2))
+}
+
+func TestEndpointBuffer(t *testing.T) {
+	c, a := listen(t)
+	defer c.Close()
+	ep := newEndpoint([]api.Option{api.FlushPeriod(10 * time.Millisecond)})
+	ep.send("app", nil, func(c client) { c.Gauge("g", 3) })
+	assert.Len(t, ep.pending, 1)
+	assert.NoError(t, ep.switchTo(a.String()))
+	assert.Len(t, ep.pending, 0)
+	assert.Equal(t, "app.g:3|g", read(c))
+}
+
+func TestEndpointBatch(t *testing.T) {
+	c, a := listen(t)
+	defer c.Close()
+	ep := newEndpoint([]api.Option{api.FlushPeriod(50 * time.Millisecond)})
+	assert.NoError(t, ep.switchTo(a.String()))
+	for i := 0; i < 3; i++ {
+		ep.send("app", nil, func(c client) { c.Count("req", 1) })
+	}
+	assert.Equal(t, "app.req:1|c\napp.req:1|c\napp.req:1|c", read(c))
+
+	ep.onWriteError(nil)
+	ep.reportDropped("app")
+	assert.Equal(t, "app.statsd.dropped.write:1|c", read(c))
+}
+
+func TestDialReplacesDriver(t *testing.T) {
+	defer metric.Set(metric.NewNoop())
+	c, a := listen(t)
+	defer c.Close()
+	other := metric.NewMemory()
+	metric.Set(other)
+	assert.NoError(t, Dial("app", a.String()))
+	first := metric.Get().(metric.Multi)[1]
+	assert.NoError(t, Dial("app", a.String()))
+	m, ok := metric.Get().(metric.Multi)
+	assert.True(t, ok)
+	assert.Len(t, m, 2)
+	assert.Equal(t, other, m[0])
+	assert.True(t, first != m[1])
+	_, ok = m[1].(*Statsd)
+	assert.True(t, ok)
+}
@@ -2,6 +2,7 @@ package statsd
 
 import (
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -16,24 +17,27 @@ import (
 
 const (
 	StatsdServiceName = "statsd" //Default name in service discovery
+	// EnvTags adds app, dc, node and version DogStatsD tags to all metrics when set to 1 or true.
+	EnvTags = "SVCKIT_STATSD_TAGS"
 )
 
 type client interface {
 	Count(stat string, value interface{})
 	Gauge(stat string, value interface{})
 	Timing(stat string, value interface{})
+	Histogram(stat string, value interface{})
 	Clone(opts ...api.Option) *api.Client
+	Close()
 }
 
 // Statsd metric driver.
 // Implements metirc.Metric interface.
-// Client is underlaying library.
+// Metrics are sent through endpoint shared by all prefixes.
 type Statsd struct {
-	prefix     string
-	mainClient client
-	client     client
-	mapLock    sync.Mutex
-	prefixes   map[string]*Statsd
+	prefix   string
+	ep       *endpoint
+	mapLock  sync.Mutex
+	prefixes map[string]*Statsd
 }
 
 // Same as Dial but raises Fatal on error.
@@ -50,13 +54,19 @@ again:
 	}
 }
 
-// Connect to statsd server and set it as metric driver.
-//   opts[0] - prefix
-//   opts[1] - statsd address
+// Connect to statsd server and add it as metric driver (see metric.Add).
+//
+//	opts[0] - prefix
+//	opts[1] - statsd address
+//
+// When address is discovered in Consul, driver follows statsd service changes
+// and switches to another instance when current one is gone.
+// Calling Dial again replaces previous statsd driver, other drivers are kept.
 // Examples:
-//   Dial()
-//   Dial("my_app")
-//   Dial("my_appp", "127.0.0.1:8125")
+//
+//	Dial()
+//	Dial("my_app")
+//	Dial("my_appp", "127.0.0.1:8125")
 func Dial(opts ...string) error {
 	prefix := fmt.Sprintf("%s.%s", env.AppName(), env.NodeName())
 	addr := ""
@@ -78,48 +88,127 @@ func Dial(opts ...string) error {
 		}
 		addr = a.String()
 	}
-	mainClient, err := api.New(api.Address(addr))
-	withPrefix := mainClient.Clone(api.Prefix(prefix))
-	if err != nil {
+	apiOpts := []api.Option{api.TagsFormat(api.Datadog)}
+	if e := os.Getenv(EnvTags); e != "" && e != "0" && e != "false" {
+		apiOpts = append(apiOpts, tagsOption(metric.EnvNamespace().Tags()))
+	}
+	ep := newEndpoint(apiOpts)
+	if err := ep.switchTo(addr); err != nil {
 		return err
 	}
-	//set statsd as metric dirver
-	metric.Set(&Statsd{prefix: prefix, mainClient: mainClient, client: withPrefix, prefixes: make(map[string]*Statsd)})
+	unwatch := func() {}
+	if len(opts) < 2 {
+		// follow statsd moves in service discovery
+		unwatch = dcy.Watch(StatsdServiceName, func(addrs dcy.Addresses) {
+			go ep.failover(addrs)
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(DroppedInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				ep.reportDropped(prefix)
+			case <-done:
+				return
+			}
+		}
+	}()
+	// add statsd alongside other drivers, replacing previous Dial
+	replace(&Statsd{prefix: prefix, ep: ep, prefixes: make(map[string]*Statsd)}, func() {
+		unwatch()
+		close(done)
+	})
 	logger().S("addr", addr).S("prefix", prefix).Info("connected")
 	return nil
 }
 
+var (
+	dialMu   sync.Mutex
+	dialed   *Statsd
+	stopDial func()
+)
+
+// replace replaces driver of the previous Dial with d and stops its background work,
+// stop is kept for the next Dial.
+func replace(d *Statsd, stop func()) {
+	dialMu.Lock()
+	var prev metric.Metric
+	if dialed != nil {
+		prev = dialed
+	}
+	metric.Replace(prev, d)
+	prevStop := stopDial
+	dialed, stopDial = d, stop
+	dialMu.Unlock()
+	if prevStop != nil {
+		prevStop()
+	}
+}
+
 // Increments counter name for sum(values).
 // If called witohout values will increment for 1.
 func (i *Statsd) Counter(name string, values ...int) {
-	go func() {
-		value := 1
-		if len(values) > 0 {
-			value = 0
-			for _, v := range values {
-				value += v
-			}
+	value := 1
+	if len(values) > 0 {
+		value = 0
+		for _, v := range values {
+			value += v
 		}
-		i.client.Count(name, value)
-	}()
+	}
+	i.send(nil, func(c client) { c.Count(name, value) })
 }
 
 // Submits/Updates a statsd gauge type.
 func (i *Statsd) Gauge(name string, value int) {
-	go i.client.Gauge(name, value)
+	i.send(nil, func(c client) { c.Gauge(name, value) })
 }
 
 // Measures execution time for f and submits it as statsd timing type.
 func (i *Statsd) Timing(name string, f func()) {
 	stopwatch := metric.NewStopwatch()
 	f()
-	duration := stopwatch.GetNs()
-	go i.client.Timing(name, duration)
+	i.Time(name, stopwatch.GetNs())
 }
 
 // Submits a statsd timing type.
 func (i *Statsd) Time(name string, duration int) {
-	go i.client.Timing(name, duration)
+	i.send(nil, func(c client) { c.Timing(name, duration) })
+}
+
+// CounterTags increments counter with DogStatsD tags.
+func (i *Statsd) CounterTags(name string, value int, tags []metric.Tag) {
+	i.send(tags, func(c client) { c.Count(name, value) })
+}
+
+// GaugeTags sets gauge with DogStatsD tags.
+func (i *Statsd) GaugeTags(name string, value int, tags []metric.Tag) {
+	i.send(tags, func(c client) { c.Gauge(name, value) })
+}
+
+// TimeTags submits timing with DogStatsD tags.
+func (i *Statsd) TimeTags(name string, duration int, tags []metric.Tag) {
+	i.send(tags, func(c client) { c.Timing(name, duration) })
+}
+
+// Histogram submits statsd histogram type.
+func (i *Statsd) Histogram(name string, value float64, tags []metric.Tag) {
+	i.send(tags, func(c client) { c.Histogram(name, value) })
+}
+
+// send sends metric with client for the driver prefix and tags.
+func (i *Statsd) send(tags []metric.Tag, f func(client)) {
+	i.ep.send(i.prefix, tags, f)
+}
+
+func tagsOption(tags []metric.Tag) api.Option {
+	kv := make([]string, 0, 2*len(tags))
+	for _, t := range tags {
+		kv = append(kv, t.Key, t.Value)
+	}
+	return api.Tags(kv...)
 }
 
 // Returns the clone of the original metric, but with a different prefix
@@ -130,7 +219,7 @@ func (i *Statsd) WithPrefix(prefix string) metric.Metric {
 	if ok && s != nil {
 		return s
 	}
-	i.prefixes[prefix] = &Statsd{prefix: prefix, mainClient: i.client, client: i.mainClient.Clone(api.Prefix(prefix)), prefixes: make(map[string]*Statsd)}
+	i.prefixes[prefix] = &Statsd{prefix: prefix, ep: i.ep, prefixes: make(map[string]*Statsd)}
 	return i.prefixes[prefix]
 }
 
@@ -3,11 +3,31 @@ package metric
 import "time"
 
 type Stopwatch struct {
-	start time.Time
+	start       time.Time
+	last        time.Time
+	skip        bool
+	tags        []Tag
+	checkpoints []Checkpoint
+}
+
+// Checkpoint is time between two Stopwatch checkpoints.
+type Checkpoint struct {
+	Name     string
+	Duration time.Duration
 }
 
 func NewStopwatch() *Stopwatch {
-	return &Stopwatch{start: time.Now()}
+	now := time.Now()
+	return &Stopwatch{start: now, last: now}
+}
+
+// NewSampledStopwatch creates stopwatch which submits checkpoints only for rate (0-1] fraction of stopwatches.
+// Checkpoints of one stopwatch are either all submitted or none.
+func NewSampledStopwatch(rate float64, tags ...Tag) *Stopwatch {
+	s := NewStopwatch()
+	s.skip = !sampled(rate)
+	s.tags = tags
+	return s
 }
 
 func (s *Stopwatch) GetMs() float64 {
@@ -23,3 +43,26 @@ func (s *Stopwatch) Lap() float64 {
 	s.start = time.Now()
 	return ret
 }
+
+// Checkpoint submits time since previous checkpoint (or stopwatch start) as timing name.
+//
+//	sw := metric.NewStopwatch()
+//	parse()
+//	sw.Checkpoint("req.parse")
+//	store()
+//	sw.Checkpoint("req.store")
+func (s *Stopwatch) Checkpoint(name string) time.Duration {
+	now := time.Now()
+	d := now.Sub(s.last)
+	s.last = now
+	s.checkpoints = append(s.checkpoints, Checkpoint{Name: name, Duration: d})
+	if !s.skip {
+		Time(name, int(d), s.tags...)
+	}
+	return d
+}
+
+// Checkpoints returns all checkpoints in order.
+func (s *Stopwatch) Checkpoints() []Checkpoint {
+	return s.checkpoints
+}
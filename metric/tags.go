@@ -0,0 +1,75 @@
+package metric
+
+// Tag is metric tag (label).
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// T creates tag.
+//
+//	metric.CounterT("req", 1, metric.T("route", route), metric.T("code", code))
+func T(key, value string) Tag {
+	return Tag{Key: key, Value: value}
+}
+
+// Tagged is implemented by drivers which support tags and histograms of arbitrary values.
+// Drivers without tags support get metrics without tags, and histogram values as gauges.
+type Tagged interface {
+	CounterTags(name string, value int, tags []Tag)
+	GaugeTags(name string, value int, tags []Tag)
+	TimeTags(name string, duration int, tags []Tag)
+	Histogram(name string, value float64, tags []Tag)
+}
+
+func count(d Metric, name string, value int, tags []Tag) {
+	if t, ok := d.(Tagged); ok && len(tags) > 0 {
+		t.CounterTags(name, value, tags)
+		return
+	}
+	d.Counter(name, value)
+}
+
+func gauge(d Metric, name string, value int, tags []Tag) {
+	if t, ok := d.(Tagged); ok && len(tags) > 0 {
+		t.GaugeTags(name, value, tags)
+		return
+	}
+	d.Gauge(name, value)
+}
+
+func timing(d Metric, name string, duration int, tags []Tag) {
+	if t, ok := d.(Tagged); ok && len(tags) > 0 {
+		t.TimeTags(name, duration, tags)
+		return
+	}
+	d.Time(name, duration)
+}
+
+func observe(d Metric, name string, value float64, tags []Tag) {
+	if t, ok := d.(Tagged); ok {
+		t.Histogram(name, value, tags)
+		return
+	}
+	d.Gauge(name, int(value))
+}
+
+// prefixed returns name with prefix separated by dot.
+func prefixed(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// sum returns sum of values, 1 if there are no values.
+func sum(values []int) int {
+	if len(values) == 0 {
+		return 1
+	}
+	s := 0
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
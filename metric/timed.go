@@ -0,0 +1,32 @@
+package metric
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Timed starts timer, returned func submits elapsed time as timing name.
+//
+//	func handle() {
+//		defer metric.Timed("handle")()
+//		...
+//	}
+func Timed(name string, tags ...Tag) func() {
+	start := time.Now()
+	return func() {
+		Time(name, int(time.Since(start)), tags...)
+	}
+}
+
+// TimedSampled is Timed which submits only rate (0-1] fraction of timings.
+// Use it on hot paths where every call timing is too expensive.
+func TimedSampled(name string, rate float64, tags ...Tag) func() {
+	if !sampled(rate) {
+		return func() {}
+	}
+	return Timed(name, tags...)
+}
+
+func sampled(rate float64) bool {
+	return rate >= 1 || rand.Float64() < rate
+}
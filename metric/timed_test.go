@@ -0,0 +1,54 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type timings struct {
+	Noop
+	times map[string]int
+}
+
+func (t *timings) Time(name string, duration int) {
+	t.times[name]++
+}
+
+func TestTimed(t *testing.T) {
+	defer Set(NewNoop())
+	tm := &timings{times: map[string]int{}}
+	Set(tm)
+	func() {
+		defer Timed("op")()
+	}()
+	for i := 0; i < 10; i++ {
+		TimedSampled("sampled", 0)()
+		TimedSampled("all", 1)()
+	}
+	assert.Equal(t, 1, tm.times["op"])
+	assert.Equal(t, 0, tm.times["sampled"])
+	assert.Equal(t, 10, tm.times["all"])
+}
+
+func TestCheckpoint(t *testing.T) {
+	defer Set(NewNoop())
+	tm := &timings{times: map[string]int{}}
+	Set(tm)
+	sw := NewStopwatch()
+	time.Sleep(time.Millisecond)
+	sw.Checkpoint("parse")
+	sw.Checkpoint("store")
+	cs := sw.Checkpoints()
+	assert.Len(t, cs, 2)
+	assert.Equal(t, "parse", cs[0].Name)
+	assert.True(t, cs[0].Duration >= time.Millisecond)
+	assert.True(t, cs[1].Duration < cs[0].Duration)
+	assert.Equal(t, 1, tm.times["store"])
+
+	sw = NewSampledStopwatch(0)
+	sw.Checkpoint("skipped")
+	assert.Equal(t, 0, tm.times["skipped"])
+	assert.Len(t, sw.Checkpoints(), 1)
+}
@@ -0,0 +1,218 @@
+package nsq
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/signal"
+
+	gonsq "github.com/nsqio/go-nsq"
+)
+
+// ErrNoNsqd is returned when there is no nsqd to publish to.
+var ErrNoNsqd = errors.New("no nsqd found")
+
+// publisher is implemented by go-nsq Producer.
+type publisher interface {
+	Publish(topic string, body []byte) error
+	MultiPublish(topic string, body [][]byte) error
+	Stop()
+}
+
+var (
+	lookupNsqds = func() (dcy.Addresses, error) {
+		return dcy.Services(NsqdTCPServiceName)
+	}
+	newPublisher = func(addr string) (publisher, error) {
+		p, err := gonsq.NewProducer(addr, gonsq.NewConfig())
+		if err != nil {
+			return nil, err
+		}
+		p.SetLogger(defaults.logger, defaults.logLevel)
+		return p, nil
+	}
+)
+
+// BalancedProducer publishes to all nsqd instances registered in Consul (NsqdTCPServiceName).
+// Publishes are distributed round robin, on failure next instance is tried.
+// When all instances fail publish is retried with backoff (see PublishRetry).
+// List of instances is updated on Consul changes.
+// If EnvNsqd is set only that nsqd is used.
+type BalancedProducer struct {
+	topic   string
+	o       options
+	next    uint32
+	mu      sync.Mutex
+	byAddr  map[string]publisher
+	pubs    []publisher
+	unwatch func()
+}
+
+// MustNewBalancedProducer creates BalancedProducer, fatal on error.
+func MustNewBalancedProducer(topic string, opts ...func(*options)) *BalancedProducer {
+	p, err := NewBalancedProducer(topic, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
+
+// NewBalancedProducer creates BalancedProducer for topic.
+func NewBalancedProducer(topic string, opts ...func(*options)) (*BalancedProducer, error) {
+	Set(opts...)
+	p := &BalancedProducer{topic: topic, o: *defaults}
+	if e := os.Getenv(EnvNsqd); e != "" {
+		pub, err := newPublisher(e)
+		if err != nil {
+			return nil, err
+		}
+		p.set(e, pub)
+		return p, nil
+	}
+	addrs, err := lookupNsqds()
+	if err != nil || len(addrs) == 0 {
+		p.logger().S("nsqd", p.o.nsqdTCPAddr).Warn("nsqd not found in Consul, using default")
+		pub, err := newPublisher(p.o.nsqdTCPAddr)
+		if err != nil {
+			return nil, err
+		}
+		p.set(p.o.nsqdTCPAddr, pub)
+	} else if err := p.update(addrs); err != nil {
+		return nil, err
+	}
+	p.unwatch = dcy.Watch(NsqdTCPServiceName, p.onNsqdChanges)
+	return p, nil
+}
+
+func (p *BalancedProducer) set(addr string, pub publisher) {
+	p.byAddr = map[string]publisher{addr: pub}
+	p.pubs = []publisher{pub}
+}
+
+func (p *BalancedProducer) logger() *log.Agregator {
+	return logger().S("topic", p.topic)
+}
+
+func (p *BalancedProducer) onNsqdChanges(addrs dcy.Addresses) {
+	if len(addrs) == 0 {
+		p.logger().Warn("nsqd list empty, keeping previous")
+		return
+	}
+	if err := p.update(addrs); err != nil {
+		p.logger().Error(err)
+	}
+}
+
+// update creates publishers for new addresses and stops removed ones.
+func (p *BalancedProducer) update(addrs dcy.Addresses) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var (
+		byAddr = make(map[string]publisher, len(addrs))
+		pubs   []publisher
+		errs   []error
+	)
+	for _, a := range addrs {
+		addr := a.String()
+		if _, ok := byAddr[addr]; ok {
+			continue
+		}
+		pub, ok := p.byAddr[addr]
+		if !ok {
+			var err error
+			if pub, err = newPublisher(addr); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		byAddr[addr] = pub
+		pubs = append(pubs, pub)
+	}
+	if len(pubs) == 0 {
+		return errors.Join(errs...)
+	}
+	for addr, old := range p.byAddr {
+		if _, ok := byAddr[addr]; !ok {
+			old.Stop()
+		}
+	}
+	p.byAddr = byAddr
+	p.pubs = pubs
+	p.logger().S("nsqds", fmt.Sprintf("%v", addrs.String())).Debug("nsqds update")
+	return errors.Join(errs...)
+}
+
+func (p *BalancedProducer) publishers() []publisher {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pubs
+}
+
+// try calls f on each publisher, round robin, until first success.
+func (p *BalancedProducer) try(f func(publisher) error) error {
+	pubs := p.publishers()
+	if len(pubs) == 0 {
+		return ErrNoNsqd
+	}
+	start := int(atomic.AddUint32(&p.next, 1))
+	var err error
+	for i := range pubs {
+		if err = f(pubs[(start+i)%len(pubs)]); err == nil {
+			return nil
+		}
+		metric.CounterT("nsq.publish.error", 1, metric.T("topic", p.topic))
+	}
+	return err
+}
+
+func (p *BalancedProducer) publish(f func(publisher) error) error {
+	b := signal.NewBackoff()
+	b.MaxElapsedTime = p.o.publishRetry
+	b.Notify = func(err error, next time.Duration) {
+		p.logger().S("error", err.Error()).S("retry", next.String()).Warn("publish failed")
+	}
+	return b.Retry(signal.ShutdownContext(), func() error { return p.try(f) })
+}
+
+// Publish publishes message to the producer topic.
+func (p *BalancedProducer) Publish(msg []byte) error {
+	return p.PublishTo(p.topic, msg)
+}
+
+// PublishTo publishes message to topic.
+func (p *BalancedProducer) PublishTo(topic string, msg []byte) error {
+	return p.publish(func(pub publisher) error { return pub.Publish(topic, msg) })
+}
+
+// MultiPublish publishes messages to the producer topic in one command.
+func (p *BalancedProducer) MultiPublish(msgs [][]byte) error {
+	return p.publish(func(pub publisher) error { return pub.MultiPublish(p.topic, msgs) })
+}
+
+// MustPublish publishes message, fatal on error.
+func (p *BalancedProducer) MustPublish(msg []byte) {
+	if err := p.Publish(msg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Close stops all nsqd producers.
+func (p *BalancedProducer) Close() {
+	if p.unwatch != nil {
+		p.unwatch()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pub := range p.pubs {
+		pub.Stop()
+	}
+	p.pubs = nil
+	p.byAddr = nil
+}
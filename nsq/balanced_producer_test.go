@@ -0,0 +1,103 @@
+package nsq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePublisher struct {
+	addr    string
+	fail    bool
+	msgs    int
+	stopped bool
+}
+
+func (f *fakePublisher) Publish(topic string, body []byte) error {
+	if f.fail {
+		return errors.New("connection refused")
+	}
+	f.msgs++
+	return nil
+}
+
+func (f *fakePublisher) MultiPublish(topic string, body [][]byte) error {
+	return f.Publish(topic, nil)
+}
+
+func (f *fakePublisher) Stop() { f.stopped = true }
+
+func TestBalancedProducer(t *testing.T) {
+	defer func(d *options, l func() (dcy.Addresses, error), n func(string) (publisher, error)) {
+		defaults, lookupNsqds, newPublisher = d, l, n
+	}(defaults, lookupNsqds, newPublisher)
+	defaults = &options{nsqdTCPAddr: "127.0.0.1:4150", logger: &nsqLogger{}}
+	pubs := map[string]*fakePublisher{}
+	newPublisher = func(addr string) (publisher, error) {
+		p := &fakePublisher{addr: addr}
+		pubs[addr] = p
+		return p, nil
+	}
+	lookupNsqds = func() (dcy.Addresses, error) {
+		return dcy.Addresses{{Address: "10.0.0.1", Port: 4150}, {Address: "10.0.0.2", Port: 4150}}, nil
+	}
+
+	p, err := NewBalancedProducer("topic")
+	assert.Nil(t, err)
+	defer p.Close()
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, p.Publish([]byte("msg")))
+	}
+	assert.Equal(t, 2, pubs["10.0.0.1:4150"].msgs)
+	assert.Equal(t, 2, pubs["10.0.0.2:4150"].msgs)
+
+	// failover to healthy instance
+	pubs["10.0.0.1:4150"].fail = true
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, p.Publish([]byte("msg")))
+	}
+	assert.Equal(t, 4, pubs["10.0.0.2:4150"].msgs)
+
+	// topology change
+	p.onNsqdChanges(dcy.Addresses{{Address: "10.0.0.2", Port: 4150}, {Address: "10.0.0.3", Port: 4150}})
+	assert.True(t, pubs["10.0.0.1:4150"].stopped)
+	assert.False(t, pubs["10.0.0.2:4150"].stopped)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, p.Publish([]byte("msg")))
+	}
+	assert.Equal(t, 1, pubs["10.0.0.3:4150"].msgs)
+
+	// all failing, retry gives up
+	p.o.publishRetry = 1
+	pubs["10.0.0.2:4150"].fail = true
+	pubs["10.0.0.3:4150"].fail = true
+	assert.EqualError(t, p.Publish([]byte("msg")), "connection refused")
+}
+
+func TestBalancedProducerReplacesDefault(t *testing.T) {
+	defer func(d *options, l func() (dcy.Addresses, error), n func(string) (publisher, error)) {
+		defaults, lookupNsqds, newPublisher = d, l, n
+	}(defaults, lookupNsqds, newPublisher)
+	defaults = &options{nsqdTCPAddr: "127.0.0.1:4150", logger: &nsqLogger{}}
+	pubs := map[string]*fakePublisher{}
+	newPublisher = func(addr string) (publisher, error) {
+		p := &fakePublisher{addr: addr}
+		pubs[addr] = p
+		return p, nil
+	}
+	lookupNsqds = func() (dcy.Addresses, error) { return nil, nil }
+
+	p, err := NewBalancedProducer("topic")
+	assert.Nil(t, err)
+	defer p.Close()
+	assert.NotNil(t, pubs["127.0.0.1:4150"])
+
+	// nsqd appears in consul, default is stopped
+	p.onNsqdChanges(dcy.Addresses{{Address: "10.0.0.1", Port: 4150}, {Address: "10.0.0.1", Port: 4150}})
+	assert.True(t, pubs["127.0.0.1:4150"].stopped)
+	assert.Nil(t, p.Publish([]byte("msg")))
+	assert.Equal(t, 1, pubs["10.0.0.1:4150"].msgs)
+	assert.Equal(t, 1, len(p.publishers()))
+}
@@ -16,6 +16,8 @@ type Consumer struct {
 	onceClose   sync.Once
 	logger      func() *log.Agregator
 	lookups     dcy.Addresses
+	topic       string
+	channel     string
 }
 
 type nsqHandler struct {
@@ -38,6 +40,10 @@ func MustNewConsumer(topic string, handler func(*Message) error,
 
 func NewConsumer(topic string, handler func(*Message) error,
 	opts ...func(*options)) (*Consumer, error) {
+	return newConsumer(topic, &nsqHandler{fn: handler}, opts...)
+}
+
+func newConsumer(topic string, handler gonsq.Handler, opts ...func(*options)) (*Consumer, error) {
 	Set(opts...)
 
 	cfg := gonsq.NewConfig()
@@ -49,8 +55,12 @@ func NewConsumer(topic string, handler func(*Message) error,
 		return nil, err
 	}
 
+	concurrency := defaults.concurrency
+	if concurrency <= 0 {
+		concurrency = cfg.MaxInFlight
+	}
 	c.SetLogger(defaults.logger, defaults.logLevel)
-	c.AddConcurrentHandlers(&nsqHandler{fn: handler}, cfg.MaxInFlight)
+	c.AddConcurrentHandlers(handler, concurrency)
 
 	err = c.ConnectToNSQLookupds(defaults.lookupds.String())
 	if err != nil {
@@ -60,13 +70,16 @@ func NewConsumer(topic string, handler func(*Message) error,
 	co := &Consumer{
 		lookups:     defaults.lookupds,
 		nsqConsumer: c,
+		topic:       topic,
+		channel:     defaults.channel,
 		logger: func() *log.Agregator {
 			return logger().S("topic", topic).S("channel", defaults.channel)
 		},
 	}
 
-	co.logger().I("maxInFlight", defaults.maxInFlight).Info("starting consumer")
+	co.logger().I("maxInFlight", defaults.maxInFlight).I("concurrency", concurrency).Info("starting consumer")
 	dcy.Subscribe(LookupdHTTPServiceName, co.onLookupChanges)
+	addConsumed(co.topic, co.channel)
 	return co, nil
 }
 
@@ -90,6 +103,7 @@ func (c *Consumer) onLookupChanges(as dcy.Addresses) {
 func (c *Consumer) Close() {
 	c.onceClose.Do(func() {
 		dcy.Unsubscribe(LookupdHTTPServiceName, c.onLookupChanges)
+		removeConsumed(c.topic, c.channel)
 		c.nsqConsumer.Stop()
 		<-c.nsqConsumer.StopChan
 	})
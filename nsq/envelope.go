@@ -19,8 +19,10 @@ type Envelope struct {
 	ReplyTo string `json:"r,omitempty"`
 	// connection between request and response
 	CorrelationId string `json:"c,omitempty"`
-	// unix timestamp when message expires, after that should be dropped
+	// unix timestamp in milliseconds when message expires, after that should be dropped
 	ExpiresAt int64 `json:"e,omitempty"`
+	// error returned by remote handler
+	Error string `json:"x,omitempty"`
 	// message body
 	Body []byte `json:"-"`
 }
@@ -75,5 +77,5 @@ func (m *Envelope) Expired() bool {
 	if m.ExpiresAt <= 0 {
 		return false
 	}
-	return time.Now().Unix() > m.ExpiresAt
+	return time.Now().UnixMilli() > m.ExpiresAt
 }
@@ -0,0 +1,68 @@
+package nsq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/signal"
+
+	gonsq "github.com/nsqio/go-nsq"
+)
+
+// Handle starts consumer of topic which calls handler for each message.
+// Message is requeued when handler returns error (or panics).
+// Handler context is cancelled on shutdown or when nsq message timeout expires.
+// Lookupds are discovered in Consul and updated on changes.
+// Consumer is closed on shutdown (signal.PriorityStopAccepting).
+//
+//	nsq.Handle("orders", func(ctx context.Context, m *nsq.Message) error {
+//		return process(ctx, m.Body)
+//	}, nsq.Concurrency(16))
+func Handle(topic string, handler func(ctx context.Context, m *Message) error,
+	opts ...func(*options)) (*Consumer, error) {
+	h := &ctxHandler{
+		fn:      handler,
+		timeout: gonsq.NewConfig().MsgTimeout,
+		logger: func() *log.Agregator {
+			return logger().S("topic", topic)
+		},
+	}
+	c, err := newConsumer(topic, h, opts...)
+	if err != nil {
+		return nil, err
+	}
+	signal.OnShutdown("nsq consumer "+topic, signal.PriorityStopAccepting, 0, c.Close)
+	return c, nil
+}
+
+// MustHandle is Handle which is fatal on error.
+func MustHandle(topic string, handler func(ctx context.Context, m *Message) error,
+	opts ...func(*options)) *Consumer {
+	c, err := Handle(topic, handler, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
+type ctxHandler struct {
+	fn      func(context.Context, *Message) error
+	timeout time.Duration
+	logger  func() *log.Agregator
+}
+
+func (h *ctxHandler) HandleMessage(m *gonsq.Message) (err error) {
+	ctx, cancel := context.WithTimeout(signal.ShutdownContext(), h.timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			h.logger().I("attempts", int(m.Attempts)).S("error", err.Error()).Info("message requeued")
+		}
+	}()
+	return h.fn(ctx, newMessage(m))
+}
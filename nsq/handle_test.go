@@ -0,0 +1,40 @@
+package nsq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	"github.com/stretchr/testify/assert"
+
+	gonsq "github.com/nsqio/go-nsq"
+)
+
+func TestCtxHandler(t *testing.T) {
+	var calls int
+	h := &ctxHandler{
+		timeout: time.Minute,
+		logger:  func() *log.Agregator { return logger() },
+		fn: func(ctx context.Context, m *Message) error {
+			calls++
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			switch string(m.Body) {
+			case "fail":
+				return errors.New("fail")
+			case "panic":
+				panic("boom")
+			}
+			return nil
+		},
+	}
+	msg := func(body string) *gonsq.Message {
+		return gonsq.NewMessage(gonsq.MessageID{}, []byte(body))
+	}
+	assert.Nil(t, h.HandleMessage(msg("ok")))
+	assert.EqualError(t, h.HandleMessage(msg("fail")), "fail")
+	assert.EqualError(t, h.HandleMessage(msg("panic")), "panic: boom")
+	assert.Equal(t, 3, calls)
+}
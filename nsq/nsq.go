@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/minus5/svckit/dcy"
 	"github.com/minus5/svckit/env"
@@ -18,6 +19,7 @@ import (
 
 const (
 	DefaultMaxInFlight     = 256
+	DefaultPublishRetry    = 10 * time.Second
 	LookupdHTTPServiceName = "nsqlookupd-http"
 	NsqdTCPServiceName     = "nsqd-tcp"
 	EnvNsqd                = "SVCKIT_NSQD"
@@ -49,6 +51,8 @@ func initDefaults() {
 		lookupds:    dcy.Addresses{dcy.Address{Address: "127.0.0.1", Port: 4161}},
 		logLevel:    gonsq.LogLevelWarning,
 		logger:      &nsqLogger{},
+
+		publishRetry: DefaultPublishRetry,
 	}
 	if e, ok := os.LookupEnv(EnvNsqd); ok && e != "" {
 		defaults.nsqdTCPAddr = e
@@ -67,6 +71,7 @@ func initDefaults() {
 	if err := signal.WithExponentialBackoff(connect); err != nil {
 		logger().Fatal(err)
 	}
+	initStats()
 }
 
 func logger() *log.Agregator {
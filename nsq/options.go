@@ -9,6 +9,7 @@ package nsq
 
 import (
 	"strings"
+	"time"
 
 	"github.com/minus5/svckit/dcy"
 	"github.com/minus5/svckit/log"
@@ -46,11 +47,14 @@ func (n *nsqLogger) Output(calldepth int, s string) error {
 
 type options struct {
 	maxInFlight int
+	concurrency int
 	channel     string
 	nsqdTCPAddr string
 	logger      *nsqLogger
 	logLevel    gonsq.LogLevel
 	lookupds    dcy.Addresses
+	// max time of publish retries in BalancedProducer
+	publishRetry time.Duration
 }
 
 func (c *options) apply(opts ...func(*options)) *options {
@@ -66,8 +70,31 @@ func MaxInFlight(m int) func(*options) {
 	}
 }
 
+// Concurrency sets number of goroutines handling consumer messages.
+// Default is max in flight.
+func Concurrency(n int) func(*options) {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
 func Channel(c string) func(*options) {
 	return func(o *options) {
 		o.channel = c
 	}
 }
+
+// NsqdTCPAddr sets nsqd address used by producers.
+func NsqdTCPAddr(addr string) func(*options) {
+	return func(o *options) {
+		o.nsqdTCPAddr = addr
+	}
+}
+
+// PublishRetry sets max duration of publish retries in BalancedProducer.
+// Default is 10s, 0 retries until shutdown.
+func PublishRetry(d time.Duration) func(*options) {
+	return func(o *options) {
+		o.publishRetry = d
+	}
+}
@@ -38,6 +38,11 @@ func (p *Producer) Publish(msg []byte) error {
 	return p.nsqProducer.Publish(p.topic, msg)
 }
 
+// MultiPublish publishes messages to the producer topic in one command.
+func (p *Producer) MultiPublish(msgs [][]byte) error {
+	return p.nsqProducer.MultiPublish(p.topic, msgs)
+}
+
 func (p *Producer) PublishTo(topic string, msg []byte) error {
 	return p.nsqProducer.Publish(topic, msg)
 }
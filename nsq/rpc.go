@@ -0,0 +1,195 @@
+package nsq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// RemoteError is error returned by remote rpc handler.
+type RemoteError struct {
+	Type    string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// RPCClient is typed request response client over nsq.
+// Each client has its own ephemeral reply topic.
+//
+//	c := nsq.NewRPCClient()
+//	defer c.Close()
+//	ctx, cancel := context.WithTimeout(ctx, time.Second)
+//	defer cancel()
+//	rsp, err := nsq.Call[GetUser, User](ctx, c, "users.req", "get_user.req", GetUser{ID: 1})
+type RPCClient struct {
+	rr *RrProducer
+}
+
+// NewRPCClient creates client listening for responses on its own reply topic.
+func NewRPCClient() *RPCClient {
+	return &RPCClient{rr: RrPub(replyTopic())}
+}
+
+var rpcClients uint32
+
+// replyTopic is unique ephemeral topic for each client in the process.
+func replyTopic() string {
+	n := atomic.AddUint32(&rpcClients, 1)
+	return fmt.Sprintf("%s-%s-%d-%d.rsp#ephemeral", env.AppName(), env.NodeName(), os.Getpid(), n)
+}
+
+// Close stops client.
+func (c *RPCClient) Close() {
+	c.rr.Close()
+}
+
+// Call sends request of type typ to topic and waits for response.
+// Waiting is stopped when ctx is done, ctx deadline is used as message ttl
+// (expired requests are dropped by server).
+// Error returned by remote handler is *RemoteError.
+func Call[Req, Rsp any](ctx context.Context, c *RPCClient, topic, typ string, req Req) (Rsp, error) {
+	var rsp Rsp
+	var ttl time.Duration
+	if d, ok := ctx.Deadline(); ok {
+		if ttl = time.Until(d); ttl <= 0 {
+			return rsp, fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded)
+		}
+	}
+	re, err := c.rr.request(topic, typ, req, ctx.Done(), ttl)
+	if err == ErrTimeout && ctx.Err() != nil {
+		return rsp, fmt.Errorf("%w: %w", ErrTimeout, ctx.Err())
+	}
+	if err != nil {
+		return rsp, err
+	}
+	if re.Error != "" {
+		return rsp, &RemoteError{Type: re.Type, Message: re.Error}
+	}
+	err = re.ParseBody(&rsp)
+	return rsp, err
+}
+
+// RPCServer is typed request response server over nsq.
+// Handler errors are returned to the client as RemoteError.
+//
+//	s := nsq.NewRPCServer("users.req")
+//	nsq.HandleRPC(s, "get_user.req", func(ctx context.Context, req GetUser) (User, error) {
+//		return store.User(ctx, req.ID)
+//	})
+//	s.Start()
+type RPCServer struct {
+	topic     string
+	handlers  map[string]func(context.Context, []byte) (interface{}, error)
+	sub       *Consumer
+	reply     func(topic string, e *Envelope) error
+	producers map[string]*Producer
+	sync.Mutex
+}
+
+// NewRPCServer creates server for requests arriving on topic.
+// Register handlers with HandleRPC and then call Start.
+func NewRPCServer(topic string) *RPCServer {
+	s := &RPCServer{
+		topic:     topic,
+		handlers:  make(map[string]func(context.Context, []byte) (interface{}, error)),
+		producers: make(map[string]*Producer),
+	}
+	s.reply = func(topic string, e *Envelope) error {
+		return s.pub(topic).Publish(e.Bytes())
+	}
+	return s
+}
+
+func (s *RPCServer) pub(topic string) *Producer {
+	s.Lock()
+	defer s.Unlock()
+	if p, ok := s.producers[topic]; ok {
+		return p
+	}
+	p := Pub(topic)
+	s.producers[topic] = p
+	return p
+}
+
+// HandleRPC registers handler for requests of type typ.
+func HandleRPC[Req, Rsp any](s *RPCServer, typ string, handler func(context.Context, Req) (Rsp, error)) {
+	s.Lock()
+	defer s.Unlock()
+	s.handlers[typ] = func(ctx context.Context, body []byte) (interface{}, error) {
+		var req Req
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Start starts consuming requests.
+func (s *RPCServer) Start(opts ...func(*options)) error {
+	c, err := Handle(s.topic, s.handle, opts...)
+	if err != nil {
+		return err
+	}
+	s.sub = c
+	return nil
+}
+
+func (s *RPCServer) handle(ctx context.Context, m *Message) error {
+	eReq, err := NewEnvelope(m.Body)
+	if err != nil {
+		log.S("topic", s.topic).Error(err)
+		return nil
+	}
+	if eReq.Expired() {
+		log.S("type", eReq.Type).S("correlationId", eReq.CorrelationId).Info("expired")
+		return nil
+	}
+	if eReq.ExpiresAt > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.UnixMilli(eReq.ExpiresAt))
+		defer cancel()
+	}
+	s.Lock()
+	h, ok := s.handlers[eReq.Type]
+	s.Unlock()
+	var rsp interface{}
+	if !ok {
+		err = fmt.Errorf("unknown request type %s", eReq.Type)
+	} else {
+		rsp, err = h(ctx, eReq.Body)
+	}
+	if eReq.ReplyTo == "" {
+		return nil
+	}
+	eRsp, rerr := eReq.Reply(rsp)
+	if rerr != nil {
+		return rerr
+	}
+	if err != nil {
+		eRsp.Body = nil
+		eRsp.Error = err.Error()
+	}
+	return s.reply(eReq.ReplyTo, eRsp)
+}
+
+// Close stops server.
+func (s *RPCServer) Close() {
+	if s.sub != nil {
+		s.sub.Close()
+	}
+	s.Lock()
+	defer s.Unlock()
+	for _, p := range s.producers {
+		p.Close()
+	}
+}
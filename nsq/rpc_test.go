@@ -0,0 +1,74 @@
+package nsq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type getUser struct {
+	ID int `json:"id"`
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestRPCServer(t *testing.T) {
+	s := NewRPCServer("users.req")
+	var replies []*Envelope
+	s.reply = func(topic string, e *Envelope) error {
+		assert.Equal(t, "client.rsp", topic)
+		replies = append(replies, e)
+		return nil
+	}
+	HandleRPC(s, "get_user.req", func(ctx context.Context, req getUser) (user, error) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		if req.ID != 1 {
+			return user{}, errors.New("not found")
+		}
+		return user{Name: "pero"}, nil
+	})
+	request := func(typ string, req interface{}) *Envelope {
+		e := &Envelope{Type: typ, ReplyTo: "client.rsp", CorrelationId: "42",
+			ExpiresAt: time.Now().Add(time.Minute).UnixMilli()}
+		e.Body, _ = json.Marshal(req)
+		assert.Nil(t, s.handle(context.Background(), &Message{Body: e.Bytes()}))
+		return replies[len(replies)-1]
+	}
+
+	e := request("get_user.req", getUser{ID: 1})
+	assert.Equal(t, "get_user.rsp", e.Type)
+	assert.Equal(t, "42", e.CorrelationId)
+	var u user
+	assert.Nil(t, e.ParseBody(&u))
+	assert.Equal(t, "pero", u.Name)
+
+	e = request("get_user.req", getUser{ID: 2})
+	assert.Equal(t, "not found", e.Error)
+	assert.Empty(t, e.Body)
+
+	e = request("unknown.req", getUser{ID: 1})
+	assert.Equal(t, "unknown request type unknown.req", e.Error)
+
+	// decode envelope with error
+	e, err := NewEnvelope(e.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "unknown request type unknown.req", e.Error)
+}
+
+func TestReplyTopicUnique(t *testing.T) {
+	assert.NotEqual(t, replyTopic(), replyTopic())
+}
+
+func TestEnvelopeExpiresAtMillis(t *testing.T) {
+	e := &Envelope{ExpiresAt: time.Now().Add(-10 * time.Millisecond).UnixMilli()}
+	assert.True(t, e.Expired())
+	e.ExpiresAt = time.Now().Add(500 * time.Millisecond).UnixMilli()
+	assert.False(t, e.Expired())
+}
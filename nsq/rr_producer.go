@@ -81,12 +81,24 @@ func (s *RrProducer) pub(topic string) *Producer {
 // sig   - timout singal to signal stop witing for response
 // ttl   - time to live of message for envelope
 func (s *RrProducer) ReqRsp(topic, typ string, req interface{}, rsp interface{}, sig chan struct{}, ttl time.Duration) error {
+	re, err := s.request(topic, typ, req, sig, ttl)
+	if err != nil {
+		return err
+	}
+	if re.Error != "" {
+		return &RemoteError{Type: re.Type, Message: re.Error}
+	}
+	return re.ParseBody(rsp)
+}
+
+// request sends request and waits for response envelope.
+func (s *RrProducer) request(topic, typ string, req interface{}, sig <-chan struct{}, ttl time.Duration) (*Envelope, error) {
 	if ttl < 0 {
-		return ErrTimeout
+		return nil, ErrTimeout
 	}
 	buf, err := json.Marshal(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	correlationId := s.correlationId()
 	eReq := &Envelope{
@@ -96,26 +108,25 @@ func (s *RrProducer) ReqRsp(topic, typ string, req interface{}, rsp interface{},
 		Body:          buf,
 	}
 	if ttl > 0 {
-		eReq.ExpiresAt = time.Now().Add(ttl).Unix()
+		eReq.ExpiresAt = time.Now().Add(ttl).UnixMilli()
 	}
-	c := make(chan *Envelope)
+	// buffered, listener doesn't block if timeout happens meanwhile
+	c := make(chan *Envelope, 1)
 	s.add(correlationId, c)
 
 	p := s.pub(topic)
 	if err := p.Publish(eReq.Bytes()); err != nil {
-		return err
+		s.get(correlationId)
+		return nil, err
 	}
 
 	select {
 	case re := <-c:
-		if err := json.Unmarshal(re.Body, rsp); err != nil {
-			return err
-		}
+		return re, nil
 	case <-sig:
 		s.timeout(correlationId)
-		return ErrTimeout
+		return nil, ErrTimeout
 	}
-	return nil
 }
 
 // creates unique request identifier
@@ -0,0 +1,237 @@
+package nsq
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/metric"
+)
+
+// EnvStats enables consumer stats collection, value is interval (e.g. 30s).
+const EnvStats = "SVCKIT_NSQ_STATS"
+
+var (
+	consumedMu sync.Mutex
+	consumed   = make(map[topicChannel]int)
+)
+
+type topicChannel struct {
+	topic   string
+	channel string
+}
+
+func addConsumed(topic, channel string) {
+	consumedMu.Lock()
+	defer consumedMu.Unlock()
+	consumed[topicChannel{topic, channel}]++
+}
+
+func removeConsumed(topic, channel string) {
+	consumedMu.Lock()
+	defer consumedMu.Unlock()
+	tc := topicChannel{topic, channel}
+	if consumed[tc]--; consumed[tc] <= 0 {
+		delete(consumed, tc)
+	}
+}
+
+func consumedChannels() []topicChannel {
+	consumedMu.Lock()
+	defer consumedMu.Unlock()
+	tcs := make([]topicChannel, 0, len(consumed))
+	for tc := range consumed {
+		tcs = append(tcs, tc)
+	}
+	return tcs
+}
+
+// ChannelStats is state of the channel summed over all nsqd-s.
+type ChannelStats struct {
+	Depth         int64 `json:"depth"`
+	InFlightCount int64 `json:"in_flight_count"`
+	DeferredCount int64 `json:"deferred_count"`
+	RequeueCount  int64 `json:"requeue_count"`
+	TimeoutCount  int64 `json:"timeout_count"`
+}
+
+func (s *ChannelStats) add(s2 ChannelStats) {
+	s.Depth += s2.Depth
+	s.InFlightCount += s2.InFlightCount
+	s.DeferredCount += s2.DeferredCount
+	s.RequeueCount += s2.RequeueCount
+	s.TimeoutCount += s2.TimeoutCount
+}
+
+// CollectStats periodically polls nsqd-s (found through lookupds) for channels
+// consumed by this service and reports metrics tagged with topic and channel:
+//
+//	nsq.depth       gauge, messages waiting in channel
+//	nsq.in_flight   gauge
+//	nsq.deferred    gauge
+//	nsq.requeue     counter, requeued messages in interval
+//	nsq.timeout     counter, timed out messages in interval
+//
+// Returns function which stops collecting.
+func CollectStats(interval time.Duration) func() {
+	c := &statsCollector{
+		client: &http.Client{Timeout: 5 * time.Second},
+		last:   make(map[topicChannel]ChannelStats),
+		lookupds: func() dcy.Addresses {
+			Set()
+			return defaults.lookupds
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.collect()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+type statsCollector struct {
+	client   *http.Client
+	lookupds func() dcy.Addresses
+	last     map[topicChannel]ChannelStats
+}
+
+func (c *statsCollector) collect() {
+	for _, tc := range consumedChannels() {
+		s, err := c.stats(tc.topic, tc.channel)
+		if err != nil {
+			logger().S("topic", tc.topic).S("channel", tc.channel).Error(err)
+			continue
+		}
+		tags := []metric.Tag{metric.T("topic", tc.topic), metric.T("channel", tc.channel)}
+		metric.Gauge("nsq.depth", int(s.Depth), tags...)
+		metric.Gauge("nsq.in_flight", int(s.InFlightCount), tags...)
+		metric.Gauge("nsq.deferred", int(s.DeferredCount), tags...)
+		if last, ok := c.last[tc]; ok {
+			// counts are reset on nsqd restart
+			if d := s.RequeueCount - last.RequeueCount; d >= 0 {
+				metric.CounterT("nsq.requeue", int(d), tags...)
+			}
+			if d := s.TimeoutCount - last.TimeoutCount; d >= 0 {
+				metric.CounterT("nsq.timeout", int(d), tags...)
+			}
+		}
+		c.last[tc] = s
+	}
+}
+
+// stats returns channel stats summed over all nsqd-s which have the topic.
+func (c *statsCollector) stats(topic, channel string) (ChannelStats, error) {
+	var s ChannelStats
+	nsqds, err := c.lookup(topic)
+	if err != nil {
+		return s, err
+	}
+	for _, addr := range nsqds {
+		var rsp struct {
+			Topics []nsqdTopic `json:"topics"`
+			Data   struct {
+				Topics []nsqdTopic `json:"topics"`
+			} `json:"data"` // nsqd before 1.0
+		}
+		q := url.Values{"format": {"json"}, "topic": {topic}, "channel": {channel}}
+		if err := c.get(fmt.Sprintf("http://%s/stats?%s", addr, q.Encode()), &rsp); err != nil {
+			return s, err
+		}
+		if rsp.Topics == nil {
+			rsp.Topics = rsp.Data.Topics
+		}
+		for _, t := range rsp.Topics {
+			if t.Name != topic {
+				continue
+			}
+			for _, ch := range t.Channels {
+				if ch.Name == channel {
+					s.add(ch.ChannelStats)
+				}
+			}
+		}
+	}
+	return s, nil
+}
+
+type nsqdTopic struct {
+	Name     string `json:"topic_name"`
+	Channels []struct {
+		Name string `json:"channel_name"`
+		ChannelStats
+	} `json:"channels"`
+}
+
+// lookup returns http addresses of nsqd-s which have the topic.
+func (c *statsCollector) lookup(topic string) ([]string, error) {
+	type producer struct {
+		BroadcastAddress string `json:"broadcast_address"`
+		HTTPPort         int    `json:"http_port"`
+	}
+	var lastErr error
+	nsqds := make(map[string]struct{})
+	for _, l := range c.lookupds() {
+		var rsp struct {
+			Producers []producer `json:"producers"`
+			Data      struct {
+				Producers []producer `json:"producers"`
+			} `json:"data"` // nsqlookupd before 1.0
+		}
+		u := fmt.Sprintf("http://%s/lookup?topic=%s", l.String(), url.QueryEscape(topic))
+		if err := c.get(u, &rsp); err != nil {
+			lastErr = err
+			continue
+		}
+		if rsp.Producers == nil {
+			rsp.Producers = rsp.Data.Producers
+		}
+		for _, p := range rsp.Producers {
+			nsqds[net.JoinHostPort(p.BroadcastAddress, strconv.Itoa(p.HTTPPort))] = struct{}{}
+		}
+	}
+	if len(nsqds) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	addrs := make([]string, 0, len(nsqds))
+	for a := range nsqds {
+		addrs = append(addrs, a)
+	}
+	return addrs, nil
+}
+
+func (c *statsCollector) get(url string, v interface{}) error {
+	rsp, err := c.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s status %s", url, rsp.Status)
+	}
+	return json.NewDecoder(rsp.Body).Decode(v)
+}
+
+func initStats() {
+	if d, err := time.ParseDuration(os.Getenv(EnvStats)); err == nil && d > 0 {
+		CollectStats(d)
+	}
+}
@@ -0,0 +1,51 @@
+package nsq
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minus5/svckit/dcy"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/metric/metrictest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectStats(t *testing.T) {
+	requeue := 10
+	nsqd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "pero", r.URL.Query().Get("topic"))
+		fmt.Fprintf(w, `{"topics":[{"topic_name":"pero","channels":[
+			{"channel_name":"zdero","depth":5,"in_flight_count":2,"requeue_count":%d,"timeout_count":1},
+			{"channel_name":"other","depth":100}]}]}`, requeue)
+	}))
+	defer nsqd.Close()
+	port := nsqd.Listener.Addr().(*net.TCPAddr).Port
+	lookupd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// nsqlookupd before 1.0 format
+		fmt.Fprintf(w, `{"status_code":200,"data":{"producers":[{"broadcast_address":"127.0.0.1","http_port":%d}]}}`, port)
+	}))
+	defer lookupd.Close()
+	lport := lookupd.Listener.Addr().(*net.TCPAddr).Port
+
+	rec := metrictest.Start(t)
+	addConsumed("pero", "zdero")
+	defer removeConsumed("pero", "zdero")
+
+	c := &statsCollector{
+		client:   http.DefaultClient,
+		last:     make(map[topicChannel]ChannelStats),
+		lookupds: func() dcy.Addresses { return dcy.Addresses{{Address: "127.0.0.1", Port: lport}} },
+	}
+	c.collect()
+	requeue = 13
+	c.collect()
+	tags := []metric.Tag{metric.T("topic", "pero"), metric.T("channel", "zdero")}
+	rec.AssertGauge(t, "nsq.depth", 5, tags...)
+	rec.AssertGauge(t, "nsq.in_flight", 2, tags...)
+	rec.AssertCount(t, "nsq.requeue", 3, tags...)
+	rec.AssertCount(t, "nsq.timeout", 0, tags...)
+	rec.AssertNotRecorded(t, "nsq.depth", metric.T("channel", "other"))
+}
@@ -0,0 +1,127 @@
+// Package pidfile writes process pid file and guards against running
+// multiple instances of the service on the same host.
+//
+//	if err := pidfile.Write("/var/run/app.pid"); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Pid file is removed on shutdown.
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/signal"
+)
+
+// EnvPidFile sets pid file path, used by FromEnv.
+const EnvPidFile = "SVCKIT_PIDFILE"
+
+var (
+	// ErrRunning is returned when pid file belongs to running process.
+	ErrRunning = errors.New("process is already running")
+	// ErrLocked is returned when single instance lock is held by other process.
+	ErrLocked = errors.New("instance lock is held by other process")
+)
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.pidfile")
+}
+
+// FromEnv writes pid file to path from EnvPidFile, if set.
+func FromEnv() error {
+	if path := os.Getenv(EnvPidFile); path != "" {
+		return Write(path)
+	}
+	return nil
+}
+
+// Write writes pid of the current process to path.
+// Existing pid file is stale if its process is not running, then it is overwritten,
+// otherwise ErrRunning is returned.
+// Pid file is removed on shutdown.
+func Write(path string) error {
+	if pid, err := Read(path); err == nil && pid != os.Getpid() {
+		if alive(pid) {
+			return fmt.Errorf("%w: pid %d in %s", ErrRunning, pid, path)
+		}
+		logger().S("path", path).I("pid", pid).Info("removing stale pid file")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return err
+	}
+	signal.OnShutdown("pidfile", signal.PriorityDeregister+1, 0, func() { Remove(path) })
+	return nil
+}
+
+// Read returns pid from pid file.
+func Read(path string) (int, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(buf)))
+}
+
+// Remove removes pid file if it belongs to the current process.
+func Remove(path string) {
+	if pid, err := Read(path); err != nil || pid != os.Getpid() {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		logger().S("path", path).Error(err)
+	}
+}
+
+// Lock is single instance guard.
+type Lock struct {
+	f *os.File
+}
+
+// SingleInstance takes exclusive lock (flock) on lock file of the application in dir
+// (os.TempDir if empty), so only one instance of the application runs on the host.
+// Lock is released when process exits or on Release.
+// Returns ErrLocked if other instance holds the lock.
+func SingleInstance(dir string) (*Lock, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return TryLock(filepath.Join(dir, env.AppName()+".lock"))
+}
+
+// TryLock takes exclusive lock on file path without waiting.
+// Pid of the current process is written to the file.
+func TryLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			if pid, err := Read(path); err == nil {
+				return nil, fmt.Errorf("%w: pid %d", ErrLocked, pid)
+			}
+		}
+		return nil, err
+	}
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release releases the lock.
+func (l *Lock) Release() error {
+	return l.f.Close()
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+
+package pidfile
+
+import (
+	"errors"
+	"os"
+)
+
+// alive is not supported, existing pid file is always considered stale.
+func alive(pid int) bool {
+	return false
+}
+
+func flock(f *os.File) error {
+	return errors.New("flock not supported")
+}
@@ -0,0 +1,44 @@
+package pidfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "app.pid")
+	assert.Nil(t, Write(path))
+	pid, err := Read(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.Getpid(), pid)
+
+	// parent process is running
+	os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0644)
+	err = Write(path)
+	assert.True(t, errors.Is(err, ErrRunning))
+
+	// stale pid file
+	os.WriteFile(path, []byte("999999999"), 0644)
+	assert.Nil(t, Write(path))
+
+	Remove(path)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSingleInstance(t *testing.T) {
+	dir := t.TempDir()
+	l, err := SingleInstance(dir)
+	assert.Nil(t, err)
+	_, err = SingleInstance(dir)
+	assert.True(t, errors.Is(err, ErrLocked))
+	assert.Nil(t, l.Release())
+	l, err = SingleInstance(dir)
+	assert.Nil(t, err)
+	l.Release()
+}
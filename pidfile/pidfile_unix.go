@@ -0,0 +1,22 @@
+//go:build linux || darwin
+
+package pidfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// alive reports whether process with pid is running.
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func flock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return ErrLocked
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package signal
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff is exponential backoff retry strategy.
+// Interval starts at InitialInterval and is multiplied by Multiplier after each retry,
+// up to MaxInterval. Retries stop after MaxElapsedTime (0 means retry forever).
+// Set fields before first use; Backoff is not safe for concurrent use.
+type Backoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+	Multiplier      float64
+	// Randomization factor, interval is randomized in [interval*(1-r), interval*(1+r)].
+	Randomization float64
+	// FullJitter randomizes interval in [0, interval], Randomization is ignored.
+	FullJitter bool
+	// Notify is called on each error before waiting for next retry.
+	Notify func(err error, next time.Duration)
+
+	current time.Duration
+	start   time.Time
+}
+
+// NewBackoff creates Backoff with default values:
+// initial interval 500ms, multiplier 1.5, randomization 0.5,
+// max interval 10s, max elapsed time 1 minute.
+func NewBackoff() *Backoff {
+	b := &Backoff{
+		InitialInterval: 500 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		MaxElapsedTime:  time.Minute,
+		Multiplier:      1.5,
+		Randomization:   0.5,
+	}
+	b.Reset()
+	return b
+}
+
+// Reset starts backoff from the initial interval.
+func (b *Backoff) Reset() {
+	b.current = b.InitialInterval
+	b.start = time.Now()
+}
+
+// Next returns duration to wait before next retry.
+// Returns false when MaxElapsedTime is exceeded.
+func (b *Backoff) Next() (time.Duration, bool) {
+	if b.start.IsZero() {
+		b.Reset()
+	}
+	if b.MaxElapsedTime > 0 && time.Since(b.start) > b.MaxElapsedTime {
+		return 0, false
+	}
+	interval := b.current
+	if b.MaxInterval > 0 && interval > b.MaxInterval {
+		interval = b.MaxInterval
+	}
+	next := time.Duration(float64(b.current) * b.Multiplier)
+	if b.MaxInterval > 0 && next > b.MaxInterval {
+		next = b.MaxInterval
+	}
+	b.current = next
+	return b.jitter(interval), true
+}
+
+func (b *Backoff) jitter(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	if b.FullJitter {
+		return time.Duration(rand.Int63n(int64(interval) + 1))
+	}
+	if b.Randomization <= 0 {
+		return interval
+	}
+	delta := b.Randomization * float64(interval)
+	min := float64(interval) - delta
+	return time.Duration(min + rand.Float64()*2*delta)
+}
+
+// Sleep waits for next retry interval.
+// Returns false if ctx is done or MaxElapsedTime is exceeded.
+func (b *Backoff) Sleep(ctx context.Context) bool {
+	d, ok := b.Next()
+	if !ok {
+		return false
+	}
+	return sleep(ctx, d)
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Retry calls handler until it succeeds, ctx is done or MaxElapsedTime is exceeded.
+// Handler is called at least once, when ctx is done pending wait is aborted.
+// Returns last handler error, or ctx error if ctx is done.
+func (b *Backoff) Retry(ctx context.Context, handler func() error) error {
+	b.Reset()
+	for {
+		err := handler()
+		if err == nil {
+			return nil
+		}
+		d, ok := b.Next()
+		if !ok {
+			return err
+		}
+		if b.Notify != nil {
+			b.Notify(err, d)
+		}
+		if !sleep(ctx, d) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package signal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffNext(t *testing.T) {
+	b := &Backoff{InitialInterval: time.Second, MaxInterval: 3 * time.Second, Multiplier: 2}
+	var ds []time.Duration
+	for i := 0; i < 4; i++ {
+		d, ok := b.Next()
+		assert.True(t, ok)
+		ds = append(ds, d)
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}, ds)
+
+	b.Reset()
+	b.FullJitter = true
+	for i := 0; i < 10; i++ {
+		d, _ := b.Next()
+		assert.True(t, d >= 0 && d <= 3*time.Second)
+	}
+}
+
+func TestBackoffMaxElapsedTime(t *testing.T) {
+	b := &Backoff{InitialInterval: time.Millisecond, Multiplier: 1, MaxElapsedTime: 20 * time.Millisecond}
+	calls := 0
+	err := b.Retry(context.Background(), func() error {
+		calls++
+		return errors.New("fail")
+	})
+	assert.EqualError(t, err, "fail")
+	assert.True(t, calls > 1)
+}
+
+func TestBackoffRetry(t *testing.T) {
+	b := NewBackoff()
+	b.InitialInterval = time.Millisecond
+	calls := 0
+	var notified []error
+	b.Notify = func(err error, _ time.Duration) { notified = append(notified, err) }
+	err := b.Retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("fail")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, notified, 2)
+}
+
+func TestBackoffContext(t *testing.T) {
+	b := NewBackoff()
+	b.InitialInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	err := b.Retry(ctx, func() error { return errors.New("fail") })
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestWithExponentialBackoffContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	calls := 0
+	start := time.Now()
+	err := WithExponentialBackoffContext(ctx, func() error {
+		calls++
+		return errors.New("fail")
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, calls >= 1)
+	assert.True(t, time.Since(start) < time.Second)
+}
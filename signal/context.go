@@ -0,0 +1,69 @@
+package signal
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	ctxMu      sync.Mutex
+	ctx        context.Context
+	cancel     context.CancelFunc
+	notifyOnce sync.Once
+)
+
+// shutdownContext returns context cancelled by Shutdown.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	ctxMu.Lock()
+	defer ctxMu.Unlock()
+	if ctx == nil {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	return ctx, cancel
+}
+
+// ShutdownContext returns process context which is cancelled when Shutdown is called
+// (by WaitForInterupt or application).
+// Unlike Context it doesn't install signal handler, so it is safe to use in libraries:
+// application which doesn't use WaitForInterupt keeps default SIGINT/SIGTERM behavior.
+func ShutdownContext() context.Context {
+	c, _ := shutdownContext()
+	return c
+}
+
+// Context returns process context, cancelled on SIGINT or SIGTERM
+// or when Shutdown is called.
+// First call installs SIGINT/SIGTERM handler, after that signals don't terminate process.
+// Libraries should use ShutdownContext.
+func Context() context.Context {
+	c, cf := shutdownContext()
+	notifyOnce.Do(func() {
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			defer signal.Stop(sc)
+			select {
+			case sig := <-sc:
+				logger().S("signal", sig.String()).Info("signal received")
+				cf()
+			case <-c.Done():
+			}
+		}()
+	})
+	return c
+}
+
+// AfterFunc calls f in its own goroutine when process context is cancelled.
+// Returned stop func unregisters f (reports false if f has already been started).
+// Unlike OnShutdown hooks, Shutdown doesn't wait for f.
+func AfterFunc(f func()) (stop func() bool) {
+	return context.AfterFunc(Context(), f)
+}
+
+func cancelContext() {
+	_, cf := shutdownContext()
+	cf()
+}
@@ -0,0 +1,56 @@
+package signal
+
+import (
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetContext() {
+	cancelContext()
+	ctxMu.Lock()
+	ctx, cancel = nil, nil
+	ctxMu.Unlock()
+	notifyOnce = sync.Once{}
+}
+
+func TestContextSignal(t *testing.T) {
+	resetContext()
+	defer resetContext()
+	called := make(chan struct{})
+	AfterFunc(func() { close(called) })
+	assert.Nil(t, Context().Err())
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled on SIGTERM")
+	}
+	assert.NotNil(t, Context().Err())
+}
+
+func TestContextShutdown(t *testing.T) {
+	resetContext()
+	resetHooks()
+	defer resetContext()
+	defer resetHooks()
+	var err error
+	OnShutdown("check", PriorityDrain, 0, func() { err = Context().Err() })
+	Shutdown()
+	assert.NotNil(t, err)
+}
+
+func TestShutdownContext(t *testing.T) {
+	resetContext()
+	resetHooks()
+	defer resetContext()
+	defer resetHooks()
+	c := ShutdownContext()
+	assert.Nil(t, c.Err())
+	Shutdown()
+	assert.NotNil(t, c.Err())
+	assert.Equal(t, c, Context())
+}
@@ -0,0 +1,129 @@
+package signal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
+)
+
+// EnvDiagDir sets directory for diagnostic dumps, if not set stacks are written to stderr.
+const EnvDiagDir = "SVCKIT_DIAG_DIR"
+
+// EnvDiagSignals enables diagnostic signals (see ListenDiag) when set to any value.
+const EnvDiagSignals = "SVCKIT_DIAG_SIGNALS"
+
+var diagOnce sync.Once
+
+func init() {
+	if os.Getenv(EnvDiagSignals) != "" && !env.InTest() {
+		ListenDiag()
+	}
+}
+
+// ListenDiag enables diagnostic signals:
+//
+//	SIGUSR1 dumps goroutine stacks (and heap profile if EnvDiagDir is set), logs heap stats
+//	SIGUSR2 toggles debug log level
+//
+// Diagnostic signals are opt-in because leader uses SIGUSR1 to release leadership,
+// don't enable them in services which use leader.
+func ListenDiag() {
+	diagOnce.Do(listenDiag)
+}
+
+func listenDiag() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range c {
+			switch sig {
+			case syscall.SIGUSR1:
+				if err := Dump(); err != nil {
+					logger().Error(err)
+				}
+			case syscall.SIGUSR2:
+				ToggleDebug()
+			}
+		}
+	}()
+}
+
+// Dump writes goroutine stacks and heap stats.
+// Stacks and heap profile are written to files in EnvDiagDir,
+// or stacks to stderr if it is not set.
+func Dump() error {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	logger().
+		I("goroutines", runtime.NumGoroutine()).
+		I("heap_alloc", int(ms.HeapAlloc)).
+		I("heap_inuse", int(ms.HeapInuse)).
+		I("heap_objects", int(ms.HeapObjects)).
+		I("sys", int(ms.Sys)).
+		I("num_gc", int(ms.NumGC)).
+		S("pause_total", time.Duration(ms.PauseTotalNs).String()).
+		Info("heap stats")
+
+	dir := os.Getenv(EnvDiagDir)
+	if dir == "" {
+		return writeStacks(os.Stderr)
+	}
+	prefix := filepath.Join(dir, fmt.Sprintf("%s-%d-%s", env.AppName(), os.Getpid(), time.Now().Format("20060102150405")))
+	if err := writeFile(prefix+"-goroutines.txt", writeStacks); err != nil {
+		return err
+	}
+	if err := writeFile(prefix+"-heap.pprof", func(w io.Writer) error {
+		return pprof.Lookup("heap").WriteTo(w, 0)
+	}); err != nil {
+		return err
+	}
+	logger().S("prefix", prefix).Info("diagnostics written")
+	return nil
+}
+
+func writeStacks(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var (
+	debugMu   sync.Mutex
+	prevLevel *log.Level
+)
+
+// ToggleDebug switches log level to debug, next call restores previous level.
+func ToggleDebug() {
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	if prevLevel != nil {
+		log.SetLevel(*prevLevel)
+		logger().S("level", prevLevel.String()).Info("debug logging disabled")
+		prevLevel = nil
+		return
+	}
+	l := log.GetLevel()
+	prevLevel = &l
+	log.SetLevel(log.DebugLevel)
+	logger().S("previous", l.String()).Info("debug logging enabled")
+}
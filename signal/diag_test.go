@@ -0,0 +1,32 @@
+package signal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minus5/svckit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv(EnvDiagDir, dir)
+	defer os.Unsetenv(EnvDiagDir)
+	assert.Nil(t, Dump())
+	stacks, _ := filepath.Glob(filepath.Join(dir, "*-goroutines.txt"))
+	heap, _ := filepath.Glob(filepath.Join(dir, "*-heap.pprof"))
+	assert.Len(t, stacks, 1)
+	assert.Len(t, heap, 1)
+	buf, _ := os.ReadFile(stacks[0])
+	assert.Contains(t, string(buf), "TestDump")
+}
+
+func TestToggleDebug(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.WarnLevel)
+	ToggleDebug()
+	assert.Equal(t, log.DebugLevel, log.GetLevel())
+	ToggleDebug()
+	assert.Equal(t, log.WarnLevel, log.GetLevel())
+}
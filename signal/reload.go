@@ -0,0 +1,94 @@
+package signal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/minus5/svckit/metric"
+)
+
+type reloadHook struct {
+	name string
+	f    func() error
+}
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []reloadHook
+	reloadOnce  sync.Once
+)
+
+// OnReload registers named reload handler (e.g. log level, config, TLS certs)
+// called on SIGHUP or Reload, in order of registration.
+func OnReload(name string, f func() error) {
+	reloadOnce.Do(listenReload)
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	reloadHooks = append(reloadHooks, reloadHook{name: name, f: f})
+}
+
+func listenReload() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			logger().Info("SIGHUP received")
+			Reload()
+		}
+	}()
+}
+
+// Reload calls all reload handlers.
+// All handlers are called, returned error joins errors of the failed ones.
+// Each reload is logged and counted in signal.reload metric (tagged by hook and result).
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	hooksMu.Lock()
+	hs := make([]reloadHook, len(reloadHooks))
+	copy(hs, reloadHooks)
+	hooksMu.Unlock()
+
+	start := time.Now()
+	var errs []error
+	for _, h := range hs {
+		if err := h.run(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	err := errors.Join(errs...)
+	l := logger().I("hooks", len(hs)).I("failed", len(errs)).S("duration", time.Since(start).String())
+	if err != nil {
+		metric.CounterT("signal.reload", 1, metric.T("result", "error"))
+		l.S("error", err.Error()).ErrorS("reload failed")
+		return err
+	}
+	metric.CounterT("signal.reload", 1, metric.T("result", "ok"))
+	l.Info("reload completed")
+	return nil
+}
+
+func (h reloadHook) run() error {
+	err := h.call()
+	result := "ok"
+	if err != nil {
+		result = "error"
+		logger().S("hook", h.name).S("error", err.Error()).ErrorS("reload hook failed")
+	}
+	metric.CounterT("signal.reload.hook", 1, metric.T("hook", h.name), metric.T("result", result))
+	return err
+}
+
+func (h reloadHook) call() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h.f()
+}
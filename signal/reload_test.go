@@ -0,0 +1,33 @@
+package signal
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	defer func() { reloadHooks = nil }()
+	var calls []string
+	OnReload("a", func() error { calls = append(calls, "a"); return nil })
+	OnReload("b", func() error { calls = append(calls, "b"); return errors.New("bad cert") })
+	OnReload("c", func() error { calls = append(calls, "c"); return nil })
+	err := Reload()
+	assert.EqualError(t, err, "b: bad cert")
+	assert.Equal(t, []string{"a", "b", "c"}, calls)
+}
+
+func TestReloadSIGHUP(t *testing.T) {
+	defer func() { reloadHooks = nil }()
+	done := make(chan struct{})
+	OnReload("signal", func() error { close(done); return nil })
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reload not called on SIGHUP")
+	}
+}
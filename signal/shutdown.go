@@ -0,0 +1,178 @@
+package signal
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+)
+
+// Shutdown phases. Hooks with lower priority run first,
+// hooks with the same priority in order of registration.
+const (
+	PriorityReadiness     = math.MinInt32 // mark service not ready (health), before any other hook
+	PriorityStopAccepting = 100           // stop accepting new work (close listeners, stop consumers)
+	PriorityDrain         = 200           // wait for in flight work to finish
+	PriorityFlush         = 300           // flush buffers (logs, metrics, producers)
+	PriorityDeregister    = 400           // deregister from service discovery
+)
+
+// DefaultHookTimeout is used for hooks registered with timeout 0.
+var DefaultHookTimeout = 10 * time.Second
+
+// EnvShutdownDeadline sets shutdown deadline (e.g. 1m).
+const EnvShutdownDeadline = "SVCKIT_SHUTDOWN_DEADLINE"
+
+// ExitShutdownDeadline is process exit code when shutdown deadline is exceeded.
+const ExitShutdownDeadline = 3
+
+// ShutdownDeadline is max duration of shutdown.
+// If hooks don't finish in time goroutine stacks are dumped to stderr,
+// still running hooks are logged and process exits with ExitShutdownDeadline.
+// Default is 30s, 0 disables deadline.
+var ShutdownDeadline = 30 * time.Second
+
+var exit = os.Exit
+
+func init() {
+	if d, err := time.ParseDuration(os.Getenv(EnvShutdownDeadline)); err == nil {
+		ShutdownDeadline = d
+	}
+}
+
+type hook struct {
+	name     string
+	priority int
+	timeout  time.Duration
+	f        func()
+	seq      int
+}
+
+var (
+	hooks        []hook
+	shutdownOnce sync.Once
+	running      = make(map[*hook]time.Time)
+)
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.signal")
+}
+
+// OnShutdown registers named hook called on shutdown in order of priority.
+// Shutdown doesn't wait for hook longer than timeout (DefaultHookTimeout if 0,
+// without limit if negative).
+func OnShutdown(name string, priority int, timeout time.Duration, f func()) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, hook{name: name, priority: priority, timeout: timeout, f: f, seq: len(hooks)})
+}
+
+// Shutdown runs registered hooks in order of priority.
+// Called by WaitForInterupt after signal is received.
+// Process context (see Context) is cancelled before hooks are run.
+// Hooks are run only once, subsequent calls return immediately.
+func Shutdown() {
+	shutdownOnce.Do(runShutdown)
+}
+
+func sortedHooks() []hook {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hs := make([]hook, len(hooks))
+	copy(hs, hooks)
+	sort.Slice(hs, func(i, j int) bool {
+		if hs[i].priority != hs[j].priority {
+			return hs[i].priority < hs[j].priority
+		}
+		return hs[i].seq < hs[j].seq
+	})
+	return hs
+}
+
+func runShutdown() {
+	start := time.Now()
+	cancelContext()
+	hs := sortedHooks()
+	logger().I("hooks", len(hs)).Info("shutdown started")
+	if d := ShutdownDeadline; d > 0 {
+		t := time.AfterFunc(d, func() { deadlineExceeded(d) })
+		defer t.Stop()
+	}
+	for i := range hs {
+		hs[i].run()
+	}
+	logger().S("duration", time.Since(start).String()).Info("shutdown completed")
+}
+
+// deadlineExceeded reports still running hooks and exits process.
+func deadlineExceeded(d time.Duration) {
+	hooksMu.Lock()
+	var names []string
+	for h, start := range running {
+		names = append(names, fmt.Sprintf("%s (%s)", h.name, time.Since(start).Round(time.Millisecond)))
+	}
+	hooksMu.Unlock()
+	sort.Strings(names)
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	fmt.Fprintf(os.Stderr, "shutdown deadline %s exceeded, goroutines:\n%s\n", d, buf)
+	metric.Counter("signal.shutdown.deadline")
+	logger().S("deadline", d.String()).S("running", strings.Join(names, ", ")).
+		ErrorS("shutdown deadline exceeded")
+	exit(ExitShutdownDeadline)
+}
+
+func (h *hook) setRunning(r bool) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if r {
+		running[h] = time.Now()
+		return
+	}
+	delete(running, h)
+}
+
+func (h *hook) logger() *log.Agregator {
+	return logger().S("hook", h.name).I("priority", h.priority)
+}
+
+func (h *hook) run() {
+	h.logger().Info("shutdown hook started")
+	start := time.Now()
+	done := make(chan struct{})
+	h.setRunning(true)
+	go func() {
+		defer close(done)
+		defer h.setRunning(false)
+		defer func() {
+			if r := recover(); r != nil {
+				h.logger().S("panic", fmt.Sprint(r)).ErrorS("shutdown hook panic")
+			}
+		}()
+		h.f()
+	}()
+	timeout := h.timeout
+	if timeout == 0 {
+		timeout = DefaultHookTimeout
+	}
+	if timeout < 0 {
+		<-done
+	} else {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		select {
+		case <-done:
+		case <-t.C:
+			h.logger().S("timeout", timeout.String()).ErrorS("shutdown hook timeout")
+			return
+		}
+	}
+	h.logger().S("duration", time.Since(start).String()).Info("shutdown hook completed")
+}
@@ -0,0 +1,63 @@
+package signal
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetHooks() {
+	hooks = nil
+	shutdownOnce = sync.Once{}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	resetHooks()
+	defer resetHooks()
+	var order []string
+	add := func(name string, priority int) {
+		OnShutdown(name, priority, 0, func() { order = append(order, name) })
+	}
+	add("deregister", PriorityDeregister)
+	add("flush", PriorityFlush)
+	add("stop", PriorityStopAccepting)
+	add("drain", PriorityDrain)
+	add("flush2", PriorityFlush)
+	BeforeShutdown(func() { order = append(order, "before") })
+	add("readiness", PriorityReadiness)
+	Shutdown()
+	Shutdown()
+	assert.Equal(t, []string{"readiness", "before", "stop", "drain", "flush", "flush2", "deregister"}, order)
+}
+
+func TestShutdownHookTimeout(t *testing.T) {
+	resetHooks()
+	defer resetHooks()
+	block := make(chan struct{})
+	defer close(block)
+	done := false
+	OnShutdown("slow", PriorityDrain, 10*time.Millisecond, func() { <-block })
+	OnShutdown("panic", PriorityFlush, 0, func() { panic("boom") })
+	OnShutdown("last", PriorityDeregister, 0, func() { done = true })
+	Shutdown()
+	assert.True(t, done)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	resetHooks()
+	defer resetHooks()
+	defer func(d time.Duration) { ShutdownDeadline = d; exit = os.Exit }(ShutdownDeadline)
+	ShutdownDeadline = 20 * time.Millisecond
+	block := make(chan struct{})
+	code := 0
+	exit = func(c int) {
+		code = c
+		close(block)
+	}
+	OnShutdown("stuck", PriorityFlush, -1, func() { <-block })
+	Shutdown()
+	assert.Equal(t, ExitShutdownDeadline, code)
+}
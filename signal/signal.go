@@ -1,12 +1,11 @@
 package signal
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-	"time"
-
-	"github.com/cenkalti/backoff"
 )
 
 func Term() chan os.Signal {
@@ -21,19 +20,34 @@ func Usr1() chan os.Signal {
 	return c
 }
 
+var hooksMu sync.Mutex
+
+// BeforeShutdown registers hook called on shutdown after readiness is flipped (PriorityReadiness),
+// before all other hooks, e.g. to notify peers before listeners are closed.
+// It is alias for OnShutdown with priority PriorityReadiness+1 and without timeout.
+// Hooks are called in order of registration.
+func BeforeShutdown(f func()) {
+	OnShutdown("before shutdown", PriorityReadiness+1, -1, f)
+}
+
+// WaitForInterupt blocks until SIGINT or SIGTERM is received,
+// then runs shutdown hooks (see OnShutdown).
 func WaitForInterupt() {
-	c := make(chan os.Signal, 1)
 	//SIGINT je ctrl-C u shell-u, SIGTERM salje upstart kada se napravi sudo stop ...
-	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-	<-c
+	<-Context().Done()
+	Shutdown()
 }
 
 // WithExponentialBackoff will retry handler on each error.
 // Retries are in exponentialy increasing interval.
 // With max interval between retries of 10 seconds, and max elapsed time of 1 minute.
 func WithExponentialBackoff(handler func() error) error {
-	b := backoff.NewExponentialBackOff()
-	b.MaxInterval = 10 * time.Second   //max interval between retries
-	b.MaxElapsedTime = 1 * time.Minute //how log will we retry
-	return backoff.Retry(handler, b)
+	return WithExponentialBackoffContext(context.Background(), handler)
+}
+
+// WithExponentialBackoffContext is WithExponentialBackoff which stops retrying when ctx is done.
+// Pending wait for next retry is aborted immediately,
+// e.g. use ShutdownContext to stop retrying on shutdown.
+func WithExponentialBackoffContext(ctx context.Context, handler func() error) error {
+	return NewBackoff().Retry(ctx, handler)
 }
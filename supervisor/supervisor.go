@@ -0,0 +1,262 @@
+// Package supervisor runs long lived worker goroutines and restarts them
+// when they exit or panic.
+//
+//	supervisor.Go("consumer", func(ctx context.Context) error {
+//		return consume(ctx)
+//	}, supervisor.Restart(supervisor.OnFailure), supervisor.MaxRestarts(10))
+//
+// Workers run with process context (signal.ShutdownContext), on shutdown context is
+// cancelled and shutdown waits for workers to exit.
+// Each worker registers readiness check which fails when supervisor gives up restarting it.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/minus5/svckit/health"
+	"github.com/minus5/svckit/log"
+	"github.com/minus5/svckit/metric"
+	"github.com/minus5/svckit/signal"
+)
+
+// Policy decides when worker is restarted.
+type Policy int
+
+const (
+	// Always restarts worker after every exit.
+	Always Policy = iota
+	// OnFailure restarts worker when it returns error or panics.
+	OnFailure
+	// Never runs worker only once.
+	Never
+)
+
+// State of the worker.
+type State string
+
+const (
+	Running    State = "running"
+	Restarting State = "restarting"
+	Stopped    State = "stopped"
+	Failed     State = "failed"
+)
+
+var (
+	wg         sync.WaitGroup
+	onShutdown sync.Once
+	// running workers by name
+	workersMu sync.Mutex
+	workers   = map[string]*Worker{}
+)
+
+func logger() *log.Agregator {
+	return log.S("lib", "svckit.supervisor")
+}
+
+type options struct {
+	policy      Policy
+	maxRestarts int
+	backoff     *signal.Backoff
+	checkOpts   []health.CheckOption
+}
+
+// Option configures worker.
+type Option func(*options)
+
+// Restart sets restart policy, default is Always.
+func Restart(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// MaxRestarts sets max number of restarts, after that worker is failed.
+// Default 0 means restart forever.
+func MaxRestarts(n int) Option {
+	return func(o *options) {
+		o.maxRestarts = n
+	}
+}
+
+// Backoff sets backoff between restarts.
+// Default is signal.NewBackoff without max elapsed time.
+func Backoff(b *signal.Backoff) Option {
+	return func(o *options) {
+		o.backoff = b
+	}
+}
+
+// CheckOptions sets options of worker readiness check (e.g. health.Informational).
+func CheckOptions(opts ...health.CheckOption) Option {
+	return func(o *options) {
+		o.checkOpts = opts
+	}
+}
+
+// Worker is supervised goroutine.
+type Worker struct {
+	name     string
+	run      func(context.Context) error
+	o        options
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	mu       sync.Mutex
+	state    State
+	restarts int
+	err      error
+}
+
+// Go starts supervised worker.
+// Worker should return when ctx is done.
+// Name must be unique, if worker with the same name is running it is returned
+// and run is not started.
+func Go(name string, run func(ctx context.Context) error, opts ...Option) *Worker {
+	o := options{policy: Always}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	if o.backoff == nil {
+		o.backoff = signal.NewBackoff()
+		o.backoff.MaxElapsedTime = 0
+	}
+	onShutdown.Do(func() {
+		signal.OnShutdown("supervisor", signal.PriorityDrain, 0, Wait)
+	})
+	w := &Worker{
+		name:  name,
+		run:   run,
+		o:     o,
+		done:  make(chan struct{}),
+		state: Running,
+	}
+	workersMu.Lock()
+	if running, ok := workers[name]; ok {
+		workersMu.Unlock()
+		logger().S("worker", name).ErrorS("worker with the same name is already running")
+		return running
+	}
+	workers[name] = w
+	workersMu.Unlock()
+	w.ctx, w.cancel = context.WithCancel(signal.ShutdownContext())
+	health.AddReadiness(w.checkName(), w.check, o.checkOpts...)
+	wg.Add(1)
+	go w.loop()
+	return w
+}
+
+// Wait waits for all workers to exit.
+func Wait() {
+	wg.Wait()
+}
+
+func (w *Worker) checkName() string {
+	return "worker." + w.name
+}
+
+func (w *Worker) check() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Failed {
+		return fmt.Errorf("worker failed after %d restarts: %v", w.restarts, w.err)
+	}
+	return nil
+}
+
+// State returns current worker state.
+func (w *Worker) State() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+// Restarts returns number of worker restarts.
+func (w *Worker) Restarts() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.restarts
+}
+
+// Stop cancels worker context and waits for worker to exit.
+func (w *Worker) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *Worker) setState(s State, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = s
+	w.err = err
+}
+
+func (w *Worker) loop() {
+	defer wg.Done()
+	defer close(w.done)
+	defer w.cancel()
+	defer func() {
+		workersMu.Lock()
+		delete(workers, w.name)
+		workersMu.Unlock()
+	}()
+	b := w.o.backoff
+	b.Reset()
+	for {
+		start := time.Now()
+		err := w.call()
+		if w.ctx.Err() != nil {
+			w.stopped(err)
+			return
+		}
+		if w.o.policy == Never || (w.o.policy == OnFailure && err == nil) {
+			w.stopped(err)
+			return
+		}
+		l := logger().S("worker", w.name)
+		if err != nil {
+			l = l.S("error", err.Error())
+		}
+		if w.o.maxRestarts > 0 && w.Restarts() >= w.o.maxRestarts {
+			w.setState(Failed, err)
+			metric.CounterT("supervisor.failed", 1, metric.T("worker", w.name))
+			l.I("restarts", w.Restarts()).ErrorS("worker failed")
+			return
+		}
+		// worker was running long enough, start backoff from the beginning
+		if b.MaxInterval > 0 && time.Since(start) > b.MaxInterval {
+			b.Reset()
+		}
+		w.setState(Restarting, err)
+		l.Warn("worker exited, restarting")
+		if !b.Sleep(w.ctx) {
+			w.stopped(err)
+			return
+		}
+		w.mu.Lock()
+		w.restarts++
+		w.state = Running
+		w.mu.Unlock()
+		metric.CounterT("supervisor.restart", 1, metric.T("worker", w.name))
+	}
+}
+
+func (w *Worker) stopped(err error) {
+	w.setState(Stopped, err)
+	health.RemoveReadiness(w.checkName())
+	logger().S("worker", w.name).Info("worker stopped")
+}
+
+// call runs worker, panic is returned as error.
+func (w *Worker) call() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			logger().S("worker", w.name).S("stack", string(debug.Stack())).ErrorS("worker panic")
+		}
+	}()
+	return w.run(w.ctx)
+}
@@ -0,0 +1,91 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minus5/svckit/health"
+	"github.com/minus5/svckit/signal"
+	"github.com/stretchr/testify/assert"
+)
+
+func fastBackoff() Option {
+	return Backoff(&signal.Backoff{InitialInterval: time.Millisecond, Multiplier: 1})
+}
+
+func readiness(name string) (health.CheckResult, bool) {
+	for _, c := range health.ReadyReport().Checks {
+		if c.Name == "worker."+name {
+			return c, true
+		}
+	}
+	return health.CheckResult{}, false
+}
+
+func TestRestartOnPanic(t *testing.T) {
+	runs := 0
+	done := make(chan struct{})
+	w := Go("panic", func(ctx context.Context) error {
+		runs++
+		if runs < 3 {
+			panic("boom")
+		}
+		close(done)
+		<-ctx.Done()
+		return nil
+	}, fastBackoff())
+	<-done
+	assert.Equal(t, 2, w.Restarts())
+	assert.Equal(t, Running, w.State())
+	_, ok := readiness("panic")
+	assert.True(t, ok)
+	w.Stop()
+	assert.Equal(t, Stopped, w.State())
+	_, ok = readiness("panic")
+	assert.False(t, ok)
+}
+
+func TestMaxRestarts(t *testing.T) {
+	w := Go("failing", func(ctx context.Context) error {
+		return errors.New("bad")
+	}, fastBackoff(), MaxRestarts(2))
+	<-w.done
+	assert.Equal(t, Failed, w.State())
+	assert.Equal(t, 2, w.Restarts())
+	c, ok := readiness("failing")
+	assert.True(t, ok)
+	assert.Equal(t, health.Fail, c.Status)
+	health.RemoveReadiness("worker.failing")
+}
+
+func TestPolicy(t *testing.T) {
+	w := Go("once", func(ctx context.Context) error {
+		return errors.New("bad")
+	}, fastBackoff(), Restart(Never))
+	<-w.done
+	assert.Equal(t, Stopped, w.State())
+	assert.Equal(t, 0, w.Restarts())
+
+	w = Go("clean", func(ctx context.Context) error {
+		return nil
+	}, fastBackoff(), Restart(OnFailure))
+	<-w.done
+	assert.Equal(t, Stopped, w.State())
+	assert.Equal(t, 0, w.Restarts())
+}
+
+func TestDuplicateName(t *testing.T) {
+	w := Go("unique", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	assert.True(t, w == Go("unique", func(ctx context.Context) error {
+		t.Error("duplicate worker started")
+		return nil
+	}))
+	w.Stop()
+	w = Go("unique", func(ctx context.Context) error { return nil }, Restart(Never))
+	<-w.done
+}
@@ -3,6 +3,7 @@ package svckit
 import (
 	"expvar"
 	"github.com/minus5/svckit/env"
+	"github.com/minus5/svckit/log"
 	"time"
 )
 
@@ -14,6 +15,12 @@ func init() {
 	publishExpvar()
 }
 
+// Start logs application start with build metadata (see log.AppStart).
+// Call it from main after configuration, services with httpi.Start get it there.
+func Start() {
+	log.AppStart()
+}
+
 func publishExpvar() {
 	expvar.Publish("svckit.stats", expvar.Func(func() interface{} {
 		stats := struct {
@@ -21,10 +28,12 @@ func publishExpvar() {
 			Uptime   time.Duration `json:"uptime"`
 			AppName  string        `json:"appName"`
 			Hostname string        `json:"hostname"`
+			Build    env.BuildInfo `json:"build"`
 		}{
 			Start:    startTime,
 			AppName:  env.AppName(),
 			Hostname: env.Hostname(),
+			Build:    env.Build(),
 			Uptime:   time.Now().Sub(startTime) / time.Second,
 		}
 		return stats